@@ -0,0 +1,10 @@
+package all
+
+import (
+	_ "github.com/bornholm/go-webdav/auth/htpasswd"
+	_ "github.com/bornholm/go-webdav/auth/jwt"
+	_ "github.com/bornholm/go-webdav/auth/ldap"
+	_ "github.com/bornholm/go-webdav/auth/mtls"
+	_ "github.com/bornholm/go-webdav/auth/password"
+	_ "github.com/bornholm/go-webdav/auth/token"
+)
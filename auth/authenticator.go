@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoCredentials is returned by authenticators when the request does not
+	// hold the kind of credentials they handle, in which case the next
+	// authenticator is tried.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned by authenticators when the request
+	// credentials are rejected.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator identifies the user issuing a request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (authz.User, error)
+}
+
+// Challenger is implemented by authenticators expecting credentials sent in the
+// Authorization header, returning the WWW-Authenticate challenge of their scheme.
+type Challenger interface {
+	Challenge(realm string) string
+}
+
+type AuthenticatorFunc func(r *http.Request) (authz.User, error)
+
+// Authenticate implements Authenticator.
+func (fn AuthenticatorFunc) Authenticate(r *http.Request) (authz.User, error) {
+	return fn(r)
+}
+
+var _ Authenticator = AuthenticatorFunc(nil)
+
+// BasicChallenge returns the challenge of the basic authentication scheme.
+func BasicChallenge(realm string) string {
+	return `Basic realm="` + realm + `", charset="UTF-8"`
+}
+
+// BearerChallenge returns the challenge of the bearer authentication scheme.
+func BearerChallenge(realm string) string {
+	return `Bearer realm="` + realm + `"`
+}
+
+// BearerToken returns the token of the request Authorization header, if
+// it uses the bearer scheme.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,158 @@
+package htpasswd
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/auth/password"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+// Authenticator authenticates basic auth credentials against an Apache
+// htpasswd file and, optionally, assigns groups from an Apache group file.
+// Files are reloaded when they are modified.
+type Authenticator struct {
+	passwordFile string
+	groupFile    string
+
+	mu        sync.RWMutex
+	passwords map[string]string
+	groups    map[string][]string
+	versions  [2]fileVersion
+
+	cache password.Cache
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	username, pwd, ok := r.BasicAuth()
+	if !ok {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	if err := a.reloadIfModified(); err != nil {
+		// Keep authenticating with the previously loaded files
+		slog.ErrorContext(r.Context(), "could not reload htpasswd files", slog.Any("error", errors.WithStack(err)))
+	}
+
+	a.mu.RLock()
+	hash, exists := a.passwords[username]
+	groups := a.groups[username]
+	a.mu.RUnlock()
+
+	if !exists {
+		password.VerifyDummy(pwd)
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "unknown user '%s'", username)
+	}
+
+	matches, err := a.cache.Verify(hash, pwd, Verify)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not verify password of user '%s'", username)
+	}
+
+	if !matches {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "invalid password for user '%s'", username)
+	}
+
+	return auth.NewUser(username, nil, groups...), nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *Authenticator) Challenge(realm string) string {
+	return auth.BasicChallenge(realm)
+}
+
+// reloadIfModified loads the files again if their modification time or size changed.
+func (a *Authenticator) reloadIfModified() error {
+	versions, err := a.stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.mu.RLock()
+	modified := versions != a.versions
+	a.mu.RUnlock()
+
+	if !modified {
+		return nil
+	}
+
+	return a.load(versions)
+}
+
+func (a *Authenticator) stat() ([2]fileVersion, error) {
+	var versions [2]fileVersion
+
+	for i, path := range []string{a.passwordFile, a.groupFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return versions, errors.WithStack(err)
+		}
+
+		versions[i] = fileVersion{info.ModTime(), info.Size()}
+	}
+
+	return versions, nil
+}
+
+func (a *Authenticator) load(versions [2]fileVersion) error {
+	passwords, err := parseFile(a.passwordFile, ParsePasswords)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	groups := map[string][]string{}
+	if a.groupFile != "" {
+		groups, err = parseFile(a.groupFile, ParseGroups)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.passwords = passwords
+	a.groups = groups
+	a.versions = versions
+
+	return nil
+}
+
+// NewAuthenticator loads the given htpasswd file and optional group file.
+func NewAuthenticator(passwordFile string, groupFile string) (*Authenticator, error) {
+	a := &Authenticator{
+		passwordFile: passwordFile,
+		groupFile:    groupFile,
+	}
+
+	versions, err := a.stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := a.load(versions); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return a, nil
+}
+
+var (
+	_ auth.Authenticator = &Authenticator{}
+	_ auth.Challenger    = &Authenticator{}
+)
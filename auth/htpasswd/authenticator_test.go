@@ -0,0 +1,99 @@
+package htpasswd
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/pkg/errors"
+)
+
+func TestApr1(t *testing.T) {
+	// Generated with "openssl passwd -apr1 -salt saltsalt secret"
+	if e, g := "$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0", apr1("secret", "saltsalt"); e != g {
+		t.Errorf("apr1(secret, saltsalt): expected %v, got %v", e, g)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	dir := t.TempDir()
+
+	passwordFile := filepath.Join(dir, "htpasswd")
+	groupFile := filepath.Join(dir, "htgroup")
+
+	writeFile(t, passwordFile, "# Users\n"+
+		"alice:$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0\n"+
+		"bob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n",
+	)
+
+	writeFile(t, groupFile, "staff: alice bob\nadmins: alice\n")
+
+	authenticator, err := NewAuthenticator(passwordFile, groupFile)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	authenticate := func(username, password string) ([]string, error) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetBasicAuth(username, password)
+
+		user, err := authenticator.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var groups []string
+		for _, g := range user.Groups() {
+			groups = append(groups, g.Name())
+		}
+
+		return groups, nil
+	}
+
+	groups, err := authenticate("alice", "secret")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 2, len(groups); e != g {
+		t.Errorf("len(groups): expected %v, got %v", e, g)
+	}
+
+	if _, err := authenticate("bob", "secret"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := authenticate("bob", "wrong"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+
+	// Modified files are reloaded
+	writeFile(t, passwordFile, "bob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n")
+
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(passwordFile, future, future); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := authenticate("alice", "secret"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+}
+
+func TestParsePasswordsUnsupportedHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	writeFile(t, path, "alice:rl0NCjGYqhNq.\n")
+
+	if _, err := NewAuthenticator(path, ""); err == nil {
+		t.Errorf("expected an error for crypt hashes")
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+}
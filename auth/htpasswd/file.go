@@ -0,0 +1,102 @@
+package htpasswd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParsePasswords parses an htpasswd file, made of "username:hash" lines.
+func ParsePasswords(r io.Reader) (map[string]string, error) {
+	passwords := make(map[string]string)
+
+	err := scanLines(r, func(lineNumber int, line string) error {
+		username, hash, found := strings.Cut(line, ":")
+		if !found || username == "" {
+			return errors.Errorf("line %d: expected 'username:hash'", lineNumber)
+		}
+
+		if !Supported(hash) {
+			return errors.Errorf("line %d: unsupported hash format for user '%s'", lineNumber, username)
+		}
+
+		passwords[username] = hash
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return passwords, nil
+}
+
+// ParseGroups parses an Apache group file, made of "group: user1 user2" lines,
+// and returns the groups of each user.
+func ParseGroups(r io.Reader) (map[string][]string, error) {
+	groups := make(map[string][]string)
+
+	err := scanLines(r, func(lineNumber int, line string) error {
+		group, members, found := strings.Cut(line, ":")
+		group = strings.TrimSpace(group)
+		if !found || group == "" {
+			return errors.Errorf("line %d: expected 'group: user1 user2'", lineNumber)
+		}
+
+		for _, username := range strings.Fields(members) {
+			groups[username] = append(groups[username], group)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return groups, nil
+}
+
+func scanLines(r io.Reader, fn func(lineNumber int, line string) error) error {
+	scanner := bufio.NewScanner(r)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := fn(lineNumber, line); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func parseFile[T any](path string, parse func(r io.Reader) (T, error)) (T, error) {
+	var zero T
+
+	file, err := os.Open(path)
+	if err != nil {
+		return zero, errors.WithStack(err)
+	}
+
+	defer file.Close()
+
+	value, err := parse(file)
+	if err != nil {
+		return zero, errors.Wrapf(err, "could not parse '%s'", path)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,127 @@
+package htpasswd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/bornholm/go-webdav/auth/password"
+	"github.com/pkg/errors"
+)
+
+const (
+	prefixSHA  = "{SHA}"
+	prefixAPR1 = "$apr1$"
+)
+
+// Verify returns true if password matches the given htpasswd hash.
+// In addition to the formats supported by [password.Verify], the SHA-1 ({SHA})
+// and Apache MD5 ($apr1$) schemes of the htpasswd tool are supported.
+// Both are weak and should only be kept for existing files.
+func Verify(hash string, pwd string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, prefixSHA):
+		sum := sha1.Sum([]byte(pwd))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash[len(prefixSHA):]), []byte(expected)) == 1, nil
+
+	case strings.HasPrefix(hash, prefixAPR1):
+		salt, _, found := strings.Cut(hash[len(prefixAPR1):], "$")
+		if !found {
+			return false, errors.New("invalid apr1 hash: missing salt")
+		}
+
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(apr1(pwd, salt))) == 1, nil
+
+	default:
+		return password.Verify(hash, pwd)
+	}
+}
+
+// Supported returns true if the format of the given hash is supported by [Verify].
+func Supported(hash string) bool {
+	return strings.HasPrefix(hash, prefixSHA) || strings.HasPrefix(hash, prefixAPR1) || password.Supported(hash)
+}
+
+const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1 returns the Apache variant of the MD5-based crypt hash of the given password.
+func apr1(pwd string, salt string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	p := []byte(pwd)
+	s := []byte(salt)
+
+	alternate := md5.Sum(bytes.Join([][]byte{p, s, p}, nil))
+
+	h := md5.New()
+	h.Write(p)
+	h.Write([]byte(prefixAPR1))
+	h.Write(s)
+
+	for i := len(p); i > 0; i -= 16 {
+		h.Write(alternate[:min(i, 16)])
+	}
+
+	for i := len(p); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(p[:1])
+		}
+	}
+
+	final := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(final)
+		}
+
+		if i%3 != 0 {
+			h.Write(s)
+		}
+
+		if i%7 != 0 {
+			h.Write(p)
+		}
+
+		if i&1 != 0 {
+			h.Write(final)
+		} else {
+			h.Write(p)
+		}
+
+		final = h.Sum(nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(prefixAPR1)
+	b.WriteString(salt)
+	b.WriteByte('$')
+
+	encode := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+
+	encode(uint32(final[0])<<16|uint32(final[6])<<8|uint32(final[12]), 4)
+	encode(uint32(final[1])<<16|uint32(final[7])<<8|uint32(final[13]), 4)
+	encode(uint32(final[2])<<16|uint32(final[8])<<8|uint32(final[14]), 4)
+	encode(uint32(final[3])<<16|uint32(final[9])<<8|uint32(final[15]), 4)
+	encode(uint32(final[4])<<16|uint32(final[10])<<8|uint32(final[5]), 4)
+	encode(uint32(final[11]), 2)
+
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package htpasswd
+
+import (
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "htpasswd"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	// Path of the htpasswd file
+	File string `mapstructure:"file" validate:"required"`
+	// Path of the Apache group file, optional
+	GroupFile string `mapstructure:"groupFile"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate htpasswd authenticator options")
+	}
+
+	authenticator, err := NewAuthenticator(opts.File, opts.GroupFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return authenticator, nil
+}
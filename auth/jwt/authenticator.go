@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// Authenticator authenticates JSON web tokens sent as bearer tokens, such as
+// the access tokens of an OpenID Connect provider.
+type Authenticator struct {
+	keys          KeySet
+	parser        *gojwt.Parser
+	usernameClaim string
+	groupsClaim   string
+}
+
+// Authenticate implements auth.Authenticator.
+// Bearer tokens which are not JWTs are left to the other authenticators.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	raw, ok := auth.BearerToken(r)
+	if !ok || strings.Count(raw, ".") != 2 {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	claims := gojwt.MapClaims{}
+
+	_, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc(r.Context()))
+	if err != nil {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "invalid token: %v", err)
+	}
+
+	username, _ := claims[a.usernameClaim].(string)
+	if username == "" {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "missing '%s' claim", a.usernameClaim)
+	}
+
+	attrs := map[string]any{
+		"claims": map[string]any(claims),
+	}
+
+	for _, name := range []string{"sub", "email", "name", "preferred_username"} {
+		if value, exists := claims[name]; exists {
+			attrs[name] = value
+		}
+	}
+
+	return auth.NewUser(username, attrs, stringsClaim(claims[a.groupsClaim])...), nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *Authenticator) Challenge(realm string) string {
+	return auth.BearerChallenge(realm)
+}
+
+// keyFunc returns the function verifying the token signature with each
+// candidate key of the key set.
+func (a *Authenticator) keyFunc(ctx context.Context) gojwt.Keyfunc {
+	return func(token *gojwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		keys, err := a.keys.Keys(ctx, kid)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		verificationKeys := make([]gojwt.VerificationKey, 0, len(keys))
+		for _, k := range keys {
+			verificationKeys = append(verificationKeys, k)
+		}
+
+		return gojwt.VerificationKeySet{Keys: verificationKeys}, nil
+	}
+}
+
+// stringsClaim returns the values of a claim holding either a list of
+// strings or a space separated string.
+func stringsClaim(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func NewAuthenticator(keys KeySet, funcs ...OptionFunc) *Authenticator {
+	opts := NewAuthenticatorOptions(funcs...)
+
+	parserOptions := []gojwt.ParserOption{
+		gojwt.WithValidMethods(opts.Algorithms),
+		gojwt.WithExpirationRequired(),
+		gojwt.WithLeeway(opts.Leeway),
+	}
+
+	if opts.Issuer != "" {
+		parserOptions = append(parserOptions, gojwt.WithIssuer(opts.Issuer))
+	}
+
+	if opts.Audience != "" {
+		parserOptions = append(parserOptions, gojwt.WithAudience(opts.Audience))
+	}
+
+	return &Authenticator{
+		keys:          keys,
+		parser:        gojwt.NewParser(parserOptions...),
+		usernameClaim: opts.UsernameClaim,
+		groupsClaim:   opts.GroupsClaim,
+	}
+}
+
+var (
+	_ auth.Authenticator = &Authenticator{}
+	_ auth.Challenger    = &Authenticator{}
+)
+
+type AuthenticatorOptions struct {
+	// Issuer, if not empty, is the expected "iss" claim
+	Issuer string
+	// Audience, if not empty, must be one of the "aud" claim values
+	Audience string
+	// Algorithms are the accepted signature algorithms
+	Algorithms []string
+	// Leeway is the tolerated clock skew when validating time based claims
+	Leeway time.Duration
+	// UsernameClaim is the claim holding the username
+	UsernameClaim string
+	// GroupsClaim is the claim holding the user groups
+	GroupsClaim string
+}
+
+type OptionFunc func(opts *AuthenticatorOptions)
+
+func WithIssuer(issuer string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.Issuer = issuer
+	}
+}
+
+func WithAudience(audience string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.Audience = audience
+	}
+}
+
+func WithAlgorithms(algorithms ...string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.Algorithms = algorithms
+	}
+}
+
+func WithLeeway(leeway time.Duration) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.Leeway = leeway
+	}
+}
+
+func WithUsernameClaim(claim string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.UsernameClaim = claim
+	}
+}
+
+func WithGroupsClaim(claim string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.GroupsClaim = claim
+	}
+}
+
+func NewAuthenticatorOptions(funcs ...OptionFunc) *AuthenticatorOptions {
+	opts := &AuthenticatorOptions{
+		Algorithms:    []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"},
+		Leeway:        30 * time.Second,
+		UsernameClaim: "sub",
+		GroupsClaim:   "groups",
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+func TestAuthenticatorJWKS(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	var fetches atomic.Int32
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"issuer": server.URL, "jwks_uri": server.URL + "/jwks"})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []any{
+				map[string]any{"kid": "key-1", "kty": "OKP", "crv": "Ed25519", "use": "sig", "x": base64.RawURLEncoding.EncodeToString(publicKey)},
+			},
+		})
+	})
+
+	authenticator, err := CreateAuthenticatorFromOptions(map[string]any{
+		"issuer":   server.URL,
+		"audience": "webdav",
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	now := time.Now()
+
+	validClaims := gojwt.MapClaims{
+		"iss":    server.URL,
+		"aud":    "webdav",
+		"sub":    "alice",
+		"email":  "alice@example.com",
+		"groups": []string{"staff", "admins"},
+		"exp":    now.Add(time.Hour).Unix(),
+	}
+
+	sign := func(claims gojwt.MapClaims, kid string) string {
+		token := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = kid
+
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		return signed
+	}
+
+	withClaim := func(name string, value any) gojwt.MapClaims {
+		claims := gojwt.MapClaims{}
+		for k, v := range validClaims {
+			claims[k] = v
+		}
+
+		claims[name] = value
+
+		return claims
+	}
+
+	type testCase struct {
+		Name        string
+		Token       string
+		ExpectedErr error
+	}
+
+	testCases := []testCase{
+		{Name: "valid", Token: sign(validClaims, "key-1")},
+		{Name: "expired", Token: sign(withClaim("exp", now.Add(-time.Hour).Unix()), "key-1"), ExpectedErr: auth.ErrInvalidCredentials},
+		{Name: "wrong issuer", Token: sign(withClaim("iss", "https://evil.example.com"), "key-1"), ExpectedErr: auth.ErrInvalidCredentials},
+		{Name: "wrong audience", Token: sign(withClaim("aud", "other"), "key-1"), ExpectedErr: auth.ErrInvalidCredentials},
+		{Name: "unknown key", Token: sign(validClaims, "key-2"), ExpectedErr: auth.ErrInvalidCredentials},
+		{Name: "opaque token", Token: "static-token", ExpectedErr: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+tc.Token)
+
+		user, err := authenticator.Authenticate(r)
+		if tc.ExpectedErr != nil {
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("%s: expected error '%v', got '%v'", tc.Name, tc.ExpectedErr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %+v", tc.Name, errors.WithStack(err))
+		}
+
+		if e, g := "alice", auth.Username(user); e != g {
+			t.Errorf("auth.Username(user): expected %v, got %v", e, g)
+		}
+
+		if e, g := "alice@example.com", user.Attrs()["email"]; e != g {
+			t.Errorf("user.Attrs()[email]: expected %v, got %v", e, g)
+		}
+
+		if e, g := 2, len(user.Groups()); e != g {
+			t.Errorf("len(user.Groups()): expected %v, got %v", e, g)
+		}
+	}
+
+	// The unknown key id triggers a single refresh, rate limited afterwards
+	if e, g := int32(1), fetches.Load(); e != g {
+		t.Errorf("fetches: expected %v, got %v", e, g)
+	}
+}
+
+func TestAuthenticatorKeyFile(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	authenticator, err := CreateAuthenticatorFromOptions(map[string]any{
+		"keyFile":           keyFile,
+		"usernameClaim":     "email",
+		"skipAudienceCheck": true,
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodES256, gojwt.MapClaims{
+		"sub":    "1234",
+		"email":  "bob@example.com",
+		"groups": "staff robots",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}).SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	user, err := authenticator.Authenticate(r)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "bob@example.com", auth.Username(user); e != g {
+		t.Errorf("auth.Username(user): expected %v, got %v", e, g)
+	}
+
+	if e, g := 2, len(user.Groups()); e != g {
+		t.Errorf("len(user.Groups()): expected %v, got %v", e, g)
+	}
+
+	// Symmetric algorithms are rejected
+	hmacToken, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"sub": "mallory",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(der)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	r.Header.Set("Authorization", "Bearer "+hmacToken)
+
+	if _, err := authenticator.Authenticate(r); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+}
+
+func TestOptionsValidation(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(keyFile, []byte(`{"keys":[]}`), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testCases := []struct {
+		Name    string
+		Options map[string]any
+		Valid   bool
+	}{
+		{
+			Name:    "missing audience",
+			Options: map[string]any{"keyFile": keyFile},
+			Valid:   false,
+		},
+		{
+			Name:    "skipped audience check",
+			Options: map[string]any{"keyFile": keyFile, "skipAudienceCheck": true},
+			Valid:   true,
+		},
+		{
+			Name:    "jwks without issuer",
+			Options: map[string]any{"jwksUrl": "https://idp.example.com/jwks", "audience": "webdav"},
+			Valid:   false,
+		},
+		{
+			Name:    "jwks with issuer",
+			Options: map[string]any{"jwksUrl": "https://idp.example.com/jwks", "issuer": "https://idp.example.com", "audience": "webdav"},
+			Valid:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := CreateAuthenticatorFromOptions(tc.Options)
+			if e, g := tc.Valid, err == nil; e != g {
+				t.Errorf("valid: expected %v, got %v (%v)", e, g, err)
+			}
+		})
+	}
+}
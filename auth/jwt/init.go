@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "jwt"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	// URL of the JSON web key set, discovered from the issuer if empty
+	JWKSURL string `mapstructure:"jwksUrl" validate:"omitempty,url"`
+	// Path of a JWKS file or of a PEM bundle of public keys, as an alternative to JWKSURL
+	KeyFile string `mapstructure:"keyFile"`
+	// Expected "iss" claim, mandatory unless the keys are read from KeyFile
+	Issuer string `mapstructure:"issuer" validate:"required_without=KeyFile"`
+	// Expected "aud" claim, mandatory unless SkipAudienceCheck is true
+	Audience          string `mapstructure:"audience" validate:"required_unless=SkipAudienceCheck true"`
+	SkipAudienceCheck bool   `mapstructure:"skipAudienceCheck"`
+	// Accepted signature algorithms, asymmetric ones by default
+	Algorithms    []string `mapstructure:"algorithms" validate:"dive,oneof=RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA"`
+	UsernameClaim string   `mapstructure:"usernameClaim"`
+	GroupsClaim   string   `mapstructure:"groupsClaim"`
+	// Leeway and RefreshInterval are expressed in seconds
+	Leeway          int `mapstructure:"leeway" validate:"gte=0"`
+	RefreshInterval int `mapstructure:"refreshInterval" validate:"gte=0"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate jwt authenticator options")
+	}
+
+	var keys KeySet
+
+	switch {
+	case opts.KeyFile != "":
+		keySet, err := LoadKeyFile(opts.KeyFile)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		keys = keySet
+
+	default:
+		client := &http.Client{Timeout: 10 * time.Second}
+
+		jwksURL := opts.JWKSURL
+		if jwksURL == "" {
+			discovered, err := DiscoverJWKS(context.Background(), client, opts.Issuer)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not discover jwks of issuer '%s'", opts.Issuer)
+			}
+
+			jwksURL = discovered
+		}
+
+		refreshInterval := time.Hour
+		if opts.RefreshInterval > 0 {
+			refreshInterval = time.Duration(opts.RefreshInterval) * time.Second
+		}
+
+		keys = NewRemoteKeySet(jwksURL, client, refreshInterval)
+	}
+
+	funcs := []OptionFunc{
+		WithIssuer(opts.Issuer),
+		WithAudience(opts.Audience),
+	}
+
+	if len(opts.Algorithms) > 0 {
+		funcs = append(funcs, WithAlgorithms(opts.Algorithms...))
+	}
+
+	if opts.Leeway > 0 {
+		funcs = append(funcs, WithLeeway(time.Duration(opts.Leeway)*time.Second))
+	}
+
+	if opts.UsernameClaim != "" {
+		funcs = append(funcs, WithUsernameClaim(opts.UsernameClaim))
+	}
+
+	if opts.GroupsClaim != "" {
+		funcs = append(funcs, WithGroupsClaim(opts.GroupsClaim))
+	}
+
+	return NewAuthenticator(keys, funcs...), nil
+}
@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// jsonWebKey is a public key of a JWK set, see RFC 7517.
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// ParseJWKS parses a JSON web key set and returns its signature keys, indexed by key id.
+// Keys of unsupported types are ignored.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "could not parse jwks")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key '%s'", jwk.Kid)
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key")
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.WithStack(errUnsupportedKey)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.WithStack(errUnsupportedKey)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("invalid ed25519 key size %d", len(x))
+		}
+
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, errors.WithStack(errUnsupportedKey)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// ParsePEM parses PEM encoded public keys and certificates.
+func ParsePEM(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			keys = append(keys, key)
+
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			keys = append(keys, cert.PublicKey)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no public key found")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,252 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeySet provides the keys verifying the signature of the tokens.
+type KeySet interface {
+	// Keys returns the candidate keys for the given key id, which may be empty.
+	Keys(ctx context.Context, kid string) ([]crypto.PublicKey, error)
+}
+
+// StaticKeySet is a fixed set of keys.
+type StaticKeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// Keys implements KeySet.
+func (s *StaticKeySet) Keys(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	return lookupKeys(s.keys, kid)
+}
+
+// NewStaticKeySet returns a key set made of the given keys, indexed by key id,
+// and of anonymous keys (i.e. PEM encoded keys) which are candidates for every token.
+func NewStaticKeySet(keys map[string]crypto.PublicKey, anonymous ...crypto.PublicKey) *StaticKeySet {
+	all := make(map[string]crypto.PublicKey, len(keys)+len(anonymous))
+	for kid, key := range keys {
+		all[kid] = key
+	}
+
+	for i, key := range anonymous {
+		all[anonymousKeyID(i)] = key
+	}
+
+	return &StaticKeySet{keys: all}
+}
+
+// LoadKeyFile loads a key set from a JWKS file or a PEM bundle of public keys and certificates.
+func LoadKeyFile(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		keys, err := ParseJWKS(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse '%s'", path)
+		}
+
+		return NewStaticKeySet(keys), nil
+	}
+
+	pemKeys, err := ParsePEM(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s'", path)
+	}
+
+	return NewStaticKeySet(nil, pemKeys...), nil
+}
+
+// RemoteKeySet fetches keys from a JWKS endpoint.
+// Keys are refreshed periodically and when a token references an unknown key id,
+// at most once per minimum refresh interval. Until a first fetch succeeds, it is
+// retried at the same pace, the last error being returned in between.
+type RemoteKeySet struct {
+	url                string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	lastErr   error
+}
+
+// Keys implements KeySet.
+func (s *RemoteKeySet) Keys(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := time.Since(s.fetchedAt)
+
+	if s.keys == nil && s.lastErr != nil && age < s.minRefreshInterval {
+		return nil, errors.WithStack(s.lastErr)
+	}
+
+	if s.keys == nil || age > s.refreshInterval {
+		if err := s.refresh(ctx); err != nil {
+			if s.keys == nil {
+				return nil, errors.WithStack(err)
+			}
+
+			// Keep using the previously fetched keys
+			slog.ErrorContext(ctx, "could not refresh jwks", slog.String("url", s.url), slog.Any("error", errors.WithStack(err)))
+		}
+	}
+
+	keys, err := lookupKeys(s.keys, kid)
+	if errors.Is(err, ErrKeyNotFound) && time.Since(s.fetchedAt) > s.minRefreshInterval {
+		// Keys may have been rotated
+		if err := s.refresh(ctx); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return lookupKeys(s.keys, kid)
+	}
+
+	return keys, err
+}
+
+func (s *RemoteKeySet) refresh(ctx context.Context) error {
+	// Prevent hammering an unavailable endpoint
+	s.fetchedAt = time.Now()
+
+	keys, err := s.fetchKeys(ctx)
+	if err != nil {
+		s.lastErr = err
+		return errors.WithStack(err)
+	}
+
+	s.keys = keys
+	s.lastErr = nil
+
+	return nil
+}
+
+func (s *RemoteKeySet) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	data, err := fetch(ctx, s.client, s.url)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return keys, nil
+}
+
+func NewRemoteKeySet(url string, client *http.Client, refreshInterval time.Duration) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RemoteKeySet{
+		url:                url,
+		client:             client,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: time.Minute,
+	}
+}
+
+// DiscoverJWKS returns the JWKS URL advertised by the given OpenID Connect issuer.
+func DiscoverJWKS(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	data, err := fetch(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	if err := json.Unmarshal(data, &discovery); err != nil {
+		return "", errors.Wrap(err, "could not parse openid configuration")
+	}
+
+	if discovery.JWKSURI == "" {
+		return "", errors.New("no jwks_uri in openid configuration")
+	}
+
+	return discovery.JWKSURI, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status '%s' for '%s'", res.Status, url)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return data, nil
+}
+
+// anonymousKeyID returns the internal id of the i-th key without id,
+// which can not collide with the ids of a JWK set.
+func anonymousKeyID(i int) string {
+	return "\x00" + strconv.Itoa(i)
+}
+
+// lookupKeys returns the key with the given id or, if there is no such key,
+// the keys without id. Every key is a candidate for tokens without key id.
+func lookupKeys(keys map[string]crypto.PublicKey, kid string) ([]crypto.PublicKey, error) {
+	if key, exists := keys[kid]; exists && kid != "" {
+		return []crypto.PublicKey{key}, nil
+	}
+
+	var candidates []crypto.PublicKey
+	for id, key := range keys {
+		if kid == "" || id == "" || strings.HasPrefix(id, "\x00") {
+			candidates = append(candidates, key)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.Wrapf(ErrKeyNotFound, "no key with id '%s'", kid)
+	}
+
+	return candidates, nil
+}
+
+var (
+	_ KeySet = &StaticKeySet{}
+	_ KeySet = &RemoteKeySet{}
+)
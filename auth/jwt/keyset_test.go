@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRemoteKeySetUnavailable(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	var (
+		fetches   atomic.Int32
+		available atomic.Bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []any{
+				map[string]any{"kid": "key-1", "kty": "OKP", "crv": "Ed25519", "use": "sig", "x": base64.RawURLEncoding.EncodeToString(publicKey)},
+			},
+		})
+	}))
+	defer server.Close()
+
+	keySet := NewRemoteKeySet(server.URL, nil, time.Hour)
+
+	ctx := context.Background()
+
+	// The failed initial fetch is not retried before the minimum refresh interval
+	for range 3 {
+		if _, err := keySet.Keys(ctx, "key-1"); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	}
+
+	if e, g := int32(1), fetches.Load(); e != g {
+		t.Errorf("fetches: expected %v, got %v", e, g)
+	}
+
+	available.Store(true)
+	keySet.fetchedAt = time.Now().Add(-2 * keySet.minRefreshInterval)
+
+	keys, err := keySet.Keys(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, len(keys); e != g {
+		t.Errorf("len(keys): expected %v, got %v", e, g)
+	}
+
+	if e, g := int32(2), fetches.Load(); e != g {
+		t.Errorf("fetches: expected %v, got %v", e, g)
+	}
+}
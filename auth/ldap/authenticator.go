@@ -0,0 +1,233 @@
+package ldap
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// Authenticator authenticates basic auth credentials by binding to an LDAP
+// directory as the user, and maps the directory groups of the user to [authz.Group].
+type Authenticator struct {
+	pool  *Pool
+	cache *bindCache
+	opts  *AuthenticatorOptions
+	now   func() time.Time
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	// Unauthenticated binds would otherwise succeed
+	if username == "" || password == "" {
+		return nil, errors.Wrap(auth.ErrInvalidCredentials, "empty username or password")
+	}
+
+	now := a.now()
+
+	if user, found := a.cache.Get(username, password, now); found {
+		return user, nil
+	}
+
+	conn, err := a.pool.Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to directory")
+	}
+
+	user, err := a.authenticate(conn, username, password)
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		// Idle connections may have been closed by the directory meanwhile,
+		// the authentication is retried once on a new connection
+		a.pool.Discard(conn)
+
+		conn, err = a.pool.Dial()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not connect to directory")
+		}
+
+		user, err = a.authenticate(conn, username, password)
+	}
+
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			a.pool.Put(conn)
+		} else {
+			a.pool.Discard(conn)
+		}
+
+		return nil, errors.WithStack(err)
+	}
+
+	a.pool.Put(conn)
+	a.cache.Put(username, password, user, now)
+
+	return user, nil
+}
+
+func (a *Authenticator) authenticate(conn Conn, username string, password string) (authz.User, error) {
+	if err := a.bindService(conn); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entry, err := a.findUser(conn, username)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	groups, err := a.findGroups(conn, entry.DN, username)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errors.Wrapf(auth.ErrInvalidCredentials, "invalid password for user '%s'", username)
+		}
+
+		return nil, errors.Wrapf(err, "could not bind as user '%s'", username)
+	}
+
+	attrs := map[string]any{
+		"dn": entry.DN,
+	}
+
+	for _, name := range a.opts.Attributes {
+		values := entry.GetAttributeValues(name)
+		switch len(values) {
+		case 0:
+		case 1:
+			attrs[name] = values[0]
+		default:
+			attrs[name] = values
+		}
+	}
+
+	return auth.NewUser(username, attrs, groups...), nil
+}
+
+// bindService binds the connection with the service account, or
+// anonymously if none is configured.
+func (a *Authenticator) bindService(conn Conn) error {
+	var err error
+	if a.opts.BindDN == "" {
+		err = conn.Bind("", "")
+	} else {
+		err = conn.Bind(a.opts.BindDN, a.opts.BindPassword)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "could not bind service account")
+	}
+
+	return nil
+}
+
+func (a *Authenticator) findUser(conn Conn, username string) (*ldap.Entry, error) {
+	filter := expandFilter(a.opts.UserFilter, map[string]string{
+		"username": username,
+	})
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		a.opts.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, 0, false,
+		filter,
+		a.opts.Attributes,
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, errors.Wrap(err, "could not search user")
+	}
+
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "unknown user '%s'", username)
+	case len(res.Entries) > 1:
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "ambiguous user '%s'", username)
+	}
+
+	return res.Entries[0], nil
+}
+
+func (a *Authenticator) findGroups(conn Conn, dn string, username string) ([]string, error) {
+	if a.opts.GroupFilter == "" {
+		return nil, nil
+	}
+
+	baseDN := a.opts.GroupBaseDN
+	if baseDN == "" {
+		baseDN = a.opts.BaseDN
+	}
+
+	filter := expandFilter(a.opts.GroupFilter, map[string]string{
+		"username": username,
+		"dn":       dn,
+	})
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		baseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		[]string{a.opts.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search groups")
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, entry := range res.Entries {
+		if name := entry.GetAttributeValue(a.opts.GroupAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+
+	return groups, nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *Authenticator) Challenge(realm string) string {
+	return auth.BasicChallenge(realm)
+}
+
+// Close closes the idle connections to the directory.
+func (a *Authenticator) Close() error {
+	return a.pool.Close()
+}
+
+// expandFilter replaces the {name} placeholders of the given filter with
+// their escaped values.
+func expandFilter(filter string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		pairs = append(pairs, "{"+name+"}", ldap.EscapeFilter(value))
+	}
+
+	return strings.NewReplacer(pairs...).Replace(filter)
+}
+
+func NewAuthenticator(dial DialFunc, funcs ...OptionFunc) *Authenticator {
+	opts := NewAuthenticatorOptions(funcs...)
+
+	return &Authenticator{
+		pool:  NewPool(dial, opts.PoolSize),
+		cache: newBindCache(opts.CacheTTL),
+		opts:  opts,
+		now:   time.Now,
+	}
+}
+
+var (
+	_ auth.Authenticator = &Authenticator{}
+	_ auth.Challenger    = &Authenticator{}
+)
@@ -0,0 +1,228 @@
+package ldap
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// directory is an in-process stand-in of an LDAP server, supporting
+// simple binds and single equality filter searches.
+type directory struct {
+	mu        sync.Mutex
+	passwords map[string]string // map[dn]password
+	entries   []*ldap.Entry
+	conns     []*directoryConn
+	dials     int
+	binds     int
+}
+
+func (d *directory) Dial() (Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dials++
+
+	conn := &directoryConn{directory: d}
+	d.conns = append(d.conns, conn)
+
+	return conn, nil
+}
+
+// disconnect closes the opened connections on the directory side, as done
+// for idle connections by most servers.
+func (d *directory) disconnect() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, c := range d.conns {
+		c.disconnected = true
+	}
+}
+
+type directoryConn struct {
+	directory    *directory
+	disconnected bool
+}
+
+func (c *directoryConn) Bind(dn string, password string) error {
+	d := c.directory
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if c.disconnected {
+		return ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection closed"))
+	}
+
+	d.binds++
+
+	if expected, exists := d.passwords[dn]; !exists || expected != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+
+	return nil
+}
+
+func (c *directoryConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d := c.directory
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if c.disconnected {
+		return nil, ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection closed"))
+	}
+
+	attr, value, found := strings.Cut(strings.Trim(req.Filter, "()"), "=")
+	if !found {
+		return nil, ldap.NewError(ldap.LDAPResultFilterError, errors.Errorf("unsupported filter '%s'", req.Filter))
+	}
+
+	res := &ldap.SearchResult{}
+	for _, entry := range d.entries {
+		if !strings.HasSuffix(entry.DN, req.BaseDN) {
+			continue
+		}
+
+		for _, v := range entry.GetAttributeValues(attr) {
+			if v == value {
+				res.Entries = append(res.Entries, entry)
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (c *directoryConn) Close() error {
+	return nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	const (
+		serviceDN = "cn=webdav,dc=example,dc=org"
+		aliceDN   = "uid=alice,ou=people,dc=example,dc=org"
+		bobDN     = "uid=bob,ou=people,dc=example,dc=org"
+	)
+
+	dir := &directory{
+		passwords: map[string]string{
+			serviceDN: "service",
+			aliceDN:   "alice-secret",
+			bobDN:     "bob-secret",
+		},
+		entries: []*ldap.Entry{
+			ldap.NewEntry(aliceDN, map[string][]string{"uid": {"alice"}, "mail": {"alice@example.org"}}),
+			ldap.NewEntry(bobDN, map[string][]string{"uid": {"bob"}}),
+			ldap.NewEntry("cn=staff,ou=groups,dc=example,dc=org", map[string][]string{"cn": {"staff"}, "member": {aliceDN, bobDN}}),
+			ldap.NewEntry("cn=admins,ou=groups,dc=example,dc=org", map[string][]string{"cn": {"admins"}, "member": {aliceDN}}),
+		},
+	}
+
+	authenticator := NewAuthenticator(
+		dir.Dial,
+		WithServiceAccount(serviceDN, "service"),
+		WithUserSearch("ou=people,dc=example,dc=org", "(uid={username})"),
+		WithGroupSearch("ou=groups,dc=example,dc=org", "(member={dn})", "cn"),
+		WithCacheTTL(time.Minute),
+	)
+
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+
+	authenticate := func(username, password string) (int, error) {
+		r := httptest.NewRequest("PROPFIND", "/", nil)
+		r.SetBasicAuth(username, password)
+
+		user, err := authenticator.Authenticate(r)
+		if err != nil {
+			return 0, err
+		}
+
+		if e, g := username, auth.Username(user); e != g {
+			t.Errorf("auth.Username(user): expected %v, got %v", e, g)
+		}
+
+		return len(user.Groups()), nil
+	}
+
+	totalGroups, err := authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 2, totalGroups; e != g {
+		t.Errorf("len(user.Groups()): expected %v, got %v", e, g)
+	}
+
+	binds := dir.binds
+
+	// Successful binds are cached
+	for range 10 {
+		if _, err := authenticate("alice", "alice-secret"); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	if e, g := binds, dir.binds; e != g {
+		t.Errorf("binds: expected %v, got %v", e, g)
+	}
+
+	if _, err := authenticate("alice", "wrong"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+
+	if _, err := authenticate("carol", "alice-secret"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+
+	// Empty passwords would be unauthenticated binds
+	if _, err := authenticate("alice", ""); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+
+	// Filters are escaped
+	if _, err := authenticate("*", "alice-secret"); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, err)
+	}
+
+	if totalGroups, err := authenticate("bob", "bob-secret"); err != nil || totalGroups != 1 {
+		t.Errorf("expected 1 group and no error, got %d and '%+v'", totalGroups, err)
+	}
+
+	// Connections are reused
+	if e, g := 1, dir.dials; e != g {
+		t.Errorf("dials: expected %v, got %v", e, g)
+	}
+
+	// Expired binds hit the directory again
+	now = now.Add(2 * time.Minute)
+
+	if _, err := authenticate("alice", "alice-secret"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if g := dir.binds; g <= binds {
+		t.Errorf("binds: expected more than %v, got %v", binds, g)
+	}
+
+	// Connections closed by the directory are replaced
+	dir.disconnect()
+	now = now.Add(2 * time.Minute)
+
+	if _, err := authenticate("alice", "alice-secret"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 2, dir.dials; e != g {
+		t.Errorf("dials: expected %v, got %v", e, g)
+	}
+}
@@ -0,0 +1,77 @@
+package ldap
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/bornholm/go-webdav/authz"
+)
+
+// bindCache remembers the users of successful binds for a limited time,
+// so that the many requests issued by a client do not each hit the directory.
+type bindCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]bindCacheEntry
+}
+
+type bindCacheEntry struct {
+	user    authz.User
+	expires time.Time
+}
+
+func (c *bindCache) Get(username string, password string, now time.Time) (authz.User, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := bindCacheKey(username, password)
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+
+	if now.After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.user, true
+}
+
+func (c *bindCache) Put(username string, password string, user authz.User, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[bindCacheKey(username, password)] = bindCacheEntry{
+		user:    user,
+		expires: now.Add(c.ttl),
+	}
+}
+
+func bindCacheKey(username string, password string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(username + "\x00" + password))
+}
+
+func newBindCache(ttl time.Duration) *bindCache {
+	return &bindCache{
+		ttl:     ttl,
+		entries: make(map[[sha256.Size]byte]bindCacheEntry),
+	}
+}
@@ -0,0 +1,111 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "ldap"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	// URL of the directory, i.e. ldap://localhost:389 or ldaps://localhost:636
+	URL                string `mapstructure:"url" validate:"required,url"`
+	StartTLS           bool   `mapstructure:"startTLS"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
+	BindDN             string `mapstructure:"bindDN"`
+	BindPassword       string `mapstructure:"bindPassword"`
+	BaseDN             string `mapstructure:"baseDN" validate:"required"`
+	UserFilter         string `mapstructure:"userFilter"`
+	GroupBaseDN        string `mapstructure:"groupBaseDN"`
+	GroupFilter        string `mapstructure:"groupFilter"`
+	GroupAttribute     string `mapstructure:"groupAttribute"`
+	// Attributes of the user entry exposed as user attributes, mail and displayName by default
+	Attributes []string `mapstructure:"attributes"`
+	PoolSize   int      `mapstructure:"poolSize" validate:"gte=0"`
+	// CacheTTL and Timeout are expressed in seconds, a negative CacheTTL disables the cache
+	CacheTTL int `mapstructure:"cacheTTL"`
+	Timeout  int `mapstructure:"timeout" validate:"gte=0"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate ldap authenticator options")
+	}
+
+	timeout := 10 * time.Second
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	dial := func() (Conn, error) {
+		conn, err := ldap.DialURL(opts.URL, ldap.DialWithTLSConfig(tlsConfig))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		conn.SetTimeout(timeout)
+
+		if opts.StartTLS {
+			if err := conn.StartTLS(tlsConfig); err != nil {
+				_ = conn.Close()
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		return conn, nil
+	}
+
+	defaults := NewAuthenticatorOptions()
+
+	funcs := []OptionFunc{
+		WithServiceAccount(opts.BindDN, opts.BindPassword),
+		WithUserSearch(opts.BaseDN, valueOr(opts.UserFilter, defaults.UserFilter)),
+		WithGroupSearch(opts.GroupBaseDN, valueOr(opts.GroupFilter, defaults.GroupFilter), valueOr(opts.GroupAttribute, defaults.GroupAttribute)),
+	}
+
+	if opts.Attributes != nil {
+		funcs = append(funcs, WithAttributes(opts.Attributes...))
+	}
+
+	if opts.PoolSize > 0 {
+		funcs = append(funcs, WithPoolSize(opts.PoolSize))
+	}
+
+	switch {
+	case opts.CacheTTL < 0:
+		funcs = append(funcs, WithCacheTTL(0))
+	case opts.CacheTTL > 0:
+		funcs = append(funcs, WithCacheTTL(time.Duration(opts.CacheTTL)*time.Second))
+	}
+
+	return NewAuthenticator(dial, funcs...), nil
+}
+
+func valueOr(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
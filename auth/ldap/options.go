@@ -0,0 +1,86 @@
+package ldap
+
+import "time"
+
+type AuthenticatorOptions struct {
+	// BindDN and BindPassword are the credentials of the service account
+	// searching the directory, which is searched anonymously if BindDN is empty
+	BindDN       string
+	BindPassword string
+	// BaseDN is the root of the user searches
+	BaseDN string
+	// UserFilter finds the entry of a user, {username} being replaced by the escaped username
+	UserFilter string
+	// GroupBaseDN is the root of the group searches, BaseDN if empty
+	GroupBaseDN string
+	// GroupFilter finds the groups of a user, {dn} and {username} being
+	// replaced by the escaped user DN and username. Groups are not resolved if empty
+	GroupFilter string
+	// GroupAttribute is the group entries attribute holding the group name
+	GroupAttribute string
+	// Attributes are the user entry attributes exposed as user attributes
+	Attributes []string
+	// PoolSize is the maximum number of idle connections
+	PoolSize int
+	// CacheTTL is the duration successful binds are remembered, disabled if zero
+	CacheTTL time.Duration
+}
+
+type OptionFunc func(opts *AuthenticatorOptions)
+
+func WithServiceAccount(bindDN string, bindPassword string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.BindDN = bindDN
+		opts.BindPassword = bindPassword
+	}
+}
+
+func WithUserSearch(baseDN string, filter string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.BaseDN = baseDN
+		opts.UserFilter = filter
+	}
+}
+
+func WithGroupSearch(baseDN string, filter string, attribute string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.GroupBaseDN = baseDN
+		opts.GroupFilter = filter
+		opts.GroupAttribute = attribute
+	}
+}
+
+func WithAttributes(attributes ...string) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.Attributes = attributes
+	}
+}
+
+func WithPoolSize(size int) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.PoolSize = size
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) OptionFunc {
+	return func(opts *AuthenticatorOptions) {
+		opts.CacheTTL = ttl
+	}
+}
+
+func NewAuthenticatorOptions(funcs ...OptionFunc) *AuthenticatorOptions {
+	opts := &AuthenticatorOptions{
+		UserFilter:     "(uid={username})",
+		GroupFilter:    "(member={dn})",
+		GroupAttribute: "cn",
+		Attributes:     []string{"mail", "displayName"},
+		PoolSize:       4,
+		CacheTTL:       time.Minute,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,98 @@
+package ldap
+
+import (
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// Conn is the subset of an LDAP connection used by the authenticator.
+type Conn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// DialFunc opens a new connection to the directory.
+type DialFunc func() (Conn, error)
+
+// Pool keeps idle connections to the directory for reuse.
+type Pool struct {
+	dial DialFunc
+	idle chan Conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Get returns an idle connection or opens a new one.
+func (p *Pool) Get() (Conn, error) {
+	select {
+	case conn, ok := <-p.idle:
+		if ok {
+			return conn, nil
+		}
+	default:
+	}
+
+	return p.Dial()
+}
+
+// Dial opens a new connection, regardless of the idle ones.
+func (p *Pool) Dial() (Conn, error) {
+	conn, err := p.dial()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return conn, nil
+}
+
+// Put returns a healthy connection to the pool, closing it if the pool is full.
+func (p *Pool) Put(conn Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		_ = conn.Close()
+		return
+	}
+
+	select {
+	case p.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+// Discard closes a connection which can not be reused.
+func (p *Pool) Discard(conn Conn) {
+	_ = conn.Close()
+}
+
+// Close closes the idle connections.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+	close(p.idle)
+
+	for conn := range p.idle {
+		_ = conn.Close()
+	}
+
+	return nil
+}
+
+func NewPool(dial DialFunc, size int) *Pool {
+	return &Pool{
+		dial: dial,
+		idle: make(chan Conn, size),
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+// Middleware authenticates requests with the given authenticators, tried in
+// order until one of them finds its credentials in the request.
+// The authenticated user is injected in the request context, see [authz.ContextUser].
+// Requests without valid credentials are rejected with 401.
+func Middleware(authenticators []Authenticator, funcs ...OptionFunc) func(http.Handler) http.Handler {
+	opts := NewOptions(funcs...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := Authenticate(r, authenticators...)
+			if err != nil {
+				if opts.OnError != nil {
+					opts.OnError(r, err)
+				}
+
+				var challenges []string
+				for _, a := range authenticators {
+					challenger, ok := a.(Challenger)
+					if !ok {
+						continue
+					}
+
+					// Several authenticators may share the same scheme
+					challenge := challenger.Challenge(opts.Realm)
+					if slices.Contains(challenges, challenge) {
+						continue
+					}
+
+					challenges = append(challenges, challenge)
+					w.Header().Add("WWW-Authenticate", challenge)
+				}
+
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(authz.WithContextUser(r.Context(), user)))
+		})
+	}
+}
+
+// Authenticate returns the user identified by the first of the given
+// authenticators accepting the request credentials.
+// Credentials rejected by an authenticator are submitted to the next ones, as
+// several of them may handle the same scheme (i.e. distinct basic auth user bases).
+// It returns [ErrInvalidCredentials] if at least one of them rejected the
+// credentials, and [ErrNoCredentials] if none of them found any.
+func Authenticate(r *http.Request, authenticators ...Authenticator) (authz.User, error) {
+	var rejected error
+
+	for _, a := range authenticators {
+		user, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if errors.Is(err, ErrInvalidCredentials) {
+			if rejected == nil {
+				rejected = err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if user == nil {
+			return nil, errors.New("no user returned by authenticator")
+		}
+
+		return user, nil
+	}
+
+	if rejected != nil {
+		return nil, errors.WithStack(rejected)
+	}
+
+	return nil, errors.WithStack(ErrNoCredentials)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+type testAuthenticator struct {
+	scheme string
+	user   authz.User
+}
+
+func (a *testAuthenticator) Authenticate(r *http.Request) (authz.User, error) {
+	value := r.Header.Get("Authorization")
+	if !strings.HasPrefix(value, a.scheme+" ") {
+		return nil, errors.WithStack(ErrNoCredentials)
+	}
+
+	if value != a.scheme+" valid" {
+		return nil, errors.WithStack(ErrInvalidCredentials)
+	}
+
+	return a.user, nil
+}
+
+func (a *testAuthenticator) Challenge(realm string) string {
+	return a.scheme + ` realm="` + realm + `"`
+}
+
+func TestMiddleware(t *testing.T) {
+	basic := &testAuthenticator{"Basic", NewUser("alice", nil)}
+	bearer := &testAuthenticator{"Bearer", NewUser("ci", nil)}
+
+	handler := Middleware([]Authenticator{basic, bearer}, WithRealm("test"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := authz.ContextUser(r.Context())
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		w.Write([]byte(Username(user)))
+	}))
+
+	type testCase struct {
+		Authorization  string
+		ExpectedStatus int
+		ExpectedBody   string
+	}
+
+	testCases := []testCase{
+		{Authorization: "Basic valid", ExpectedStatus: http.StatusOK, ExpectedBody: "alice"},
+		{Authorization: "Bearer valid", ExpectedStatus: http.StatusOK, ExpectedBody: "ci"},
+		{Authorization: "Bearer invalid", ExpectedStatus: http.StatusUnauthorized},
+		{Authorization: "", ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tc.Authorization != "" {
+			r.Header.Set("Authorization", tc.Authorization)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if e, g := tc.ExpectedStatus, w.Code; e != g {
+			t.Errorf("%s: status: expected %v, got %v", tc.Authorization, e, g)
+		}
+
+		if tc.ExpectedStatus != http.StatusOK {
+			if e, g := 2, len(w.Header().Values("WWW-Authenticate")); e != g {
+				t.Errorf("%s: len(WWW-Authenticate): expected %v, got %v", tc.Authorization, e, g)
+			}
+			continue
+		}
+
+		if e, g := tc.ExpectedBody, w.Body.String(); e != g {
+			t.Errorf("%s: body: expected %v, got %v", tc.Authorization, e, g)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"slices"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+// UsernameField is the certificate field holding the username.
+type UsernameField string
+
+const (
+	UsernameCommonName UsernameField = "cn"
+	UsernameEmail      UsernameField = "email"
+	UsernameDNS        UsernameField = "dns"
+	UsernameURI        UsernameField = "uri"
+)
+
+// Identity holds the groups and attributes added to the user of a certificate.
+type Identity struct {
+	Groups []string
+	Attrs  map[string]any
+}
+
+// Authenticator authenticates the client certificates of TLS connections.
+type Authenticator struct {
+	roots         *x509.CertPool
+	usernameField UsernameField
+	ouGroups      bool
+	identities    map[string]Identity
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+
+	if a.roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range r.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         a.roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(auth.ErrInvalidCredentials, "could not verify client certificate: %v", err)
+		}
+	} else if len(r.TLS.VerifiedChains) == 0 {
+		// Without dedicated roots, the certificate must have been verified by the server
+		return nil, errors.Wrap(auth.ErrInvalidCredentials, "client certificate has not been verified")
+	}
+
+	username := a.username(cert)
+	if username == "" {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "no '%s' in client certificate", a.usernameField)
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	attrs := map[string]any{
+		"commonName":   cert.Subject.CommonName,
+		"emails":       cert.EmailAddresses,
+		"dnsNames":     cert.DNSNames,
+		"organization": cert.Subject.Organization,
+		"serialNumber": cert.SerialNumber.String(),
+		"fingerprint":  hex.EncodeToString(fingerprint[:]),
+	}
+
+	var groups []string
+	if a.ouGroups {
+		groups = append(groups, cert.Subject.OrganizationalUnit...)
+	}
+
+	if identity, exists := a.identities[username]; exists {
+		for k, v := range identity.Attrs {
+			attrs[k] = v
+		}
+
+		for _, g := range identity.Groups {
+			if !slices.Contains(groups, g) {
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	return auth.NewUser(username, attrs, groups...), nil
+}
+
+func (a *Authenticator) username(cert *x509.Certificate) string {
+	switch a.usernameField {
+	case UsernameEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	case UsernameDNS:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case UsernameURI:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+
+	return ""
+}
+
+// NewAuthenticator returns an authenticator verifying client certificates
+// against the given roots or, if nil, trusting the verification made by the server.
+// Usernames are read from the given certificate field and, if ouGroups is true,
+// the organizational units of the certificate subject are mapped to groups.
+func NewAuthenticator(roots *x509.CertPool, usernameField UsernameField, ouGroups bool, identities map[string]Identity) *Authenticator {
+	if usernameField == "" {
+		usernameField = UsernameCommonName
+	}
+
+	return &Authenticator{
+		roots:         roots,
+		usernameField: usernameField,
+		ouGroups:      ouGroups,
+		identities:    identities,
+	}
+}
+
+var _ auth.Authenticator = &Authenticator{}
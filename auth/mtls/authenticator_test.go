@@ -0,0 +1,128 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+func TestAuthenticator(t *testing.T) {
+	ca, caKey := createCertificate(t, nil, nil, pkix.Name{CommonName: "Test CA"}, nil)
+	client, clientKey := createCertificate(t, ca, caKey, pkix.Name{CommonName: "alice", OrganizationalUnit: []string{"staff"}}, []string{"alice@example.com"})
+	other, otherKey := createCertificate(t, nil, nil, pkix.Name{CommonName: "mallory"}, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	authenticator := NewAuthenticator(roots, UsernameEmail, true, map[string]Identity{
+		"alice@example.com": {Groups: []string{"admins"}},
+	})
+
+	var (
+		user    authz.User
+		authErr error
+	)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, authErr = authenticator.Authenticate(r)
+	}))
+
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	request := func(cert *x509.Certificate, key *ecdsa.PrivateKey) {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = nil
+
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}}
+		}
+
+		client := &http.Client{Transport: transport}
+
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		res.Body.Close()
+	}
+
+	request(client, clientKey)
+
+	if authErr != nil {
+		t.Fatalf("%+v", errors.WithStack(authErr))
+	}
+
+	if e, g := "alice@example.com", auth.Username(user); e != g {
+		t.Errorf("auth.Username(user): expected %v, got %v", e, g)
+	}
+
+	if e, g := 2, len(user.Groups()); e != g {
+		t.Errorf("len(user.Groups()): expected %v, got %v", e, g)
+	}
+
+	request(other, otherKey)
+
+	if !errors.Is(authErr, auth.ErrInvalidCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrInvalidCredentials, authErr)
+	}
+
+	request(nil, nil)
+
+	if !errors.Is(authErr, auth.ErrNoCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrNoCredentials, authErr)
+	}
+}
+
+// createCertificate returns a certificate signed by the given parent or,
+// if nil, a self-signed certificate authority.
+func createCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, subject pkix.Name, emails []string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        subject,
+		EmailAddresses: emails,
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent = template
+		parentKey = key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	return cert, key
+}
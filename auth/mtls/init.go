@@ -0,0 +1,67 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"os"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "mtls"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	// Path of the PEM bundle of the certificate authorities issuing client
+	// certificates, optional if the server verifies them
+	CAFile   string                     `mapstructure:"caFile"`
+	Username string                     `mapstructure:"username" validate:"omitempty,oneof=cn email dns uri"`
+	OUGroups bool                       `mapstructure:"ouGroups"`
+	Users    map[string]IdentityOptions `mapstructure:"users"`
+}
+
+type IdentityOptions struct {
+	Groups []string       `mapstructure:"groups"`
+	Attrs  map[string]any `mapstructure:"attrs"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate mtls authenticator options")
+	}
+
+	var roots *x509.CertPool
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read client certificate authorities")
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in '%s'", opts.CAFile)
+		}
+	}
+
+	identities := make(map[string]Identity, len(opts.Users))
+	for username, u := range opts.Users {
+		identities[username] = Identity{
+			Groups: u.Groups,
+			Attrs:  u.Attrs,
+		}
+	}
+
+	return NewAuthenticator(roots, UsernameField(opts.Username), opts.OUGroups, identities), nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+type Options struct {
+	// Realm advertised in the authentication challenges
+	Realm string
+	// OnError, if not nil, is called when a request can not be authenticated
+	OnError func(r *http.Request, err error)
+}
+
+type OptionFunc func(opts *Options)
+
+func WithRealm(realm string) OptionFunc {
+	return func(opts *Options) {
+		opts.Realm = realm
+	}
+}
+
+func WithErrorHandler(fn func(r *http.Request, err error)) OptionFunc {
+	return func(opts *Options) {
+		opts.OnError = fn
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Realm: "go-webdav",
+		OnError: func(r *http.Request, err error) {
+			slog.DebugContext(r.Context(), "could not authenticate request", slog.Any("error", err))
+		},
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
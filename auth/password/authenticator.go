@@ -0,0 +1,81 @@
+package password
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is an account authenticated with a hashed password.
+type User struct {
+	Hash   string
+	Groups []string
+	Attrs  map[string]any
+}
+
+// Authenticator authenticates basic auth credentials against hashed passwords.
+type Authenticator struct {
+	users map[string]User
+	cache Cache
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	user, exists := a.users[username]
+	if !exists {
+		VerifyDummy(password)
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "unknown user '%s'", username)
+	}
+
+	matches, err := a.cache.Verify(user.Hash, password, Verify)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not verify password of user '%s'", username)
+	}
+
+	if !matches {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "invalid password for user '%s'", username)
+	}
+
+	return auth.NewUser(username, user.Attrs, user.Groups...), nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *Authenticator) Challenge(realm string) string {
+	return auth.BasicChallenge(realm)
+}
+
+func NewAuthenticator(users map[string]User) *Authenticator {
+	return &Authenticator{
+		users: users,
+	}
+}
+
+var (
+	_ auth.Authenticator = &Authenticator{}
+	_ auth.Challenger    = &Authenticator{}
+)
+
+// VerifyDummy compares the given password with a dummy bcrypt hash, to be
+// called when the user is unknown so that the response time does not reveal
+// the existing usernames.
+func VerifyDummy(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+}
+
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(errors.WithStack(err))
+	}
+
+	return hash
+})
@@ -0,0 +1,87 @@
+package password
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticator(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	argon2Hash, err := Hash("argon2-secret")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	authenticator := NewAuthenticator(map[string]User{
+		"alice": {Hash: string(bcryptHash), Groups: []string{"staff"}, Attrs: map[string]any{"team": "accounting"}},
+		"bob":   {Hash: argon2Hash},
+	})
+
+	type testCase struct {
+		Username    string
+		Password    string
+		ExpectedErr error
+	}
+
+	testCases := []testCase{
+		{Username: "alice", Password: "bcrypt-secret"},
+		{Username: "alice", Password: "bcrypt-secret"},
+		{Username: "alice", Password: "wrong", ExpectedErr: auth.ErrInvalidCredentials},
+		{Username: "bob", Password: "argon2-secret"},
+		{Username: "bob", Password: "wrong", ExpectedErr: auth.ErrInvalidCredentials},
+		{Username: "carol", Password: "bcrypt-secret", ExpectedErr: auth.ErrInvalidCredentials},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetBasicAuth(tc.Username, tc.Password)
+
+		user, err := authenticator.Authenticate(r)
+		if tc.ExpectedErr != nil {
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("%s/%s: expected error '%v', got '%v'", tc.Username, tc.Password, tc.ExpectedErr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s/%s: %+v", tc.Username, tc.Password, errors.WithStack(err))
+		}
+
+		if e, g := tc.Username, auth.Username(user); e != g {
+			t.Errorf("auth.Username(user): expected %v, got %v", e, g)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, err := authenticator.Authenticate(r); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("expected error '%v', got '%v'", auth.ErrNoCredentials, err)
+	}
+
+	r.SetBasicAuth("alice", "bcrypt-secret")
+
+	user, err := authenticator.Authenticate(r)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "accounting", user.Attrs()["team"]; e != g {
+		t.Errorf("user.Attrs()[team]: expected %v, got %v", e, g)
+	}
+
+	if e, g := 1, len(user.Groups()); e != g {
+		t.Fatalf("len(user.Groups()): expected %v, got %v", e, g)
+	}
+
+	if e, g := "staff", user.Groups()[0].Name(); e != g {
+		t.Errorf("user.Groups()[0].Name(): expected %v, got %v", e, g)
+	}
+}
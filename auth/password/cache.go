@@ -0,0 +1,35 @@
+package password
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// Cache remembers the successful verifications, sparing the cost of hashing
+// the password of each request of a client.
+// Only matching passwords are remembered, so its size is bounded by the number
+// of hashes.
+type Cache struct {
+	verified sync.Map // map[[sha256.Size]byte]struct{}
+}
+
+// Verify checks password against hash with the given function, unless it
+// has already been verified.
+func (c *Cache) Verify(hash string, password string, verify VerifyFunc) (bool, error) {
+	key := sha256.Sum256([]byte(hash + "\x00" + password))
+
+	if _, ok := c.verified.Load(key); ok {
+		return true, nil
+	}
+
+	ok, err := verify(hash, password)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		c.verified.Store(key, struct{}{})
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,130 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported hash")
+
+// Default argon2id parameters, as recommended by RFC 9106
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// VerifyFunc returns true if password matches the given hash.
+type VerifyFunc func(hash string, password string) (bool, error)
+
+// Verify returns true if password matches the given hash.
+// Supported formats are bcrypt ($2a$, $2b$, $2y$) and argon2 PHC strings ($argon2id$, $argon2i$).
+func Verify(hash string, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		return true, nil
+
+	case strings.HasPrefix(hash, "$argon2id$"), strings.HasPrefix(hash, "$argon2i$"):
+		return verifyArgon2(hash, password)
+
+	default:
+		return false, errors.WithStack(ErrUnsupportedHash)
+	}
+}
+
+// Supported returns true if the format of the given hash is supported by [Verify].
+func Supported(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$", "$argon2id$", "$argon2i$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hash returns the argon2id hash of the given password, as a PHC string.
+func Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyArgon2 checks password against a PHC string such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func verifyArgon2(hash string, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, errors.Errorf("invalid argon2 hash: unexpected number of fields")
+	}
+
+	variant := parts[1]
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, errors.Wrap(err, "invalid argon2 hash version")
+	}
+
+	if version != argon2.Version {
+		return false, errors.Errorf("unsupported argon2 version '%d'", version)
+	}
+
+	var (
+		memory  uint32
+		time    uint32
+		threads uint8
+	)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, errors.Wrap(err, "invalid argon2 hash parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errors.Wrap(err, "invalid argon2 hash salt")
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errors.Wrap(err, "invalid argon2 hash key")
+	}
+
+	var key []byte
+	switch variant {
+	case "argon2id":
+		key = argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	case "argon2i":
+		key = argon2.Key([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	default:
+		return false, errors.WithStack(ErrUnsupportedHash)
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
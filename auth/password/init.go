@@ -0,0 +1,52 @@
+package password
+
+import (
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "password"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	Users map[string]UserOptions `mapstructure:"users" validate:"required,dive"`
+}
+
+type UserOptions struct {
+	Hash   string         `mapstructure:"hash" validate:"required"`
+	Groups []string       `mapstructure:"groups"`
+	Attrs  map[string]any `mapstructure:"attrs"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate password authenticator options")
+	}
+
+	users := make(map[string]User, len(opts.Users))
+	for username, u := range opts.Users {
+		if !Supported(u.Hash) {
+			return nil, errors.Wrapf(ErrUnsupportedHash, "invalid hash for user '%s'", username)
+		}
+
+		users[username] = User{
+			Hash:   u.Hash,
+			Groups: u.Groups,
+			Attrs:  u.Attrs,
+		}
+	}
+
+	return NewAuthenticator(users), nil
+}
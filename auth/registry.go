@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"github.com/pkg/errors"
+)
+
+var ErrNotRegistered = errors.New("not registered")
+
+type Type string
+
+type Factory func(options any) (Authenticator, error)
+
+var factories = make(map[Type]Factory, 0)
+
+func Register(authType Type, factory Factory) {
+	factories[authType] = factory
+}
+
+func Registered() []Type {
+	types := make([]Type, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	return types
+}
+
+func New(authType Type, options any) (Authenticator, error) {
+	factory, exists := factories[authType]
+	if !exists {
+		return nil, errors.Wrapf(ErrNotRegistered, "no authenticator associated with type '%s'", authType)
+	}
+
+	authenticator, err := factory(options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return authenticator, nil
+}
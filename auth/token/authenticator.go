@@ -0,0 +1,77 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+)
+
+// Identity is the user a token authenticates.
+type Identity struct {
+	Username string
+	Groups   []string
+	Attrs    map[string]any
+}
+
+// Authenticator authenticates static API tokens sent as bearer tokens.
+// Tokens are indexed by their SHA-256 digest, so that the configuration
+// does not have to hold them in clear.
+type Authenticator struct {
+	identities map[[sha256.Size]byte]Identity
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *Authenticator) Authenticate(r *http.Request) (authz.User, error) {
+	token, ok := auth.BearerToken(r)
+	if !ok {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	identity, exists := a.identities[sha256.Sum256([]byte(token))]
+	if !exists {
+		return nil, errors.Wrap(auth.ErrInvalidCredentials, "unknown token")
+	}
+
+	return auth.NewUser(identity.Username, identity.Attrs, identity.Groups...), nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *Authenticator) Challenge(realm string) string {
+	return auth.BearerChallenge(realm)
+}
+
+// Add registers the given token.
+func (a *Authenticator) Add(token string, identity Identity) {
+	a.identities[sha256.Sum256([]byte(token))] = identity
+}
+
+// AddDigest registers the token with the given hex-encoded SHA-256 digest.
+func (a *Authenticator) AddDigest(digest string, identity Identity) error {
+	raw, err := hex.DecodeString(digest)
+	if err != nil {
+		return errors.Wrap(err, "invalid token digest")
+	}
+
+	if len(raw) != sha256.Size {
+		return errors.Errorf("invalid token digest length %d", len(raw))
+	}
+
+	a.identities[[sha256.Size]byte(raw)] = identity
+
+	return nil
+}
+
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{
+		identities: make(map[[sha256.Size]byte]Identity),
+	}
+}
+
+var (
+	_ auth.Authenticator = &Authenticator{}
+	_ auth.Challenger    = &Authenticator{}
+)
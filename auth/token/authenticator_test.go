@@ -0,0 +1,62 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/pkg/errors"
+)
+
+func TestAuthenticator(t *testing.T) {
+	digest := sha256.Sum256([]byte("hashed-token"))
+
+	authenticator, err := CreateAuthenticatorFromOptions(map[string]any{
+		"tokens": []any{
+			map[string]any{"token": "clear-token", "username": "ci", "groups": []string{"robots"}},
+			map[string]any{"sha256": hex.EncodeToString(digest[:]), "username": "backup"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	type testCase struct {
+		Authorization    string
+		ExpectedUsername string
+		ExpectedErr      error
+	}
+
+	testCases := []testCase{
+		{Authorization: "Bearer clear-token", ExpectedUsername: "ci"},
+		{Authorization: "bearer hashed-token", ExpectedUsername: "backup"},
+		{Authorization: "Bearer unknown", ExpectedErr: auth.ErrInvalidCredentials},
+		{Authorization: "Basic Y2k6Y2k=", ExpectedErr: auth.ErrNoCredentials},
+		{Authorization: "", ExpectedErr: auth.ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tc.Authorization != "" {
+			r.Header.Set("Authorization", tc.Authorization)
+		}
+
+		user, err := authenticator.Authenticate(r)
+		if tc.ExpectedErr != nil {
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("%s: expected error '%v', got '%v'", tc.Authorization, tc.ExpectedErr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %+v", tc.Authorization, errors.WithStack(err))
+		}
+
+		if e, g := tc.ExpectedUsername, auth.Username(user); e != g {
+			t.Errorf("%s: auth.Username(user): expected %v, got %v", tc.Authorization, e, g)
+		}
+	}
+}
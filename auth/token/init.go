@@ -0,0 +1,62 @@
+package token
+
+import (
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type auth.Type = "token"
+
+func init() {
+	auth.Register(Type, CreateAuthenticatorFromOptions)
+}
+
+type Options struct {
+	Tokens []TokenOptions `mapstructure:"tokens" validate:"required,dive"`
+}
+
+type TokenOptions struct {
+	// Token in clear
+	Token string `mapstructure:"token" validate:"required_without=SHA256"`
+	// Hex-encoded SHA-256 digest of the token, as an alternative to Token
+	SHA256   string         `mapstructure:"sha256" validate:"required_without=Token,omitempty,hexadecimal,len=64"`
+	Username string         `mapstructure:"username" validate:"required"`
+	Groups   []string       `mapstructure:"groups"`
+	Attrs    map[string]any `mapstructure:"attrs"`
+}
+
+func CreateAuthenticatorFromOptions(options any) (auth.Authenticator, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' authenticator options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate token authenticator options")
+	}
+
+	authenticator := NewAuthenticator()
+
+	for _, t := range opts.Tokens {
+		identity := Identity{
+			Username: t.Username,
+			Groups:   t.Groups,
+			Attrs:    t.Attrs,
+		}
+
+		if t.Token != "" {
+			authenticator.Add(t.Token, identity)
+			continue
+		}
+
+		if err := authenticator.AddDigest(t.SHA256, identity); err != nil {
+			return nil, errors.Wrapf(err, "invalid token of user '%s'", t.Username)
+		}
+	}
+
+	return authenticator, nil
+}
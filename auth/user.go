@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"maps"
+
+	"github.com/bornholm/go-webdav/authz"
+)
+
+// AttrUsername is the user attribute holding the authenticated username.
+const AttrUsername = "username"
+
+// NewUser returns an authenticated user with the given attributes and groups.
+// Groups do not hold any rule, those being attached by the authorization layer.
+func NewUser(username string, attrs map[string]any, groups ...string) *authz.BaseUser {
+	userAttrs := maps.Clone(attrs)
+	if userAttrs == nil {
+		userAttrs = map[string]any{}
+	}
+
+	userAttrs[AttrUsername] = username
+
+	userGroups := make([]*authz.Group, 0, len(groups))
+	for _, name := range groups {
+		userGroups = append(userGroups, authz.NewGroup(name))
+	}
+
+	return authz.NewUser(userAttrs, nil, userGroups...)
+}
+
+// Username returns the username attribute of the given user.
+func Username(user authz.User) string {
+	username, _ := user.Attrs()[AttrUsername].(string)
+	return username
+}
@@ -0,0 +1,20 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// CheckRename implements [webdav.RenameChecker].
+// The rename itself is authorized when performed.
+func (f *FileSystem) CheckRename(ctx context.Context, oldName string, newName string) error {
+	checker, ok := f.backend.(webdav.RenameChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.CheckRename(ctx, oldName, newName)
+}
+
+var _ webdav.RenameChecker = &FileSystem{}
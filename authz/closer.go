@@ -0,0 +1,14 @@
+package authz
+
+import (
+	"io"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// Close implements [io.Closer].
+func (f *FileSystem) Close() error {
+	return webdav.Close(f.backend)
+}
+
+var _ io.Closer = &FileSystem{}
@@ -0,0 +1,122 @@
+package authz
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// CopyDeadProps implements [webdav.DeadPropsCopier].
+// The source must be visible to the user and the destination writable. In the
+// recursive case, the descendant collections failing these checks are left out.
+func (f *FileSystem) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	copier, ok := f.backend.(webdav.DeadPropsCopier)
+	if !ok {
+		return nil
+	}
+
+	if err := f.assertCopyAuthorization(ctx, src, dst); err != nil {
+		return err
+	}
+
+	if err := copier.CopyDeadProps(ctx, src, dst, false); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	return f.copyDescendantsDeadProps(ctx, copier, src, dst)
+}
+
+// copyDescendantsDeadProps copies the dead properties of the descendant
+// collections of the destination the user is allowed to copy.
+func (f *FileSystem) copyDescendantsDeadProps(ctx context.Context, copier webdav.DeadPropsCopier, src string, dst string) error {
+	dir, err := f.backend.OpenFile(ctx, dst, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	children, err := dir.Readdir(-1)
+	if err != nil {
+		_ = dir.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := dir.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, child := range children {
+		if !child.IsDir() {
+			continue
+		}
+
+		childSrc, childDst := path.Join(src, child.Name()), path.Join(dst, child.Name())
+
+		if err := f.assertCopyAuthorization(ctx, childSrc, childDst); err != nil {
+			if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return err
+		}
+
+		if err := copier.CopyDeadProps(ctx, childSrc, childDst, false); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := f.copyDescendantsDeadProps(ctx, copier, childSrc, childDst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CopyFile implements [webdav.FileCopier].
+// The source must be readable by the user and the destination writable.
+func (f *FileSystem) CopyFile(ctx context.Context, src string, dst string) error {
+	copier, ok := f.backend.(webdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	err := f.assertAuthorization(ctx, OperationOpen, map[string]any{
+		"name": src,
+		"flag": os.O_RDONLY,
+		"perm": os.FileMode(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := f.assertDestinationAuthorization(ctx, src, dst); err != nil {
+		return err
+	}
+
+	return copier.CopyFile(ctx, src, dst)
+}
+
+// assertCopyAuthorization checks that the source of a copy is visible to the
+// user and that its destination can be created.
+func (f *FileSystem) assertCopyAuthorization(ctx context.Context, src string, dst string) error {
+	err := f.assertAuthorization(ctx, OperationStat, map[string]any{
+		"name": src,
+	})
+	if err != nil {
+		return err
+	}
+
+	return f.assertDestinationAuthorization(ctx, src, dst)
+}
+
+var (
+	_ webdav.DeadPropsCopier = &FileSystem{}
+	_ webdav.FileCopier      = &FileSystem{}
+)
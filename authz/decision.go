@@ -0,0 +1,24 @@
+package authz
+
+import "context"
+
+// Decision describes the outcome of an authorization check.
+type Decision struct {
+	User      User
+	Operation Operation
+	// Params are the operation parameters (name, flag...) made available to the rules
+	Params map[string]any
+	// Allowed is true if the operation has been authorized
+	Allowed bool
+	// Rule is the rule which decided the operation, nil if no rule
+	// matched and the operation has been denied by default
+	Rule Rule
+	// Effect is the effect of the deciding rule, if any
+	Effect Effect
+	// Group is the name of the group the deciding rule belongs to,
+	// empty if it is one of the user's own rules
+	Group string
+}
+
+// DecisionFunc is called after each authorization check, for audit or debugging purposes.
+type DecisionFunc func(ctx context.Context, decision Decision)
@@ -1,29 +1,286 @@
 package expr
 
 import (
-	"reflect"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/expr-lang/expr"
-	"github.com/expr-lang/expr/builtin"
 	"github.com/expr-lang/expr/conf"
+	"github.com/pkg/errors"
 )
 
-var functions = []builtin.Function{
-	{
-		Name: "isRead",
-		Func: func(args ...any) (any, error) {
-			return nil, nil
+// Operations, mirroring the authz package ones
+const (
+	opMkdir  = "mkdir"
+	opOpen   = "open"
+	opRemove = "remove"
+	opRename = "rename"
+	opStat   = "stat"
+)
+
+// writeFlags are the open flags implying a modification of the file
+const writeFlags = os.O_WRONLY | os.O_APPEND | os.O_RDWR | os.O_TRUNC | os.O_CREATE
+
+// HomeDir is the directory holding the users home directories, as used by isOwnHome().
+const HomeDir = "/home"
+
+// functions are the rule helpers independent of the evaluated operation
+var functions = []expr.Option{
+	expr.Function(
+		"hasPrefix",
+		func(params ...any) (any, error) {
+			return hasPrefix(params[0].(string), params[1].(string)), nil
+		},
+		hasPrefix,
+	),
+	expr.Function(
+		"glob",
+		func(params ...any) (any, error) {
+			return glob(params[0].(string), params[1].(string)), nil
 		},
-		Types: []reflect.Type{
-			reflect.TypeFor[int](),
+		glob,
+	),
+	expr.Function(
+		"parentOf",
+		func(params ...any) (any, error) {
+			return parentOf(params[0].(string)), nil
 		},
-	},
+		parentOf,
+	),
 }
 
+// WithRuleAPI registers the helpers independent of the evaluated operation.
 func WithRuleAPI() expr.Option {
 	return func(c *conf.Config) {
 		for _, fn := range functions {
-			c.Functions[fn.Name] = &fn
+			fn(c)
 		}
 	}
 }
+
+// env returns a prototype of the rules environment, used to type check
+// rules at compile time.
+func env() map[string]any {
+	return map[string]any{
+		"operation": "",
+		"name":      "",
+		"oldName":   "",
+		"newName":   "",
+		"flag":      0,
+		"perm":      os.FileMode(0),
+		"user":      map[string]any{},
+		"groups":    []string{},
+
+		"OP_MKDIR":  opMkdir,
+		"OP_OPEN":   opOpen,
+		"OP_REMOVE": opRemove,
+		"OP_RENAME": opRename,
+		"OP_STAT":   opStat,
+
+		"O_APPEND": os.O_APPEND,
+		"O_RDONLY": os.O_RDONLY,
+		"O_WRONLY": os.O_WRONLY,
+		"O_RDWR":   os.O_RDWR,
+		"O_CREATE": os.O_CREATE,
+		"O_EXCL":   os.O_EXCL,
+		"O_SYNC":   os.O_SYNC,
+		"O_TRUNC":  os.O_TRUNC,
+		"O_WRITE":  writeFlags,
+
+		"isRead":      func() bool { return false },
+		"isWrite":     func() bool { return false },
+		"inGroup":     func(group string) bool { return false },
+		"isOwnHome":   func() bool { return false },
+		"hour":        func() int { return 0 },
+		"weekday":     func() string { return "" },
+		"withinHours": func(from int, to int) bool { return false },
+	}
+}
+
+// bindEnv completes the given environment with the constants and the
+// helpers depending on the evaluated operation.
+func bindEnv(env map[string]any, now time.Time) error {
+	for _, key := range []string{"operation", "name", "oldName", "newName"} {
+		if _, exists := env[key]; !exists {
+			env[key] = ""
+		}
+	}
+
+	if _, exists := env["flag"]; !exists {
+		env["flag"] = 0
+	}
+
+	if _, exists := env["perm"]; !exists {
+		env["perm"] = os.FileMode(0)
+	}
+
+	operation, ok := env["operation"].(string)
+	if !ok {
+		return errors.Errorf("unexpected operation type '%T'", env["operation"])
+	}
+
+	flag, ok := env["flag"].(int)
+	if !ok {
+		return errors.Errorf("unexpected flag type '%T'", env["flag"])
+	}
+
+	groups, _ := env["groups"].([]string)
+	user, _ := env["user"].(map[string]any)
+
+	// Names targeted by the operation
+	var names []string
+	for _, key := range []string{"name", "oldName", "newName"} {
+		if name, _ := env[key].(string); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	env["O_APPEND"] = os.O_APPEND
+	env["O_RDONLY"] = os.O_RDONLY
+	env["O_WRONLY"] = os.O_WRONLY
+	env["O_RDWR"] = os.O_RDWR
+	env["O_CREATE"] = os.O_CREATE
+	env["O_EXCL"] = os.O_EXCL
+	env["O_SYNC"] = os.O_SYNC
+	env["O_TRUNC"] = os.O_TRUNC
+
+	// Meta
+	env["O_WRITE"] = writeFlags
+
+	env["isRead"] = func() bool {
+		return isRead(operation, flag)
+	}
+
+	env["isWrite"] = func() bool {
+		return !isRead(operation, flag)
+	}
+
+	env["inGroup"] = func(group string) bool {
+		return slices.Contains(groups, group)
+	}
+
+	env["isOwnHome"] = func() bool {
+		username, _ := user["username"].(string)
+		if username == "" || strings.Contains(username, "/") || len(names) == 0 {
+			return false
+		}
+
+		home := path.Join(HomeDir, username)
+		for _, n := range names {
+			if !hasPrefix(n, home) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	env["hour"] = func() int {
+		return now.Hour()
+	}
+
+	env["weekday"] = func() string {
+		return strings.ToLower(now.Weekday().String())
+	}
+
+	env["withinHours"] = func(from int, to int) bool {
+		return withinHours(now.Hour(), from, to)
+	}
+
+	return nil
+}
+
+// isRead returns true if the operation does not modify the filesystem.
+func isRead(operation string, flag int) bool {
+	switch operation {
+	case opStat:
+		return true
+	case opOpen:
+		return flag&writeFlags == 0
+	default:
+		return false
+	}
+}
+
+// hasPrefix returns true if name is dir or one of its descendants.
+func hasPrefix(name string, dir string) bool {
+	name = path.Clean("/" + name)
+	dir = path.Clean("/" + dir)
+
+	if dir == "/" || name == dir {
+		return true
+	}
+
+	return strings.HasPrefix(name, dir+"/")
+}
+
+// parentOf returns the parent directory of name, the root being its own parent.
+func parentOf(name string) string {
+	return path.Dir(path.Clean("/" + name))
+}
+
+// glob returns true if name matches the given pattern.
+// Patterns follow the [path.Match] syntax, with an additional "**"
+// segment matching any number of path segments.
+func glob(pattern string, name string) bool {
+	return matchSegments(splitSegments(pattern), splitSegments(name))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive "**" segments
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 0 {
+				return true
+			}
+
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil || !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+func splitSegments(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return nil
+	}
+
+	return strings.Split(p, "/")
+}
+
+// withinHours returns true if hour is in the [from, to) range, which wraps
+// around midnight when from is greater than to.
+func withinHours(hour int, from int, to int) bool {
+	if from <= to {
+		return hour >= from && hour < to
+	}
+
+	return hour >= from || hour < to
+}
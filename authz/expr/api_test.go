@@ -0,0 +1,117 @@
+package expr
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRuleAPI(t *testing.T) {
+	// A monday, 10:30
+	clock := func() time.Time {
+		return time.Date(2025, time.June, 2, 10, 30, 0, 0, time.UTC)
+	}
+
+	type testCase struct {
+		Script   string
+		Env      map[string]any
+		Expected bool
+	}
+
+	openEnv := func(name string, flag int) map[string]any {
+		return map[string]any{
+			"operation": opOpen,
+			"name":      name,
+			"flag":      flag,
+			"perm":      os.FileMode(0644),
+			"user":      map[string]any{"username": "alice"},
+			"groups":    []string{"staff"},
+		}
+	}
+
+	renameEnv := func(oldName, newName string) map[string]any {
+		return map[string]any{
+			"operation": opRename,
+			"oldName":   oldName,
+			"newName":   newName,
+			"user":      map[string]any{"username": "alice"},
+			"groups":    []string{},
+		}
+	}
+
+	testCases := []testCase{
+		{Script: "isRead()", Env: openEnv("/file.txt", os.O_RDONLY), Expected: true},
+		{Script: "isRead()", Env: openEnv("/file.txt", os.O_RDWR|os.O_CREATE), Expected: false},
+		{Script: "isWrite()", Env: openEnv("/file.txt", os.O_WRONLY|os.O_TRUNC), Expected: true},
+		{Script: "isWrite()", Env: renameEnv("/a", "/b"), Expected: true},
+		{Script: "isRead()", Env: map[string]any{"operation": opStat, "name": "/"}, Expected: true},
+
+		{Script: "hasPrefix(name, '/shared')", Env: openEnv("/shared", 0), Expected: true},
+		{Script: "hasPrefix(name, '/shared')", Env: openEnv("/shared/hr/file.txt", 0), Expected: true},
+		{Script: "hasPrefix(name, '/shared')", Env: openEnv("/shared-old/file.txt", 0), Expected: false},
+		{Script: "hasPrefix(name, '/')", Env: openEnv("/any/file.txt", 0), Expected: true},
+
+		{Script: "glob('/shared/*.txt', name)", Env: openEnv("/shared/file.txt", 0), Expected: true},
+		{Script: "glob('/shared/*.txt', name)", Env: openEnv("/shared/sub/file.txt", 0), Expected: false},
+		{Script: "glob('/shared/**/*.txt', name)", Env: openEnv("/shared/file.txt", 0), Expected: true},
+		{Script: "glob('/shared/**/*.txt', name)", Env: openEnv("/shared/a/b/file.txt", 0), Expected: true},
+		{Script: "glob('/shared/**', name)", Env: openEnv("/shared", 0), Expected: true},
+		{Script: "glob('/shared/**', name)", Env: openEnv("/other/file.txt", 0), Expected: false},
+
+		{Script: "parentOf(name) == '/shared'", Env: openEnv("/shared/file.txt", 0), Expected: true},
+		{Script: "parentOf(name) == '/'", Env: openEnv("/", 0), Expected: true},
+		{Script: "hasPrefix(parentOf(newName), '/archive')", Env: renameEnv("/a.txt", "/archive/a.txt"), Expected: true},
+
+		{Script: "inGroup('staff')", Env: openEnv("/file.txt", 0), Expected: true},
+		{Script: "inGroup('admin')", Env: openEnv("/file.txt", 0), Expected: false},
+
+		{Script: "isOwnHome()", Env: openEnv("/home/alice/file.txt", 0), Expected: true},
+		{Script: "isOwnHome()", Env: openEnv("/home/alice", 0), Expected: true},
+		{Script: "isOwnHome()", Env: openEnv("/home/alicia/file.txt", 0), Expected: false},
+		{Script: "isOwnHome()", Env: renameEnv("/home/alice/a.txt", "/home/bob/a.txt"), Expected: false},
+		{Script: "isOwnHome()", Env: renameEnv("/home/alice/a.txt", "/home/alice/b.txt"), Expected: true},
+
+		{Script: "hour() == 10", Env: openEnv("/file.txt", 0), Expected: true},
+		{Script: "weekday() == 'monday'", Env: openEnv("/file.txt", 0), Expected: true},
+		{Script: "withinHours(9, 18)", Env: openEnv("/file.txt", 0), Expected: true},
+		{Script: "withinHours(22, 6)", Env: openEnv("/file.txt", 0), Expected: false},
+		{Script: "withinHours(11, 12)", Env: openEnv("/file.txt", 0), Expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Script, func(t *testing.T) {
+			rule := NewRule(tc.Script, WithClock(clock))
+
+			allowed, err := rule.Exec(tc.Env)
+			if err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := tc.Expected, allowed; e != g {
+				t.Errorf("%s (env: %v): expected %v, got %v", tc.Script, tc.Env, e, g)
+			}
+		})
+	}
+}
+
+func TestRuleTypeCheck(t *testing.T) {
+	scripts := []string{
+		"isRead(name)",
+		"hasPrefix(name)",
+		"hasPrefix(flag, '/shared')",
+		"inGroup(1)",
+		"withinHours('9', 18)",
+		"unknownVariable == 1",
+		"parentOf(name)",
+	}
+
+	for _, script := range scripts {
+		rule := NewRule(script)
+
+		if err := rule.Compile(); err == nil {
+			t.Errorf("%s: expected compilation error, got nil", script)
+		}
+	}
+}
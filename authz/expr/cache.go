@@ -28,7 +28,8 @@ func (c *Cache) Get(script string) (*vm.Program, error) {
 		return cached.Program, nil
 	}
 
-	program, err := expr.Compile(script, expr.AsBool(), WithRuleAPI())
+	// Rules are type checked against a prototype of their environment
+	program, err := expr.Compile(script, expr.Env(env()), expr.AsBool(), WithRuleAPI())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -0,0 +1,78 @@
+package expr_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bornholm/go-webdav/authz/expr"
+)
+
+func ExampleNewRule() {
+	// Everyone may read /shared, except /shared/hr which is reserved to the hr group
+	rule := expr.NewRule(`isRead() && hasPrefix(name, "/shared") && (!hasPrefix(name, "/shared/hr") || inGroup("hr"))`)
+
+	for _, name := range []string{"/shared/report.pdf", "/shared/hr/salaries.xlsx"} {
+		allowed, err := rule.Exec(map[string]any{
+			"operation": "open",
+			"name":      name,
+			"flag":      os.O_RDONLY,
+			"user":      map[string]any{"username": "alice"},
+			"groups":    []string{"staff"},
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(name, allowed)
+	}
+
+	// Output:
+	// /shared/report.pdf true
+	// /shared/hr/salaries.xlsx false
+}
+
+func ExampleNewRule_home() {
+	// Users may do anything in their own home directory, i.e. /home/{username}
+	rule := expr.NewRule(`isOwnHome()`)
+
+	for _, name := range []string{"/home/alice/notes.md", "/home/bob/notes.md"} {
+		allowed, err := rule.Exec(map[string]any{
+			"operation": "open",
+			"name":      name,
+			"flag":      os.O_RDWR | os.O_CREATE,
+			"user":      map[string]any{"username": "alice"},
+			"groups":    []string{},
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(name, allowed)
+	}
+
+	// Output:
+	// /home/alice/notes.md true
+	// /home/bob/notes.md false
+}
+
+func ExampleNewRule_glob() {
+	// "**" matches any number of directories
+	rule := expr.NewRule(`glob("/projects/**/*.md", name)`)
+
+	for _, name := range []string{"/projects/README.md", "/projects/a/b/notes.md", "/projects/a/b/image.png"} {
+		allowed, err := rule.Exec(map[string]any{
+			"operation": "stat",
+			"name":      name,
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(name, allowed)
+	}
+
+	// Output:
+	// /projects/README.md true
+	// /projects/a/b/notes.md true
+	// /projects/a/b/image.png false
+}
@@ -1,7 +1,7 @@
 package expr
 
 import (
-	"os"
+	"time"
 
 	"github.com/bornholm/go-webdav/authz"
 	"github.com/expr-lang/expr"
@@ -11,6 +11,8 @@ import (
 
 type Rule struct {
 	script string
+	effect authz.Effect
+	clock  func() time.Time
 }
 
 // Exec implements authz.Rule.
@@ -20,17 +22,9 @@ func (r *Rule) Exec(env map[string]any) (bool, error) {
 		return false, errors.WithStack(err)
 	}
 
-	env["O_APPEND"] = os.O_APPEND
-	env["O_RDONLY"] = os.O_RDONLY
-	env["O_WRONLY"] = os.O_WRONLY
-	env["O_RDWR"] = os.O_RDWR
-	env["O_CREATE"] = os.O_CREATE
-	env["O_EXCL"] = os.O_EXCL
-	env["O_SYNC"] = os.O_SYNC
-	env["O_TRUNC"] = os.O_TRUNC
-
-	// Meta
-	env["O_WRITE"] = os.O_WRONLY | os.O_APPEND | os.O_RDWR | os.O_TRUNC | os.O_CREATE
+	if err := bindEnv(env, r.clock()); err != nil {
+		return false, errors.WithStack(err)
+	}
 
 	result, err := expr.Run(program, env)
 	if err != nil {
@@ -45,6 +39,16 @@ func (r *Rule) Exec(env map[string]any) (bool, error) {
 	return allowed, nil
 }
 
+// Compile compiles the rule script, so that syntax errors can be
+// reported before the rule is executed.
+func (r *Rule) Compile() error {
+	if _, err := r.getProgram(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
 func (r *Rule) getProgram() (*vm.Program, error) {
 	program, err := defaultCache.Get(r.script)
 	if err != nil {
@@ -54,12 +58,61 @@ func (r *Rule) getProgram() (*vm.Program, error) {
 	return program, nil
 }
 
+// Effect implements authz.EffectRule.
+func (r *Rule) Effect() authz.Effect {
+	return r.effect
+}
+
 func (r *Rule) String() string {
+	if r.effect == authz.EffectDeny {
+		return "deny: " + r.script
+	}
+
 	return r.script
 }
 
-func NewRule(script string) *Rule {
-	return &Rule{script: script}
+type Options struct {
+	// Effect of the operations matched by the rule, defaults to [authz.EffectAllow]
+	Effect authz.Effect
+	// Clock returns the current time, as used by the time of day helpers
+	Clock func() time.Time
+}
+
+type OptionFunc func(opts *Options)
+
+func WithEffect(effect authz.Effect) OptionFunc {
+	return func(opts *Options) {
+		opts.Effect = effect
+	}
+}
+
+func WithClock(clock func() time.Time) OptionFunc {
+	return func(opts *Options) {
+		opts.Clock = clock
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Effect: authz.EffectAllow,
+		Clock:  time.Now,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
+
+func NewRule(script string, funcs ...OptionFunc) *Rule {
+	opts := NewOptions(funcs...)
+
+	return &Rule{
+		script: script,
+		effect: opts.Effect,
+		clock:  opts.Clock,
+	}
 }
 
-var _ authz.Rule = &Rule{}
+var _ authz.EffectRule = &Rule{}
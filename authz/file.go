@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// File is a [webdav.File] whose directory listings only contain the
+// entries the user is allowed to stat.
+type File struct {
+	ctx  context.Context
+	name string
+	file webdav.File
+	fs   *FileSystem
+}
+
+// Close implements webdav.File.
+func (f *File) Close() error {
+	return f.file.Close()
+}
+
+// Read implements webdav.File.
+func (f *File) Read(p []byte) (n int, err error) {
+	return f.file.Read(p)
+}
+
+// Readdir implements webdav.File.
+// Entries the user is not allowed to stat are omitted. When count is positive,
+// the backend is read again until count entries are visible or the directory is exhausted.
+func (f *File) Readdir(count int) ([]fs.FileInfo, error) {
+	if count <= 0 {
+		children, err := f.file.Readdir(count)
+		if err != nil {
+			return nil, err
+		}
+
+		return f.visible(children)
+	}
+
+	var result []fs.FileInfo
+	for len(result) < count {
+		children, err := f.file.Readdir(count - len(result))
+
+		visible, visibleErr := f.visible(children)
+		if visibleErr != nil {
+			return result, visibleErr
+		}
+
+		result = append(result, visible...)
+
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(result) > 0 {
+				return result, nil
+			}
+
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// visible returns the given children the user is allowed to stat.
+func (f *File) visible(children []fs.FileInfo) ([]fs.FileInfo, error) {
+	visible := make([]fs.FileInfo, 0, len(children))
+
+	for _, child := range children {
+		err := f.fs.assertAuthorization(f.ctx, OperationStat, map[string]any{
+			"name": path.Join(f.name, child.Name()),
+		})
+		if err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				continue
+			}
+
+			return nil, errors.WithStack(err)
+		}
+
+		visible = append(visible, child)
+	}
+
+	return visible, nil
+}
+
+// Seek implements webdav.File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+// Stat implements webdav.File.
+func (f *File) Stat() (fs.FileInfo, error) {
+	return f.file.Stat()
+}
+
+// Write implements webdav.File.
+func (f *File) Write(p []byte) (n int, err error) {
+	return f.file.Write(p)
+}
+
+// DeadProps implements webdav.DeadPropsHolder.
+func (f *File) DeadProps() (map[xml.Name]webdav.Property, error) {
+	holder, ok := f.file.(webdav.DeadPropsHolder)
+	if !ok {
+		return nil, nil
+	}
+
+	return holder.DeadProps()
+}
+
+// Patch implements webdav.DeadPropsHolder.
+func (f *File) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return gowebdav.PatchDeadProps(f.file, patches)
+}
+
+var (
+	_ webdav.File            = &File{}
+	_ webdav.DeadPropsHolder = &File{}
+)
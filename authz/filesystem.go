@@ -3,8 +3,11 @@ package authz
 import (
 	"context"
 	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"slices"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/webdav"
@@ -21,7 +24,9 @@ const (
 )
 
 type FileSystem struct {
-	backend webdav.FileSystem
+	backend    webdav.FileSystem
+	onDecision DecisionFunc
+	recursive  bool
 }
 
 // Mkdir implements webdav.FileSystem.
@@ -48,7 +53,12 @@ func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm o
 		return nil, err
 	}
 
-	return f.backend.OpenFile(ctx, name, flag, perm)
+	file, err := f.backend.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{ctx: ctx, name: name, file: file, fs: f}, nil
 }
 
 // RemoveAll implements webdav.FileSystem.
@@ -60,19 +70,35 @@ func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
 		return err
 	}
 
+	if f.recursive {
+		err := f.walkDescendants(ctx, name, func(descendant string) error {
+			return f.assertAuthorization(ctx, OperationRemove, map[string]any{
+				"name": descendant,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return f.backend.RemoveAll(ctx, name)
 }
 
 // Rename implements webdav.FileSystem.
 func (f *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
-	err := f.assertAuthorization(ctx, OperationRename, map[string]any{
-		"oldName": oldName,
-		"newName": newName,
-	})
-	if err != nil {
+	if err := f.assertRenameAuthorization(ctx, oldName, newName); err != nil {
 		return err
 	}
 
+	if f.recursive {
+		err := f.walkDescendants(ctx, oldName, func(descendant string) error {
+			return f.assertRenameAuthorization(ctx, descendant, path.Join(newName, strings.TrimPrefix(descendant, oldName)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return f.backend.Rename(ctx, oldName, newName)
 }
 
@@ -88,16 +114,132 @@ func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error)
 	return f.backend.Stat(ctx, name)
 }
 
+// assertRenameAuthorization checks both the rename operation and the creation
+// of its destination, so that rules restricting where files can be written
+// also apply to moved files.
+func (f *FileSystem) assertRenameAuthorization(ctx context.Context, oldName string, newName string) error {
+	err := f.assertAuthorization(ctx, OperationRename, map[string]any{
+		"oldName": oldName,
+		"newName": newName,
+	})
+	if err != nil {
+		return err
+	}
+
+	return f.assertDestinationAuthorization(ctx, oldName, newName)
+}
+
+// assertDestinationAuthorization checks the creation of the destination of
+// the named resource, as a directory or as a file written from scratch.
+func (f *FileSystem) assertDestinationAuthorization(ctx context.Context, name string, dst string) error {
+	info, err := f.backend.Stat(ctx, name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if info.IsDir() {
+		return f.assertAuthorization(ctx, OperationMkdir, map[string]any{
+			"name": dst,
+			"perm": info.Mode().Perm(),
+		})
+	}
+
+	return f.assertAuthorization(ctx, OperationOpen, map[string]any{
+		"name": dst,
+		"flag": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+		"perm": info.Mode().Perm(),
+	})
+}
+
+// walkDescendants calls fn for each descendant of the given directory, using
+// the backend directly. It does nothing if name is not a directory.
+func (f *FileSystem) walkDescendants(ctx context.Context, name string, fn func(descendant string) error) error {
+	info, err := f.backend.Stat(ctx, name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	dir, err := f.backend.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	children, err := dir.Readdir(-1)
+	if err != nil {
+		_ = dir.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := dir.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, child := range children {
+		childName := path.Join(name, child.Name())
+
+		if err := fn(childName); err != nil {
+			return err
+		}
+
+		if !child.IsDir() {
+			continue
+		}
+
+		if err := f.walkDescendants(ctx, childName, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (f *FileSystem) assertAuthorization(ctx context.Context, operation Operation, env map[string]any) error {
 	user, err := ContextUser(ctx)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	if env == nil {
-		env = map[string]any{}
+	decision, err := f.decide(ctx, user, operation, env)
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
+	if f.onDecision != nil {
+		f.onDecision(ctx, decision)
+	}
+
+	if !decision.Allowed {
+		return os.ErrPermission
+	}
+
+	return nil
+}
+
+type scopedRule struct {
+	Rule  Rule
+	Group string
+}
+
+// decide evaluates the rules of the user and of its groups.
+// Deny rules take precedence: the operation is denied as soon as one of them
+// matches, allowed if any allow rule matches, and denied otherwise.
+func (f *FileSystem) decide(ctx context.Context, user User, operation Operation, params map[string]any) (Decision, error) {
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	decision := Decision{
+		User:      user,
+		Operation: operation,
+		Params:    maps.Clone(params),
+	}
+
+	env := params
+
 	env["operation"] = string(operation)
 	env["user"] = user.Attrs()
 	env["groups"] = slices.Collect(func(yield func(string) bool) {
@@ -114,27 +256,57 @@ func (f *FileSystem) assertAuthorization(ctx context.Context, operation Operatio
 	env["OP_RENAME"] = string(OperationRename)
 	env["OP_STAT"] = string(OperationStat)
 
-	for _, r := range user.Rules() {
-		slog.DebugContext(ctx, "executing rule", slog.Any("rule", r), slog.Any("env", env))
+	var allowRules, denyRules []scopedRule
 
-		allowed, err := r.Exec(env)
-		if err != nil {
-			return errors.WithStack(err)
+	collect := func(rules []Rule, group string) {
+		for _, r := range rules {
+			if RuleEffect(r) == EffectDeny {
+				denyRules = append(denyRules, scopedRule{r, group})
+			} else {
+				allowRules = append(allowRules, scopedRule{r, group})
+			}
 		}
+	}
+
+	collect(user.Rules(), "")
+	for _, g := range user.Groups() {
+		collect(g.Rules(), g.Name())
+	}
+
+	for _, rules := range [][]scopedRule{denyRules, allowRules} {
+		for _, sr := range rules {
+			slog.DebugContext(ctx, "executing rule", slog.Any("rule", sr.Rule), slog.String("group", sr.Group), slog.Any("env", env))
 
-		slog.DebugContext(ctx, "rule result", slog.Any("rule", r), slog.Bool("result", allowed))
+			matched, err := sr.Rule.Exec(env)
+			if err != nil {
+				return decision, errors.WithStack(err)
+			}
 
-		if allowed {
-			return nil
+			slog.DebugContext(ctx, "rule result", slog.Any("rule", sr.Rule), slog.Bool("result", matched))
+
+			if !matched {
+				continue
+			}
+
+			decision.Rule = sr.Rule
+			decision.Group = sr.Group
+			decision.Effect = RuleEffect(sr.Rule)
+			decision.Allowed = decision.Effect == EffectAllow
+
+			return decision, nil
 		}
 	}
 
-	return os.ErrPermission
+	return decision, nil
 }
 
-func NewFileSystem(backend webdav.FileSystem) *FileSystem {
+func NewFileSystem(backend webdav.FileSystem, funcs ...OptionFunc) *FileSystem {
+	opts := NewOptions(funcs...)
+
 	return &FileSystem{
-		backend: backend,
+		backend:    backend,
+		onDecision: opts.OnDecision,
+		recursive:  opts.Recursive,
 	}
 }
 
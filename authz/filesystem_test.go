@@ -0,0 +1,340 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+type testRule struct {
+	effect Effect
+	match  func(env map[string]any) bool
+}
+
+func (r *testRule) Exec(env map[string]any) (bool, error) {
+	return r.match(env), nil
+}
+
+func (r *testRule) Effect() Effect {
+	return r.effect
+}
+
+func underPrefix(prefix string) func(env map[string]any) bool {
+	return func(env map[string]any) bool {
+		name, _ := env["name"].(string)
+		return strings.HasPrefix(name, prefix)
+	}
+}
+
+func TestFileSystemDecide(t *testing.T) {
+	backend := webdav.NewMemFS()
+
+	for _, dir := range []string{"/shared", "/shared/hr", "/private"} {
+		if err := backend.Mkdir(context.Background(), dir, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	denyHR := &testRule{EffectDeny, underPrefix("/shared/hr")}
+	allowShared := &testRule{EffectAllow, underPrefix("/shared")}
+
+	staff := NewGroup("staff", allowShared)
+	user := NewUser(map[string]any{"username": "jdoe"}, []Rule{denyHR}, staff)
+
+	var decisions []Decision
+	fs := NewFileSystem(backend, WithDecisionFunc(func(ctx context.Context, decision Decision) {
+		decisions = append(decisions, decision)
+	}))
+
+	ctx := WithContextUser(context.Background(), user)
+
+	type testCase struct {
+		Name    string
+		Allowed bool
+		Rule    Rule
+		Group   string
+	}
+
+	testCases := []testCase{
+		{Name: "/shared", Allowed: true, Rule: allowShared, Group: "staff"},
+		{Name: "/shared/hr", Allowed: false, Rule: denyHR, Group: ""},
+		{Name: "/private", Allowed: false, Rule: nil, Group: ""},
+	}
+
+	for _, tc := range testCases {
+		decisions = nil
+
+		_, err := fs.Stat(ctx, tc.Name)
+		if tc.Allowed && err != nil {
+			t.Errorf("Stat(%s): unexpected error %+v", tc.Name, errors.WithStack(err))
+		}
+
+		if !tc.Allowed && !errors.Is(err, os.ErrPermission) {
+			t.Errorf("Stat(%s): expected error '%v', got '%v'", tc.Name, os.ErrPermission, err)
+		}
+
+		if e, g := 1, len(decisions); e != g {
+			t.Fatalf("len(decisions): expected %v, got %v", e, g)
+		}
+
+		decision := decisions[0]
+
+		if e, g := tc.Allowed, decision.Allowed; e != g {
+			t.Errorf("Stat(%s): decision.Allowed: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := tc.Rule, decision.Rule; e != g {
+			t.Errorf("Stat(%s): decision.Rule: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := tc.Group, decision.Group; e != g {
+			t.Errorf("Stat(%s): decision.Group: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := tc.Name, decision.Params["name"]; e != g {
+			t.Errorf("Stat(%s): decision.Params[name]: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if _, exists := decision.Params["user"]; exists {
+			t.Errorf("Stat(%s): decision.Params should not expose the rules environment", tc.Name)
+		}
+	}
+}
+
+func TestFileSystemRecursive(t *testing.T) {
+	ctx := context.Background()
+	backend := webdav.NewMemFS()
+
+	for _, dir := range []string{"/projects", "/projects/public", "/projects/secret", "/archive"} {
+		if err := backend.Mkdir(ctx, dir, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	f, err := backend.OpenFile(ctx, "/projects/secret/plan.txt", os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	denySecret := &testRule{EffectDeny, func(env map[string]any) bool {
+		for _, key := range []string{"name", "oldName"} {
+			if name, _ := env[key].(string); strings.HasPrefix(name, "/projects/secret") {
+				return true
+			}
+		}
+		return false
+	}}
+
+	allowMoveIntoArchive := &testRule{EffectAllow, func(env map[string]any) bool {
+		name, _ := env["name"].(string)
+		return env["operation"] == OperationRename || env["operation"] == OperationRemove || strings.HasPrefix(name, "/archive")
+	}}
+
+	user := NewUser(nil, []Rule{denySecret, allowMoveIntoArchive})
+	ctx = WithContextUser(ctx, user)
+
+	// Destinations outside of /archive are denied
+	if err := NewFileSystem(backend).Rename(ctx, "/projects/public", "/public"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Rename(/projects/public, /public): expected error '%v', got '%v'", os.ErrPermission, err)
+	}
+
+	if err := NewFileSystem(backend).Rename(ctx, "/projects/public", "/archive/public"); err != nil {
+		t.Errorf("Rename(/projects/public, /archive/public): unexpected error %+v", errors.WithStack(err))
+	}
+
+	// Descendants are authorized with the recursive check
+	if err := NewFileSystem(backend, WithRecursiveCheck(true)).RemoveAll(ctx, "/projects"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("RemoveAll(/projects): expected error '%v', got '%v'", os.ErrPermission, err)
+	}
+
+	if err := NewFileSystem(backend, WithRecursiveCheck(true)).Rename(ctx, "/projects", "/archive/projects"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Rename(/projects, /archive/projects): expected error '%v', got '%v'", os.ErrPermission, err)
+	}
+
+	if _, err := backend.Stat(ctx, "/projects/secret/plan.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Without it, only the top-level name is
+	if err := NewFileSystem(backend).RemoveAll(ctx, "/projects"); err != nil {
+		t.Errorf("RemoveAll(/projects): unexpected error %+v", errors.WithStack(err))
+	}
+}
+
+func TestFileSystemReaddir(t *testing.T) {
+	ctx := context.Background()
+	backend := webdav.NewMemFS()
+
+	for _, dir := range []string{"/shared", "/shared/alice", "/shared/bob"} {
+		if err := backend.Mkdir(ctx, dir, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	for _, name := range []string{"/shared/alice.txt", "/shared/bob.txt", "/shared/readme.txt"} {
+		f, err := backend.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	denyBob := &testRule{EffectDeny, underPrefix("/shared/bob")}
+	allowShared := &testRule{EffectAllow, underPrefix("/shared")}
+
+	user := NewUser(nil, []Rule{denyBob, allowShared})
+	ctx = WithContextUser(ctx, user)
+
+	fs := NewFileSystem(backend)
+
+	readdir := func(count int) []string {
+		dir, err := fs.OpenFile(ctx, "/shared", os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		defer dir.Close()
+
+		var names []string
+		for {
+			children, err := dir.Readdir(count)
+			for _, c := range children {
+				names = append(names, c.Name())
+			}
+
+			if count <= 0 || errors.Is(err, io.EOF) {
+				return names
+			}
+
+			if err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := count, len(children); g > e {
+				t.Errorf("len(children): expected at most %v, got %v", e, g)
+			}
+		}
+	}
+
+	expected := []string{"alice", "alice.txt", "readme.txt"}
+
+	for _, count := range []int{0, 1, 2} {
+		names := readdir(count)
+		slices.Sort(names)
+
+		if !slices.Equal(expected, names) {
+			t.Errorf("Readdir(%d): expected %v, got %v", count, expected, names)
+		}
+	}
+}
+
+// copier records the copies it is asked for.
+type copier struct {
+	webdav.FileSystem
+	copies []string
+}
+
+func (c *copier) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	c.copies = append(c.copies, src+" -> "+dst)
+	return nil
+}
+
+func (c *copier) CopyFile(ctx context.Context, src string, dst string) error {
+	c.copies = append(c.copies, src+" -> "+dst)
+	return nil
+}
+
+func TestFileSystemCopy(t *testing.T) {
+	ctx := context.Background()
+	backend := &copier{FileSystem: webdav.NewMemFS()}
+
+	for _, dir := range []string{"/src", "/src/public", "/src/secret", "/dst", "/dst/public", "/dst/secret", "/readonly"} {
+		if err := backend.Mkdir(ctx, dir, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	for _, name := range []string{"/src/file.txt", "/src/secret/plan.txt"} {
+		f, err := backend.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	denySecret := &testRule{EffectDeny, func(env map[string]any) bool {
+		name, _ := env["name"].(string)
+		return strings.Contains(name, "/secret")
+	}}
+
+	denyReadOnlyWrites := &testRule{EffectDeny, func(env map[string]any) bool {
+		name, _ := env["name"].(string)
+		return strings.HasPrefix(name, "/readonly") && env["operation"] != OperationStat
+	}}
+
+	allowAll := &testRule{EffectAllow, func(env map[string]any) bool { return true }}
+
+	user := NewUser(nil, []Rule{denySecret, denyReadOnlyWrites, allowAll})
+	ctx = WithContextUser(ctx, user)
+
+	fs := NewFileSystem(backend)
+
+	// Descendant collections hidden to the user are left out
+	if err := fs.CopyDeadProps(ctx, "/src", "/dst", true); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := []string{"/src -> /dst", "/src/public -> /dst/public"}, backend.copies; !slices.Equal(e, g) {
+		t.Errorf("copies: expected %v, got %v", e, g)
+	}
+
+	type testCase struct {
+		Name string
+		Copy func() error
+	}
+
+	testCases := []testCase{
+		{Name: "hidden collection", Copy: func() error { return fs.CopyDeadProps(ctx, "/src/secret", "/dst/secret", false) }},
+		{Name: "read-only collection", Copy: func() error { return fs.CopyDeadProps(ctx, "/src/public", "/readonly/public", false) }},
+		{Name: "hidden file", Copy: func() error { return fs.CopyFile(ctx, "/src/secret/plan.txt", "/dst/plan.txt") }},
+		{Name: "read-only file", Copy: func() error { return fs.CopyFile(ctx, "/src/file.txt", "/readonly/file.txt") }},
+	}
+
+	for _, tc := range testCases {
+		backend.copies = nil
+
+		if err := tc.Copy(); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("%s: expected error '%v', got '%v'", tc.Name, os.ErrPermission, err)
+		}
+
+		if len(backend.copies) != 0 {
+			t.Errorf("%s: unexpected copies %v", tc.Name, backend.copies)
+		}
+	}
+
+	backend.copies = nil
+
+	if err := fs.CopyFile(ctx, "/src/file.txt", "/dst/file.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := []string{"/src/file.txt -> /dst/file.txt"}, backend.copies; !slices.Equal(e, g) {
+		t.Errorf("copies: expected %v, got %v", e, g)
+	}
+}
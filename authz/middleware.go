@@ -0,0 +1,11 @@
+package authz
+
+import "github.com/bornholm/go-webdav"
+
+// Middleware authorizes filesystem operations against the rules of the
+// user found in the request context, see [WithContextUser].
+func Middleware(funcs ...OptionFunc) webdav.Middleware {
+	return func(next webdav.FileSystem) webdav.FileSystem {
+		return NewFileSystem(next, funcs...)
+	}
+}
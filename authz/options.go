@@ -0,0 +1,36 @@
+package authz
+
+type Options struct {
+	// OnDecision, if not nil, is called with the outcome of each authorization check
+	OnDecision DecisionFunc
+	// Recursive, if true, authorizes recursive removals and moves for each
+	// descendant of the targeted directory
+	Recursive bool
+}
+
+type OptionFunc func(opts *Options)
+
+// WithDecisionFunc records which rule decided each operation with the given function.
+func WithDecisionFunc(fn DecisionFunc) OptionFunc {
+	return func(opts *Options) {
+		opts.OnDecision = fn
+	}
+}
+
+// WithRecursiveCheck enables the authorization of each descendant of the
+// directories removed or moved, instead of the top-level name only.
+func WithRecursiveCheck(enabled bool) OptionFunc {
+	return func(opts *Options) {
+		opts.Recursive = enabled
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
@@ -7,3 +7,27 @@ type Rules interface {
 type Rule interface {
 	Exec(env map[string]any) (bool, error)
 }
+
+// Effect is the outcome of an operation matched by a rule.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// EffectRule is a [Rule] with an explicit effect.
+// Rules not implementing this interface allow the operations they match.
+type EffectRule interface {
+	Rule
+	Effect() Effect
+}
+
+// RuleEffect returns the effect of the given rule.
+func RuleEffect(r Rule) Effect {
+	if er, ok := r.(EffectRule); ok && er.Effect() == EffectDeny {
+		return EffectDeny
+	}
+
+	return EffectAllow
+}
@@ -25,3 +25,35 @@ func NewGroup(name string, rules ...Rule) *Group {
 }
 
 var _ Rules = &Group{}
+
+// BaseUser is a [User] with static attributes, rules and groups.
+type BaseUser struct {
+	attrs  map[string]any
+	rules  []Rule
+	groups []*Group
+}
+
+// Attrs implements User.
+func (u *BaseUser) Attrs() map[string]any {
+	return u.attrs
+}
+
+// Rules implements User.
+func (u *BaseUser) Rules() []Rule {
+	return u.rules
+}
+
+// Groups implements User.
+func (u *BaseUser) Groups() []*Group {
+	return u.groups
+}
+
+func NewUser(attrs map[string]any, rules []Rule, groups ...*Group) *BaseUser {
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+
+	return &BaseUser{attrs, rules, groups}
+}
+
+var _ User = &BaseUser{}
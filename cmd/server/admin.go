@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/pkg/errors"
+)
+
+type lockResponse struct {
+	Token   string     `json:"token"`
+	Root    string     `json:"root"`
+	Scope   lock.Scope `json:"scope"`
+	Depth   string     `json:"depth"`
+	Owner   string     `json:"owner"`
+	Timeout int64      `json:"timeout"`
+	Expiry  *time.Time `json:"expiry,omitempty"`
+}
+
+func newLockResponse(node *lock.LockNode) lockResponse {
+	res := lockResponse{
+		Token:   node.Token,
+		Root:    node.Details.Root,
+		Scope:   node.Scope,
+		Depth:   "infinity",
+		Owner:   node.Details.OwnerXML,
+		Timeout: -1,
+	}
+
+	if node.Details.ZeroDepth {
+		res.Depth = "0"
+	}
+
+	if node.Details.Duration >= 0 {
+		res.Timeout = int64(node.Details.Duration / time.Second)
+	}
+
+	if !node.Expiry.IsZero() {
+		expiry := node.Expiry
+		res.Expiry = &expiry
+	}
+
+	return res
+}
+
+// newAdminHandler exposes the locks administration API:
+//
+//   - GET /locks lists the active locks
+//   - GET /locks/{token} returns a lock
+//   - DELETE /locks/{token} forcibly releases a lock
+func newAdminHandler(lockSystem *lock.System, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /locks", func(w http.ResponseWriter, r *http.Request) {
+		nodes, err := lockSystem.Locks(time.Now())
+		if err != nil {
+			writeAdminError(w, r, http.StatusInternalServerError, errors.WithStack(err))
+			return
+		}
+
+		locks := make([]lockResponse, 0, len(nodes))
+		for _, n := range nodes {
+			locks = append(locks, newLockResponse(n))
+		}
+
+		writeAdminJSON(w, r, http.StatusOK, locks)
+	})
+
+	mux.HandleFunc("GET /locks/{token}", func(w http.ResponseWriter, r *http.Request) {
+		node, err := lockSystem.Lock(time.Now(), r.PathValue("token"))
+		if err != nil {
+			if errors.Is(err, lock.ErrLockNotFound) {
+				writeAdminError(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			writeAdminError(w, r, http.StatusInternalServerError, errors.WithStack(err))
+			return
+		}
+
+		writeAdminJSON(w, r, http.StatusOK, newLockResponse(node))
+	})
+
+	mux.HandleFunc("DELETE /locks/{token}", func(w http.ResponseWriter, r *http.Request) {
+		lockToken := r.PathValue("token")
+
+		if err := lockSystem.Break(lockToken); err != nil {
+			if errors.Is(err, lock.ErrLockNotFound) {
+				writeAdminError(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			writeAdminError(w, r, http.StatusInternalServerError, errors.WithStack(err))
+			return
+		}
+
+		slog.InfoContext(r.Context(), "lock broken", "token", lockToken)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return bearerAuth(mux, token)
+}
+
+func bearerAuth(handler http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		// An empty token never grants access
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAdminError(w, r, http.StatusUnauthorized, nil)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, r *http.Request, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.ErrorContext(r.Context(), "could not write response", slog.Any("error", errors.WithStack(err)))
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if err != nil && status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), err.Error(), slog.Any("error", err), "method", r.Method, "path", r.URL.Path)
+	}
+
+	writeAdminJSON(w, r, status, map[string]string{
+		"error": http.StatusText(status),
+	})
+}
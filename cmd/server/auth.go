@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/auth/mtls"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/pkg/errors"
+
+	_ "github.com/bornholm/go-webdav/auth/all"
+)
+
+// newAuthenticators returns the configured authenticators, the users with
+// plaintext passwords being tried first.
+func newAuthenticators(conf authConfig) ([]auth.Authenticator, error) {
+	authenticators := make([]auth.Authenticator, 0, len(conf.Providers)+1)
+
+	if len(conf.Users) > 0 {
+		authenticators = append(authenticators, newPlainAuthenticator(conf.Users))
+	}
+
+	for i, providerConf := range conf.Providers {
+		var options any
+		if providerConf.Options != nil {
+			options = providerConf.Options.Value
+		}
+
+		authenticator, err := auth.New(auth.Type(providerConf.Type), options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create authentication provider #%d", i)
+		}
+
+		authenticators = append(authenticators, authenticator)
+	}
+
+	return authenticators, nil
+}
+
+// requiresClientCertificates returns true if one of the configured
+// providers authenticates TLS client certificates.
+func requiresClientCertificates(conf authConfig) bool {
+	for _, providerConf := range conf.Providers {
+		if auth.Type(providerConf.Type) == mtls.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+// plainAuthenticator authenticates basic auth credentials against the
+// plaintext passwords of the configuration.
+type plainAuthenticator struct {
+	users map[string]string
+}
+
+// Authenticate implements auth.Authenticator.
+func (a *plainAuthenticator) Authenticate(r *http.Request) (authz.User, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil, errors.WithStack(auth.ErrNoCredentials)
+	}
+
+	username := user
+	password, exists := a.users[user]
+	if !exists { // Prevent timing attack
+		username = "dummy"
+		password = "dummy"
+	}
+
+	isPasswordOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+	isUsernameOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+
+	if !exists || !isPasswordOK || !isUsernameOK {
+		return nil, errors.Wrapf(auth.ErrInvalidCredentials, "invalid credentials for user '%s'", user)
+	}
+
+	return auth.NewUser(user, nil), nil
+}
+
+// Challenge implements auth.Challenger.
+func (a *plainAuthenticator) Challenge(realm string) string {
+	return auth.BasicChallenge(realm)
+}
+
+func newPlainAuthenticator(users map[string]string) *plainAuthenticator {
+	return &plainAuthenticator{users: users}
+}
+
+var (
+	_ auth.Authenticator = &plainAuthenticator{}
+	_ auth.Challenger    = &plainAuthenticator{}
+)
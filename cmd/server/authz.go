@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/bornholm/go-webdav/authz/expr"
+	webdavHandler "github.com/bornholm/go-webdav/handler"
+	"github.com/pkg/errors"
+)
+
+type authzUser struct {
+	attrs  map[string]any
+	rules  []authz.Rule
+	groups []string
+}
+
+// newUserResolver returns a resolver attaching the configured authorization
+// rules to the authenticated user of each request.
+// Attributes and groups of the authentication provider are merged with the
+// configured ones, provider groups without configuration having no rules.
+// Authenticated users missing from the configuration are therefore denied
+// every operation, unless one of their groups allows it.
+func newUserResolver(conf authzConfig) (webdavHandler.UserResolver, error) {
+	groups := make(map[string]*authz.Group, len(conf.Groups))
+	for name, groupConf := range conf.Groups {
+		rules, err := compileRules(groupConf.Rules)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not compile rules of group '%s'", name)
+		}
+
+		groups[name] = authz.NewGroup(name, rules...)
+	}
+
+	users := make(map[string]authzUser, len(conf.Users))
+	for username, userConf := range conf.Users {
+		rules, err := compileRules(userConf.Rules)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not compile rules of user '%s'", username)
+		}
+
+		for _, name := range userConf.Groups {
+			if _, exists := groups[name]; !exists {
+				return nil, errors.Errorf("unknown group '%s' for user '%s'", name, username)
+			}
+		}
+
+		users[username] = authzUser{
+			attrs:  userConf.Attrs,
+			rules:  rules,
+			groups: userConf.Groups,
+		}
+	}
+
+	return func(r *http.Request) (authz.User, error) {
+		authenticated, err := authz.ContextUser(r.Context())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		username := auth.Username(authenticated)
+		configured := users[username]
+
+		attrs := maps.Clone(authenticated.Attrs())
+		if attrs == nil {
+			attrs = map[string]any{}
+		}
+
+		maps.Copy(attrs, configured.attrs)
+		attrs[auth.AttrUsername] = username
+
+		groupNames := make([]string, 0, len(authenticated.Groups())+len(configured.groups))
+		for _, g := range authenticated.Groups() {
+			groupNames = append(groupNames, g.Name())
+		}
+
+		groupNames = append(groupNames, configured.groups...)
+
+		slices.Sort(groupNames)
+		groupNames = slices.Compact(groupNames)
+
+		userGroups := make([]*authz.Group, 0, len(groupNames))
+		for _, name := range groupNames {
+			group, exists := groups[name]
+			if !exists {
+				group = authz.NewGroup(name)
+			}
+
+			userGroups = append(userGroups, group)
+		}
+
+		return authz.NewUser(attrs, configured.rules, userGroups...), nil
+	}, nil
+}
+
+// logDecision logs the rule which decided an operation.
+func logDecision(ctx context.Context, decision authz.Decision) {
+	attrs := []any{
+		slog.String("username", auth.Username(decision.User)),
+		slog.String("operation", string(decision.Operation)),
+		slog.Any("params", decision.Params),
+		slog.Bool("allowed", decision.Allowed),
+	}
+
+	if decision.Rule != nil {
+		attrs = append(attrs,
+			slog.String("rule", fmt.Sprint(decision.Rule)),
+			slog.String("effect", string(decision.Effect)),
+			slog.String("group", decision.Group),
+		)
+	} else {
+		attrs = append(attrs, slog.String("rule", "default deny"))
+	}
+
+	slog.InfoContext(ctx, "authorization decision", attrs...)
+}
+
+func compileRules(confs []authzRuleConfig) ([]authz.Rule, error) {
+	rules := make([]authz.Rule, 0, len(confs))
+	for _, c := range confs {
+		effect := authz.EffectAllow
+		if c.Effect != "" {
+			effect = authz.Effect(c.Effect)
+		}
+
+		rule := expr.NewRule(c.Expr, expr.WithEffect(effect))
+
+		if err := rule.Compile(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// certificateCheckInterval is the minimum delay between two checks of the certificate files
+const certificateCheckInterval = 10 * time.Second
+
+// certificateReloader serves a certificate reloaded when its files are modified,
+// i.e. when they are renewed by an ACME client.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTimes    [2]time.Time
+	checkedAt   time.Time
+}
+
+// GetCertificate implements [tls.Config.GetCertificate].
+func (r *certificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.checkedAt) < certificateCheckInterval {
+		return r.certificate, nil
+	}
+
+	r.checkedAt = now
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		slog.Error("could not check certificate files", slog.Any("error", errors.WithStack(err)))
+		return r.certificate, nil
+	}
+
+	if modTimes == r.modTimes {
+		return r.certificate, nil
+	}
+
+	if err := r.load(modTimes); err != nil {
+		// Keep serving the previous certificate, the files may be partially written
+		slog.Error("could not reload certificate", slog.Any("error", errors.WithStack(err)))
+		return r.certificate, nil
+	}
+
+	slog.Info("certificate reloaded", slog.String("certFile", r.certFile))
+
+	return r.certificate, nil
+}
+
+func (r *certificateReloader) statFiles() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, errors.WithStack(err)
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func (r *certificateReloader) load(modTimes [2]time.Time) error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.certificate = &certificate
+	r.modTimes = modTimes
+
+	return nil
+}
+
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile:  certFile,
+		keyFile:   keyFile,
+		checkedAt: time.Now(),
+	}
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := r.load(modTimes); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return r, nil
+}
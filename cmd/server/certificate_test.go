@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	writeCertificate(t, certFile, keyFile, "first")
+
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	assertCommonName := func(expected string) {
+		t.Helper()
+
+		certificate, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := expected, leaf.Subject.CommonName; e != g {
+			t.Errorf("leaf.Subject.CommonName: expected %v, got %v", e, g)
+		}
+	}
+
+	assertCommonName("first")
+
+	// The files are not checked again before the check interval
+	writeCertificate(t, certFile, keyFile, "second")
+	assertCommonName("first")
+
+	reloader.checkedAt = time.Time{}
+	assertCommonName("second")
+
+	// The previous certificate is kept while the files are invalid
+	if err := os.WriteFile(keyFile, []byte("partially written"), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	touch(t, keyFile, time.Now().Add(time.Hour))
+
+	reloader.checkedAt = time.Time{}
+	assertCommonName("second")
+
+	// Or missing
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	reloader.checkedAt = time.Time{}
+	assertCommonName("second")
+
+	writeCertificate(t, certFile, keyFile, "third")
+
+	reloader.checkedAt = time.Time{}
+	assertCommonName("third")
+}
+
+// writeCertificate writes a self-signed certificate with the given common name
+// and its private key.
+func writeCertificate(t *testing.T, certFile string, keyFile string, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "PRIVATE KEY", Bytes: keyDER},
+	}
+
+	// Set explicitly, as the resolution of the file times may be coarse
+	modTime := time.Now()
+
+	for path, block := range files {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		touch(t, path, modTime)
+	}
+}
+
+func touch(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+}
@@ -1,33 +1,230 @@
 package main
 
 import (
+	"context"
 	"encoding"
 	"encoding/json"
+	"os"
 	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
 )
 
 type config struct {
 	Auth       authConfig       `json:"auth" envPrefix:"AUTH_"`
+	Authz      authzConfig      `json:"authz" envPrefix:"AUTHZ_"`
 	Filesystem filesystemConfig `json:"filesystem" envPrefix:"FILESYSTEM_"`
+	Mounts     mountsConfig     `json:"mounts" envPrefix:"MOUNTS_"`
+	Home       homeConfig       `json:"home" envPrefix:"HOME_"`
 	Cache      cacheConfig      `json:"cache" envPrefix:"CACHE_"`
+	DeadProps  deadPropsConfig  `json:"deadProps" envPrefix:"DEADPROPS_"`
+	Lock       lockConfig       `json:"lock" envPrefix:"LOCK_"`
+	Admin      adminConfig      `json:"admin" envPrefix:"ADMIN_"`
+	Listener   listenerConfig   `json:"listener" envPrefix:"LISTENER_"`
 	MDNS       mdnsConfig       `json:"mdns" envPrefix:"MDNS_"`
+	Reload     reloadConfig     `json:"reload" envPrefix:"RELOAD_"`
+	// Delay given to the in-flight requests to complete on shutdown, defaults to 30 seconds
+	ShutdownTimeout time.Duration `json:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" validate:"gte=0"`
+}
+
+// envPrefix is the prefix of the environment variables of the configuration
+const envPrefix = "GOWEBDAV_"
+
+// loadConfig reads the given configuration file, completes it with the
+// environment variables and validates it. A missing file is not an error.
+func loadConfig(ctx context.Context, path string) (*config, error) {
+	rawConfig, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "could not read configuration file")
+	}
+
+	var conf config
+
+	// The default values are set first, the configuration file and then the
+	// environment variables overriding them
+	defaults := env.Options{
+		Prefix:      envPrefix,
+		Environment: map[string]string{},
+	}
+
+	if err := env.ParseWithOptions(&conf, defaults); err != nil {
+		return nil, errors.Wrap(err, "could not set default values")
+	}
+
+	if rawConfig != nil {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			return nil, errors.Wrap(err, "could not parse configuration file")
+		}
+	}
+
+	options := env.Options{
+		Prefix: envPrefix,
+		// The default values are already set
+		DefaultValueTagName: "-",
+	}
+
+	if err := env.ParseWithOptions(&conf, options); err != nil {
+		return nil, errors.Wrap(err, "could not parse environment variables")
+	}
+
+	validate := validator.New()
+	if err := validate.StructCtx(ctx, &conf); err != nil {
+		return nil, errors.Wrap(err, "could not validate configuration")
+	}
+
+	return &conf, nil
 }
 
 type authConfig struct {
-	Enabled bool              `json:"enabled" env:"ENABLED" envDefault:"true"`
-	Users   map[string]string `json:"users" env:"USERS,expand"`
+	Enabled bool `json:"enabled" env:"ENABLED" envDefault:"true"`
+	// Users with plaintext passwords
+	Users map[string]string `json:"users" env:"USERS,expand"`
+	// Authentication providers, tried in order after the plaintext users
+	Providers []authProviderConfig `json:"providers" validate:"dive"`
+}
+
+type authProviderConfig struct {
+	Type    string   `json:"type" validate:"required,oneof=password htpasswd token mtls jwt ldap"`
+	Options *rawJSON `json:"options"`
+}
+
+type authzConfig struct {
+	Enabled bool `json:"enabled" env:"ENABLED"`
+	// Log the rule deciding each operation
+	Explain bool `json:"explain" env:"EXPLAIN"`
+	// Authorize each descendant of removed or moved directories
+	Recursive bool                        `json:"recursive" env:"RECURSIVE"`
+	Users     map[string]authzUserConfig  `json:"users" validate:"dive"`
+	Groups    map[string]authzGroupConfig `json:"groups" validate:"dive"`
+}
+
+type authzUserConfig struct {
+	Attrs  map[string]any    `json:"attrs"`
+	Groups []string          `json:"groups"`
+	Rules  []authzRuleConfig `json:"rules" validate:"dive"`
+}
+
+type authzGroupConfig struct {
+	Rules []authzRuleConfig `json:"rules" validate:"dive"`
+}
+
+type authzRuleConfig struct {
+	Effect string `json:"effect" validate:"omitempty,oneof=allow deny"`
+	Expr   string `json:"expr" validate:"required"`
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+// Rules can be given either as an object or as a plain expression, allowing the matched operations.
+func (c *authzRuleConfig) UnmarshalJSON(data []byte) error {
+	var expr string
+	if err := json.Unmarshal(data, &expr); err == nil {
+		*c = authzRuleConfig{Expr: expr}
+		return nil
+	}
+
+	type rawRuleConfig authzRuleConfig
+
+	var raw rawRuleConfig
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = authzRuleConfig(raw)
+
+	return nil
 }
 
 type filesystemConfig struct {
-	Type    string   `json:"type" env:"TYPE,expand" validate:"required,oneof=local sqlite s3"`
+	// Type of the root filesystem, optional when filesystems are mounted
+	Type    string   `json:"type" env:"TYPE,expand" validate:"omitempty,oneof=local memory sqlite s3"`
 	Options *rawJSON `json:"options" env:"OPTIONS,expand"`
 }
 
+type mountsConfig struct {
+	// Move resources across mounts by copying them, such moves being rejected otherwise
+	CopyAcrossMounts bool `json:"copyAcrossMounts" env:"COPY_ACROSS_MOUNTS"`
+	// Filesystems served under a path prefix, beside the root filesystem
+	Points []mountConfig `json:"points" validate:"dive"`
+}
+
+type mountConfig struct {
+	Path    string   `json:"path" validate:"required,startswith=/"`
+	Type    string   `json:"type" validate:"required,oneof=local memory sqlite s3"`
+	Options *rawJSON `json:"options"`
+}
+
+type homeConfig struct {
+	// Re-root each user under its home directory, requires authentication
+	Enabled bool `json:"enabled" env:"ENABLED"`
+	// Directory holding the home directories, defaults to "/home"
+	Dir string `json:"dir" env:"DIR,expand"`
+	// Directory copied to create the missing home directories, optional
+	Template string `json:"template" env:"TEMPLATE,expand"`
+	// Directory exposed in each home directory, optional
+	Shared string `json:"shared" env:"SHARED,expand"`
+	// Name of the shared directory in the home directories, defaults to "shared"
+	SharedName string `json:"sharedName" env:"SHARED_NAME" validate:"excludesall=/"`
+}
+
 type cacheConfig struct {
 	Enabled bool          `json:"enabled" env:"ENABLED" envDefault:"true"`
 	TTL     time.Duration `json:"ttl" env:"TTL" envDefault:"1h"`
 }
 
+type deadPropsConfig struct {
+	Type    string   `json:"type" env:"TYPE,expand" validate:"omitempty,oneof=memory sqlite"`
+	Options *rawJSON `json:"options" env:"OPTIONS,expand"`
+}
+
+type lockConfig struct {
+	Type    string   `json:"type" env:"TYPE,expand" validate:"omitempty,oneof=memory sqlite"`
+	Options *rawJSON `json:"options" env:"OPTIONS,expand"`
+	// Interval between two removals of the expired locks, defaults to 1 minute
+	SweepInterval time.Duration `json:"sweepInterval" env:"SWEEP_INTERVAL" validate:"gte=0"`
+}
+
+type adminConfig struct {
+	// Listening address of the administration API, disabled if empty
+	Address string `json:"address" env:"ADDRESS,expand"`
+	// Bearer token required to access the administration API, mandatory
+	// when it is enabled
+	Token string `json:"token" env:"TOKEN,expand" validate:"required_with=Address"`
+}
+
+type listenerConfig struct {
+	// Network of the listening socket, tcp by default
+	Network string `json:"network" env:"NETWORK" validate:"omitempty,oneof=tcp unix"`
+	// Listening address, or socket path, overriding the -address flag
+	Address string `json:"address" env:"ADDRESS,expand"`
+	// Permissions of the unix socket, in octal notation (i.e. "0660")
+	SocketMode string    `json:"socketMode" env:"SOCKET_MODE"`
+	TLS        tlsConfig `json:"tls" envPrefix:"TLS_"`
+	// Disable HTTP/2, negotiated by default over TLS
+	DisableHTTP2 bool `json:"disableHttp2" env:"DISABLE_HTTP2"`
+	// Enable HTTP/2 over cleartext connections, i.e. behind a reverse proxy
+	H2C bool `json:"h2c" env:"H2C"`
+}
+
+type tlsConfig struct {
+	// Paths of the PEM encoded certificate and private key, TLS is disabled if empty.
+	// Modified files are reloaded.
+	CertFile string `json:"certFile" env:"CERT_FILE,expand" validate:"required_with=KeyFile"`
+	KeyFile  string `json:"keyFile" env:"KEY_FILE,expand" validate:"required_with=CertFile"`
+	// Path of the certificate authorities verifying the client certificates, optional
+	ClientCAFile string `json:"clientCAFile" env:"CLIENT_CA_FILE,expand"`
+	// Reject connections without a valid client certificate
+	RequireClientCert bool `json:"requireClientCert" env:"REQUIRE_CLIENT_CERT" validate:"excluded_without=ClientCAFile"`
+}
+
+type reloadConfig struct {
+	// Reload the configuration when the file is modified, in addition to SIGHUP
+	Watch bool `json:"watch" env:"WATCH"`
+	// Interval between two checks of the configuration file, defaults to 5 seconds
+	WatchInterval time.Duration `json:"watchInterval" env:"WATCH_INTERVAL" validate:"gte=0"`
+}
+
 type mdnsConfig struct {
 	Enabled bool `json:"enabled" env:"ENABLED" envDefault:"true"`
 }
@@ -45,6 +242,11 @@ func (j *rawJSON) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements [json.Marshaler].
+func (j rawJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
 // UnmarshalText implements [encoding.TextUnmarshaler].
 func (j *rawJSON) UnmarshalText(text []byte) error {
 	if err := json.Unmarshal(text, &j.Value); err != nil {
@@ -56,3 +258,5 @@ func (j *rawJSON) UnmarshalText(text []byte) error {
 
 var _ encoding.TextUnmarshaler = &rawJSON{}
 var _ json.Unmarshaler = &rawJSON{}
+var _ json.Marshaler = rawJSON{}
+var _ json.Unmarshaler = &authzRuleConfig{}
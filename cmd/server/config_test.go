@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	type testCase struct {
+		Name            string
+		Config          string
+		Env             map[string]string
+		ExpectedEnabled bool
+		ExpectedTTL     time.Duration
+	}
+
+	testCases := []testCase{
+		{
+			Name:            "defaults",
+			Config:          `{}`,
+			ExpectedEnabled: true,
+			ExpectedTTL:     time.Hour,
+		},
+		{
+			Name:            "configuration file",
+			Config:          `{ "cache": { "enabled": false, "ttl": 600000000000 } }`,
+			ExpectedEnabled: false,
+			ExpectedTTL:     10 * time.Minute,
+		},
+		{
+			Name:            "environment variables",
+			Config:          `{ "cache": { "enabled": false, "ttl": 600000000000 } }`,
+			Env:             map[string]string{"GOWEBDAV_CACHE_ENABLED": "true", "GOWEBDAV_CACHE_TTL": "5m"},
+			ExpectedEnabled: true,
+			ExpectedTTL:     5 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			for key, value := range tc.Env {
+				t.Setenv(key, value)
+			}
+
+			configPath := filepath.Join(t.TempDir(), "config.json")
+
+			if err := os.WriteFile(configPath, []byte(tc.Config), 0600); err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			conf, err := loadConfig(ctx, configPath)
+			if err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := tc.ExpectedEnabled, conf.Cache.Enabled; e != g {
+				t.Errorf("conf.Cache.Enabled: expected %v, got %v", e, g)
+			}
+
+			if e, g := tc.ExpectedTTL, conf.Cache.TTL; e != g {
+				t.Errorf("conf.Cache.TTL: expected %v, got %v", e, g)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/bornholm/go-webdav/middleware/deadprops"
+	"github.com/bornholm/go-webdav/middleware/deadprops/sqlite"
+	"github.com/pkg/errors"
+)
+
+func newDeadPropsStore(conf deadPropsConfig) (deadprops.Store, error) {
+	var options any
+	if conf.Options != nil {
+		options = conf.Options.Value
+	}
+
+	switch conf.Type {
+	case "", "memory":
+		return deadprops.NewMemStore(), nil
+
+	case sqlite.Type:
+		store, err := sqlite.CreateStoreFromOptions(options)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return store, nil
+
+	default:
+		return nil, errors.Errorf("unknown dead properties store type '%s'", conf.Type)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/bornholm/go-webdav/filesystem/mount"
+	"github.com/pkg/errors"
+)
+
+// newFileSystem creates the root filesystem and, if any, the mount table
+// serving the mounted filesystems beside it.
+func newFileSystem(ctx context.Context, conf *config) (fs webdav.FileSystem, err error) {
+	if len(conf.Mounts.Points) == 0 {
+		if conf.Filesystem.Type == "" {
+			return nil, errors.New("no filesystem configured")
+		}
+
+		slog.InfoContext(ctx, "creating filesystem", "type", conf.Filesystem.Type)
+
+		return createFileSystem(conf.Filesystem.Type, conf.Filesystem.Options)
+	}
+
+	mounts := make([]mount.Mount, 0, len(conf.Mounts.Points)+1)
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		// Close the filesystems already created
+		for _, m := range mounts {
+			_ = webdav.Close(m.FileSystem)
+		}
+	}()
+
+	if conf.Filesystem.Type != "" {
+		slog.InfoContext(ctx, "creating filesystem", "type", conf.Filesystem.Type)
+
+		root, err := createFileSystem(conf.Filesystem.Type, conf.Filesystem.Options)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		mounts = append(mounts, mount.Mount{Path: "/", FileSystem: root})
+	}
+
+	for _, point := range conf.Mounts.Points {
+		slog.InfoContext(ctx, "mounting filesystem", "type", point.Type, "path", point.Path)
+
+		mounted, err := createFileSystem(point.Type, point.Options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create filesystem mounted on '%s'", point.Path)
+		}
+
+		mounts = append(mounts, mount.Mount{Path: point.Path, FileSystem: mounted})
+	}
+
+	table, err := mount.NewFileSystem(mounts, mount.WithCopyAcrossMounts(conf.Mounts.CopyAcrossMounts))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return table, nil
+}
+
+func createFileSystem(fsType string, rawOptions *rawJSON) (webdav.FileSystem, error) {
+	var options any
+	if rawOptions != nil {
+		options = rawOptions.Value
+	}
+
+	fs, err := filesystem.New(filesystem.Type(fsType), options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return fs, nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	webdavHandler "github.com/bornholm/go-webdav/handler"
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/bornholm/go-webdav/middleware/cache"
+	"github.com/bornholm/go-webdav/middleware/deadprops"
+	"github.com/bornholm/go-webdav/middleware/home"
+	"github.com/bornholm/go-webdav/middleware/logger"
+	"github.com/pkg/errors"
+	sloghttp "github.com/samber/slog-http"
+)
+
+// instance is the handler chain built from a configuration, with the
+// filesystem and the stores it relies on.
+type instance struct {
+	conf *config
+
+	fs             webdav.FileSystem
+	cacheStore     *cache.MemoryStore
+	cacheWatch     io.Closer
+	deadPropsStore deadprops.Store
+	deadPropsWatch io.Closer
+	lockStore      lock.Store
+	lockSystem     *lock.System
+	sweeper        *sweeper
+
+	handler      http.Handler
+	adminHandler http.Handler
+
+	// Resources used by the instance, some of them being shared with the
+	// previous or the next one
+	res resources
+
+	mu      sync.Mutex
+	active  int
+	retired bool
+	idle    chan struct{}
+}
+
+// newInstance builds the handler chain from the given configuration.
+// The filesystem and the stores of the previous instance, if any, are
+// reused when their configuration is unchanged.
+func newInstance(ctx context.Context, conf *config, prev *instance) (inst *instance, err error) {
+	inst = &instance{
+		conf: conf,
+		idle: make(chan struct{}),
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		// Release the resources created for the failed instance
+		if prev != nil {
+			inst.res.Release(ctx, &prev.res)
+		} else {
+			inst.res.Close(ctx)
+		}
+	}()
+
+	if prev != nil && reflect.DeepEqual(prev.conf.Filesystem, conf.Filesystem) && reflect.DeepEqual(prev.conf.Mounts, conf.Mounts) {
+		inst.fs = prev.fs
+	} else {
+		inst.fs, err = newFileSystem(ctx, conf)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create filesystem")
+		}
+	}
+
+	inst.res.Add("filesystem", inst.fs)
+
+	middlewares := []webdav.Middleware{
+		logger.Middleware(slog.Default()),
+	}
+
+	var authenticators []auth.Authenticator
+	if conf.Auth.Enabled {
+		authenticators, err = newAuthenticators(conf.Auth)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create authentication providers")
+		}
+
+		for _, a := range authenticators {
+			inst.res.Add("authenticator", a)
+		}
+	}
+
+	authEnabled := len(authenticators) > 0
+
+	if conf.Home.Enabled {
+		if !authEnabled {
+			return nil, errors.New("home directories require authentication to be enabled")
+		}
+
+		homeOptions := []home.OptionFunc{}
+
+		if conf.Home.Dir != "" {
+			homeOptions = append(homeOptions, home.WithDir(conf.Home.Dir))
+		}
+
+		if conf.Home.Template != "" {
+			homeOptions = append(homeOptions, home.WithTemplate(conf.Home.Template))
+		}
+
+		if conf.Home.Shared != "" {
+			sharedName := conf.Home.SharedName
+			if sharedName == "" {
+				sharedName = "shared"
+			}
+
+			homeOptions = append(homeOptions, home.WithShared(conf.Home.Shared, sharedName))
+		}
+
+		slog.InfoContext(ctx, "enabling home directories", "dir", conf.Home.Dir, "template", conf.Home.Template, "shared", conf.Home.Shared)
+
+		// Operations are re-rooted before being authorized, rules
+		// therefore apply to the paths of the underlying filesystem
+		middlewares = append(middlewares, home.Middleware(homeOptions...))
+	}
+
+	var userResolver webdavHandler.UserResolver
+	if conf.Authz.Enabled {
+		if !authEnabled {
+			return nil, errors.New("authorization rules require authentication to be enabled")
+		}
+
+		slog.InfoContext(ctx, "enabling authorization rules", "total_users", len(conf.Authz.Users), "total_groups", len(conf.Authz.Groups))
+
+		userResolver, err = newUserResolver(conf.Authz)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create authorization rules")
+		}
+
+		authzOptions := []authz.OptionFunc{
+			authz.WithRecursiveCheck(conf.Authz.Recursive),
+		}
+
+		if conf.Authz.Explain {
+			authzOptions = append(authzOptions, authz.WithDecisionFunc(logDecision))
+		}
+
+		middlewares = append(middlewares, authz.Middleware(authzOptions...))
+	}
+
+	if conf.Cache.Enabled {
+		// Cached entries are only valid for the filesystem they were read from
+		if prev != nil && prev.cacheStore != nil && prev.fs == inst.fs && prev.conf.Cache.TTL == conf.Cache.TTL {
+			inst.cacheStore = prev.cacheStore
+		} else {
+			slog.InfoContext(ctx, "enabling metadata cache", "ttl", conf.Cache.TTL)
+			inst.cacheStore = cache.NewMemoryStore(conf.Cache.TTL)
+		}
+
+		inst.res.Add("cache store", inst.cacheStore)
+
+		// Entries changed by other processes are invalidated immediately
+		if prev != nil && prev.cacheStore == inst.cacheStore {
+			inst.cacheWatch = prev.cacheWatch
+		} else {
+			inst.cacheWatch = cache.Watch(inst.fs, inst.cacheStore)
+		}
+
+		inst.res.Add("cache watch", inst.cacheWatch)
+
+		middlewares = append(middlewares, cache.Middleware(inst.cacheStore))
+	}
+
+	if prev != nil && reflect.DeepEqual(prev.conf.DeadProps, conf.DeadProps) {
+		inst.deadPropsStore = prev.deadPropsStore
+	} else {
+		slog.InfoContext(ctx, "creating dead properties store", "type", conf.DeadProps.Type)
+
+		inst.deadPropsStore, err = newDeadPropsStore(conf.DeadProps)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create dead properties store")
+		}
+	}
+
+	inst.res.Add("dead properties store", inst.deadPropsStore)
+
+	if prev != nil && prev.deadPropsStore == inst.deadPropsStore && prev.fs == inst.fs {
+		inst.deadPropsWatch = prev.deadPropsWatch
+	} else {
+		inst.deadPropsWatch = deadprops.Watch(inst.fs, inst.deadPropsStore)
+	}
+
+	inst.res.Add("dead properties watch", inst.deadPropsWatch)
+
+	middlewares = append(middlewares, deadprops.Middleware(inst.deadPropsStore))
+
+	if prev != nil && prev.conf.Lock.Type == conf.Lock.Type && reflect.DeepEqual(prev.conf.Lock.Options, conf.Lock.Options) {
+		inst.lockStore = prev.lockStore
+		inst.lockSystem = prev.lockSystem
+	} else {
+		slog.InfoContext(ctx, "creating lock store", "type", conf.Lock.Type)
+
+		inst.lockStore, err = newLockStore(conf.Lock)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create lock store")
+		}
+
+		inst.lockSystem = lock.NewSystem(inst.lockStore)
+	}
+
+	inst.res.Add("lock store", inst.lockStore)
+
+	sweepInterval := conf.Lock.SweepInterval
+	if sweepInterval == 0 {
+		sweepInterval = time.Minute
+	}
+
+	if prev != nil && prev.lockSystem == inst.lockSystem && prev.sweeper.interval == sweepInterval {
+		inst.sweeper = prev.sweeper
+	} else {
+		inst.sweeper = startSweeper(ctx, inst.lockSystem, sweepInterval)
+	}
+
+	inst.res.Add("lock sweeper", inst.sweeper)
+
+	var handler http.Handler = webdavHandler.New(
+		inst.fs,
+		webdavHandler.WithMiddlewares(middlewares...),
+		webdavHandler.WithLockSystem(inst.lockSystem),
+		webdavHandler.WithUserResolver(userResolver),
+	)
+
+	slogMiddleware := sloghttp.New(slog.Default())
+	handler = slogMiddleware(handler)
+
+	if authEnabled {
+		slog.InfoContext(ctx, "enabling authentication", "total_users", len(conf.Auth.Users), "total_providers", len(conf.Auth.Providers))
+		handler = auth.Middleware(authenticators)(handler)
+	}
+
+	inst.handler = handler
+	inst.adminHandler = newAdminHandler(inst.lockSystem, conf.Admin.Token)
+
+	return inst, nil
+}
+
+// acquire registers a request served by the instance, returning false
+// if the instance has been retired.
+func (i *instance) acquire() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.retired {
+		return false
+	}
+
+	i.active++
+
+	return true
+}
+
+// release unregisters a request served by the instance.
+func (i *instance) release() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.active--
+
+	if i.retired && i.active == 0 {
+		close(i.idle)
+	}
+}
+
+// retire stops the instance from serving new requests. The returned channel
+// is closed once the requests it is still serving are completed.
+func (i *instance) retire() <-chan struct{} {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.retired {
+		i.retired = true
+
+		if i.active == 0 {
+			close(i.idle)
+		}
+	}
+
+	return i.idle
+}
+
+// sweeper periodically removes the expired locks of a lock system until closed.
+type sweeper struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+}
+
+// Close implements [io.Closer].
+func (s *sweeper) Close() error {
+	s.cancel()
+	return nil
+}
+
+func startSweeper(ctx context.Context, lockSystem *lock.System, interval time.Duration) *sweeper {
+	ctx, cancel := context.WithCancel(ctx)
+
+	lockSystem.StartSweeper(ctx, interval)
+
+	return &sweeper{
+		interval: interval,
+		cancel:   cancel,
+	}
+}
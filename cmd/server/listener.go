@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// newListener returns the listener of the webdav server: the socket passed by
+// systemd if the process is socket activated, a unix socket or a TCP socket otherwise.
+func newListener(ctx context.Context, conf listenerConfig, address string) (net.Listener, error) {
+	listener, err := activationListener()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not use activation socket")
+	}
+
+	if listener != nil {
+		slog.InfoContext(ctx, "using socket activation", "address", listener.Addr().String())
+		return listener, nil
+	}
+
+	if conf.Address != "" {
+		address = conf.Address
+	}
+
+	switch conf.Network {
+	case "", "tcp":
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return listener, nil
+
+	case "unix":
+		return listenUnix(address, conf.SocketMode)
+
+	default:
+		return nil, errors.Errorf("unknown network '%s'", conf.Network)
+	}
+}
+
+// activationListener returns the first socket passed by systemd, if any.
+// See sd_listen_fds(3).
+func activationListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+
+	// Prevent child processes from using the sockets
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if fds > 1 {
+		slog.Warn("only the first activation socket is used", "total_fds", fds)
+	}
+
+	syscall.CloseOnExec(listenFDsStart)
+
+	file := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_3")
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return listener, nil
+}
+
+func listenUnix(path string, rawMode string) (net.Listener, error) {
+	// Remove the socket left by a previous process
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if rawMode != "" {
+		mode, err := strconv.ParseUint(rawMode, 8, 32)
+		if err != nil {
+			listener.Close()
+			return nil, errors.Wrapf(err, "invalid socket mode '%s'", rawMode)
+		}
+
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			listener.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return listener, nil
+}
+
+// newTLSConfig returns the TLS configuration of the webdav server, nil if TLS is disabled.
+func newTLSConfig(conf tlsConfig, requestClientCerts bool) (*tls.Config, error) {
+	if conf.CertFile == "" {
+		return nil, nil
+	}
+
+	reloader, err := newCertificateReloader(conf.CertFile, conf.KeyFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read client certificate authorities")
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in '%s'", conf.ClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+		if conf.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if requestClientCerts {
+		// Client certificates are verified by the authentication provider
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// newProtocols returns the HTTP protocols served by the webdav server.
+func newProtocols(conf listenerConfig) *http.Protocols {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+
+	if conf.TLS.CertFile != "" {
+		protocols.SetHTTP2(!conf.DisableHTTP2)
+	} else {
+		protocols.SetUnencryptedHTTP2(conf.H2C)
+	}
+
+	return protocols
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "webdav.sock")
+
+	// Socket left by a previous process
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := stale.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	listener, err := listenUnix(path, "0660")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := os.FileMode(0660), info.Mode().Perm(); e != g {
+		t.Errorf("info.Mode().Perm(): expected %v, got %v", e, g)
+	}
+
+	// Other files are never removed
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, []byte("data"), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := listenUnix(regular, ""); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+
+	if _, err := os.Stat(regular); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	for _, mode := range []string{"rw-rw----", "0999", "660a"} {
+		if _, err := listenUnix(filepath.Join(dir, "invalid.sock"), mode); err == nil {
+			t.Errorf("listenUnix(%s): expected an error, got nil", mode)
+		}
+	}
+}
+
+func TestNewListener(t *testing.T) {
+	ctx := context.Background()
+
+	// Socket activation only applies to the process it is intended for
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listener, err := newListener(ctx, listenerConfig{}, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "tcp", listener.Addr().Network(); e != g {
+		t.Errorf("listener.Addr().Network(): expected %v, got %v", e, g)
+	}
+
+	listener.Close()
+
+	path := filepath.Join(t.TempDir(), "webdav.sock")
+
+	listener, err = newListener(ctx, listenerConfig{Network: "unix", Address: path}, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := path, listener.Addr().String(); e != g {
+		t.Errorf("listener.Addr().String(): expected %v, got %v", e, g)
+	}
+
+	listener.Close()
+
+	if _, err := newListener(ctx, listenerConfig{Network: "udp"}, "127.0.0.1:0"); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, "server")
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writeCertificate(t, caFile, filepath.Join(dir, "ca-key.pem"), "ca")
+
+	type testCase struct {
+		Name               string
+		Config             tlsConfig
+		RequestClientCerts bool
+		ExpectedClientAuth tls.ClientAuthType
+		ExpectedClientCAs  bool
+	}
+
+	testCases := []testCase{
+		{
+			Name:               "server certificate",
+			Config:             tlsConfig{CertFile: certFile, KeyFile: keyFile},
+			ExpectedClientAuth: tls.NoClientCert,
+		},
+		{
+			Name:               "client certificates requested by the authentication",
+			Config:             tlsConfig{CertFile: certFile, KeyFile: keyFile},
+			RequestClientCerts: true,
+			ExpectedClientAuth: tls.RequestClientCert,
+		},
+		{
+			Name:               "client certificate authorities",
+			Config:             tlsConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile},
+			RequestClientCerts: true,
+			ExpectedClientAuth: tls.VerifyClientCertIfGiven,
+			ExpectedClientCAs:  true,
+		},
+		{
+			Name:               "required client certificate",
+			Config:             tlsConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, RequireClientCert: true},
+			ExpectedClientAuth: tls.RequireAndVerifyClientCert,
+			ExpectedClientCAs:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		config, err := newTLSConfig(tc.Config, tc.RequestClientCerts)
+		if err != nil {
+			t.Fatalf("%s: %+v", tc.Name, errors.WithStack(err))
+		}
+
+		if e, g := tc.ExpectedClientAuth, config.ClientAuth; e != g {
+			t.Errorf("%s: config.ClientAuth: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := tc.ExpectedClientCAs, config.ClientCAs != nil; e != g {
+			t.Errorf("%s: config.ClientCAs: expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := uint16(tls.VersionTLS12), config.MinVersion; e != g {
+			t.Errorf("%s: config.MinVersion: expected %v, got %v", tc.Name, e, g)
+		}
+	}
+
+	// TLS is disabled without certificate
+	config, err := newTLSConfig(tlsConfig{}, true)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if config != nil {
+		t.Errorf("expected no TLS configuration, got %v", config)
+	}
+
+	if _, err := newTLSConfig(tlsConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, false); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+}
+
+func TestNewProtocols(t *testing.T) {
+	type testCase struct {
+		Name              string
+		Config            listenerConfig
+		ExpectedHTTP2     bool
+		ExpectedCleartext bool
+	}
+
+	withTLS := tlsConfig{CertFile: "cert.pem", KeyFile: "key.pem"}
+
+	testCases := []testCase{
+		{Name: "tls", Config: listenerConfig{TLS: withTLS}, ExpectedHTTP2: true},
+		{Name: "tls without http/2", Config: listenerConfig{TLS: withTLS, DisableHTTP2: true}},
+		{Name: "cleartext", Config: listenerConfig{}},
+		{Name: "h2c", Config: listenerConfig{H2C: true}, ExpectedCleartext: true},
+	}
+
+	for _, tc := range testCases {
+		protocols := newProtocols(tc.Config)
+
+		if !protocols.HTTP1() {
+			t.Errorf("%s: expected HTTP/1 to be enabled", tc.Name)
+		}
+
+		if e, g := tc.ExpectedHTTP2, protocols.HTTP2(); e != g {
+			t.Errorf("%s: protocols.HTTP2(): expected %v, got %v", tc.Name, e, g)
+		}
+
+		if e, g := tc.ExpectedCleartext, protocols.UnencryptedHTTP2(); e != g {
+			t.Errorf("%s: protocols.UnencryptedHTTP2(): expected %v, got %v", tc.Name, e, g)
+		}
+	}
+}
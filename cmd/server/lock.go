@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/bornholm/go-webdav/lock/sqlite"
+	"github.com/pkg/errors"
+)
+
+func newLockStore(conf lockConfig) (lock.Store, error) {
+	var options any
+	if conf.Options != nil {
+		options = conf.Options.Value
+	}
+
+	switch conf.Type {
+	case "", "memory":
+		return lock.NewMemoryStore(), nil
+
+	case sqlite.Type:
+		store, err := sqlite.CreateStoreFromOptions(options)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return store, nil
+
+	default:
+		return nil, errors.Errorf("unknown lock store type '%s'", conf.Type)
+	}
+}
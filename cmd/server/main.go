@@ -2,27 +2,17 @@ package main
 
 import (
 	"context"
-	"crypto/subtle"
-	"encoding/json"
 	"flag"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"strconv"
-
-	"github.com/bornholm/go-webdav"
-	"github.com/bornholm/go-webdav/filesystem"
-	webdavHandler "github.com/bornholm/go-webdav/handler"
-	"github.com/bornholm/go-webdav/middleware/cache"
-	"github.com/bornholm/go-webdav/middleware/deadprops"
-	"github.com/bornholm/go-webdav/middleware/logger"
-	"github.com/caarlos0/env/v11"
+	"os/signal"
+	"syscall"
+
 	"github.com/pkg/errors"
-	sloghttp "github.com/samber/slog-http"
 
 	_ "github.com/bornholm/go-webdav/filesystem/all"
-	"github.com/go-playground/validator/v10"
 )
 
 var (
@@ -38,8 +28,8 @@ func init() {
 }
 
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	flag.Parse()
 
@@ -51,126 +41,127 @@ func main() {
 
 	slog.SetLogLoggerLevel(logLevel)
 
-	rawConfig, err := os.ReadFile(configFile)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		slog.ErrorContext(ctx, "could not read filesystem configuration file", slog.Any("error", errors.WithStack(err)))
+	conf, err := loadConfig(ctx, configFile)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not load configuration", slog.Any("error", errors.WithStack(err)))
 		os.Exit(1)
 	}
 
-	var conf config
-
-	if rawConfig != nil {
-		if err := json.Unmarshal(rawConfig, &conf); err != nil {
-			slog.ErrorContext(ctx, "could not parse filesystem configuration file", slog.Any("error", errors.WithStack(err)))
-			os.Exit(1)
-		}
-	}
-
-	if err := env.ParseWithOptions(&conf, env.Options{Prefix: "GOWEBDAV_"}); err != nil {
-		slog.ErrorContext(ctx, "could not parse environment variables", slog.Any("error", errors.WithStack(err)))
+	inst, err := newInstance(ctx, conf, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not create server", slog.Any("error", errors.WithStack(err)))
 		os.Exit(1)
 	}
 
-	validate := validator.New()
-	if err := validate.StructCtx(ctx, &conf); err != nil {
-		slog.ErrorContext(ctx, "could not validate config", slog.Any("error", errors.WithStack(err)))
-		os.Exit(1)
-	}
+	reloader := newReloader(configFile, inst)
 
-	slog.InfoContext(ctx, "creating filesystem", "type", conf.Filesystem.Type)
+	// In-flight requests are not canceled by the termination signals,
+	// in order to let them complete during the shutdown
+	requestCtx := context.WithoutCancel(ctx)
 
-	fs, err := filesystem.New(filesystem.Type(conf.Filesystem.Type), conf.Filesystem.Options.Value)
-	if err != nil {
-		slog.ErrorContext(ctx, "could not create filesystem", slog.Any("error", errors.WithStack(err)))
-		os.Exit(1)
-	}
+	var servers []*http.Server
 
-	middlewares := []webdav.Middleware{
-		logger.Middleware(slog.Default()),
-	}
+	serveErr := make(chan error, 2)
 
-	if conf.Cache.Enabled {
-		slog.InfoContext(ctx, "enabling metadata cache", "ttl", conf.Cache.TTL)
-		cacheStore := cache.NewMemoryStore(conf.Cache.TTL)
-		middlewares = append(middlewares, cache.Middleware(cacheStore))
-	}
+	if conf.Admin.Address != "" {
+		adminServer := &http.Server{
+			Addr:    conf.Admin.Address,
+			Handler: reloader.AdminHandler(),
+			BaseContext: func(l net.Listener) context.Context {
+				return requestCtx
+			},
+		}
 
-	middlewares = append(middlewares, deadprops.Middleware(deadprops.NewMemStore()))
+		servers = append(servers, adminServer)
 
-	var handler http.Handler = webdavHandler.New(
-		fs,
-		webdavHandler.WithMiddlewares(middlewares...),
-	)
+		go func() {
+			slog.InfoContext(ctx, "administration api listening", "address", conf.Admin.Address)
 
-	slogMiddleware := sloghttp.New(slog.Default())
-	handler = slogMiddleware(handler)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+	}
 
-	if conf.Auth.Enabled && len(conf.Auth.Users) > 0 {
-		slog.InfoContext(ctx, "enabling basic auth", "total_users", len(conf.Auth.Users))
-		handler = basicAuth(handler, "go-webdav", conf.Auth.Users)
+	tlsConfig, err := newTLSConfig(conf.Listener.TLS, requiresClientCertificates(conf.Auth))
+	if err != nil {
+		slog.ErrorContext(ctx, "could not create tls configuration", slog.Any("error", errors.WithStack(err)))
+		os.Exit(1)
 	}
 
-	if conf.MDNS.Enabled {
-		_, rawPort, err := net.SplitHostPort(address)
-		if err != nil {
-			slog.ErrorContext(ctx, "could not parse listening address", slog.Any("error", errors.WithStack(err)))
-			os.Exit(1)
-		}
+	if tlsConfig == nil && requiresClientCertificates(conf.Auth) {
+		slog.WarnContext(ctx, "client certificates authentication requires tls to be enabled")
+	}
 
-		port, err := strconv.ParseInt(rawPort, 10, 32)
-		if err != nil {
-			slog.ErrorContext(ctx, "could not parse listening port", slog.Any("error", errors.WithStack(err)))
-			os.Exit(1)
-		}
+	listener, err := newListener(ctx, conf.Listener, address)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not listen", slog.Any("error", errors.WithStack(err)))
+		os.Exit(1)
+	}
 
-		slog.InfoContext(ctx, "enabling mdns announce", "port", port)
-		if err := startAnnouncingService(ctx, int(port)); err != nil {
-			slog.ErrorContext(ctx, "could not announce service", slog.Any("error", errors.WithStack(err)))
-			os.Exit(1)
+	if conf.MDNS.Enabled {
+		if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+			slog.InfoContext(ctx, "enabling mdns announce", "port", addr.Port)
+			if err := startAnnouncingService(ctx, addr.Port); err != nil {
+				slog.ErrorContext(ctx, "could not announce service", slog.Any("error", errors.WithStack(err)))
+				os.Exit(1)
+			}
 		}
 	}
 
 	server := &http.Server{
-		Addr:    address,
-		Handler: handler,
+		Handler:   reloader.Handler(),
+		TLSConfig: tlsConfig,
+		Protocols: newProtocols(conf.Listener),
 		BaseContext: func(l net.Listener) context.Context {
-			return ctx
+			return requestCtx
 		},
 	}
 
-	slog.InfoContext(ctx, "listening", "address", address)
+	servers = append(servers, server)
 
-	if err := server.ListenAndServe(); err != nil {
-		slog.ErrorContext(ctx, err.Error(), slog.Any("error", errors.WithStack(err)))
-		os.Exit(1)
-	}
-}
+	slog.InfoContext(ctx, "listening", "address", listener.Addr().String(), "tls", tlsConfig != nil)
 
-func basicAuth(handler http.Handler, realm string, users map[string]string) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, pass, ok := r.BasicAuth()
+	reloader.Watch(ctx)
 
-		unauthorized := func() {
-			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
-			w.WriteHeader(401)
-			w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
+	go func() {
+		if tlsConfig != nil {
+			// Certificates are provided by the TLS configuration
+			serveErr <- server.ServeTLS(listener, "", "")
+		} else {
+			serveErr <- server.Serve(listener)
 		}
+	}()
 
-		username := user
-		password, exists := users[user]
-		if !exists { // Prevent timing attack
-			username = "dummy"
-			password = "dummy"
-		}
+	exitCode := 0
 
-		isPasswordOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
-		isUsernameOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+	select {
+	case err := <-serveErr:
+		// The other servers are shut down before exiting
+		slog.ErrorContext(ctx, err.Error(), slog.Any("error", errors.WithStack(err)))
+		exitCode = 1
 
-		if !exists || !ok || !isPasswordOK || !isUsernameOK {
-			unauthorized()
-			return
-		}
+	case <-ctx.Done():
+		// Restore the default behavior, a second signal terminating the process immediately
+		stop()
+	}
+
+	shutdownTimeout := reloader.Config().ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	slog.InfoContext(ctx, "shutting down", "timeout", shutdownTimeout)
+
+	shutdown(ctx, shutdownTimeout, servers...)
 
-		handler.ServeHTTP(w, r)
-	})
+	// Requests served with a previous configuration may still be running
+	// after an interrupted shutdown
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	reloader.Close(closeCtx)
+	cancel()
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
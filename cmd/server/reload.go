@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultWatchInterval is the default interval between two checks of the configuration file
+const defaultWatchInterval = 5 * time.Second
+
+// restartRequired are the configuration options only applied on startup
+var restartRequired = []string{"listener", "admin.address", "mdns", "reload"}
+
+// reloader serves the requests with the instance built from the current
+// configuration, swapping it atomically on reload.
+type reloader struct {
+	path    string
+	current atomic.Pointer[instance]
+
+	// Serializes the reloads
+	mu sync.Mutex
+	// Closed once the resources of the last retired instance are released
+	released chan struct{}
+}
+
+func newReloader(path string, inst *instance) *reloader {
+	r := &reloader{
+		path:     path,
+		released: make(chan struct{}),
+	}
+
+	close(r.released)
+	r.current.Store(inst)
+
+	return r
+}
+
+// Config returns the current configuration.
+func (r *reloader) Config() *config {
+	return r.current.Load().conf
+}
+
+// Handler returns the webdav handler of the current instance.
+func (r *reloader) Handler() http.Handler {
+	return r.serve(func(inst *instance) http.Handler {
+		return inst.handler
+	})
+}
+
+// AdminHandler returns the administration API handler of the current instance.
+func (r *reloader) AdminHandler() http.Handler {
+	return r.serve(func(inst *instance) http.Handler {
+		return inst.adminHandler
+	})
+}
+
+func (r *reloader) serve(handler func(inst *instance) http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for {
+			inst := r.current.Load()
+
+			// The instance has been retired in the meantime, use the new one
+			if !inst.acquire() {
+				continue
+			}
+
+			defer inst.release()
+
+			handler(inst).ServeHTTP(w, req)
+
+			return
+		}
+	})
+}
+
+// Reload loads the configuration and replaces the current instance.
+// The current instance is kept if the configuration is invalid.
+// Resources not reused by the new instance are released once the requests
+// served by the previous one are completed.
+func (r *reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.current.Load()
+
+	conf, err := loadConfig(ctx, r.path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	changes, err := diffConfig(prev.conf, conf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(changes) == 0 {
+		slog.InfoContext(ctx, "configuration unchanged")
+		return nil
+	}
+
+	for _, c := range changes {
+		if requiresRestart(c) {
+			slog.WarnContext(ctx, "configuration change requires a restart", "change", c)
+		}
+	}
+
+	next, err := newInstance(ctx, conf, prev)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.current.Store(next)
+
+	slog.InfoContext(ctx, "configuration reloaded", "changes", changes)
+
+	previouslyReleased := r.released
+	released := make(chan struct{})
+	r.released = released
+
+	go func() {
+		defer close(released)
+
+		<-prev.retire()
+
+		// Resources shared with the previous instances are released in order
+		<-previouslyReleased
+
+		prev.res.Release(ctx, &next.res)
+	}()
+
+	return nil
+}
+
+// Close releases the resources of the current instance, once the ones of the
+// retired instances are released or the given context is done.
+func (r *reloader) Close(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.released:
+	case <-ctx.Done():
+		slog.WarnContext(ctx, "releasing resources still used by in-flight requests")
+	}
+
+	r.current.Load().res.Close(context.WithoutCancel(ctx))
+}
+
+// Watch reloads the configuration on SIGHUP and, if enabled, when the
+// configuration file is modified, until the given context is canceled.
+func (r *reloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	conf := r.Config().Reload
+
+	interval := conf.WatchInterval
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+
+		var ticks <-chan time.Time
+		if conf.Watch {
+			slog.InfoContext(ctx, "watching configuration file", "path", r.path, "interval", interval)
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			ticks = ticker.C
+		}
+
+		lastStat := statConfig(r.path)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-hup:
+				slog.InfoContext(ctx, "reloading configuration", "path", r.path)
+
+			case <-ticks:
+				stat := statConfig(r.path)
+				if stat == lastStat {
+					continue
+				}
+
+				slog.InfoContext(ctx, "configuration file modified, reloading", "path", r.path)
+			}
+
+			lastStat = statConfig(r.path)
+
+			if err := r.Reload(ctx); err != nil {
+				slog.ErrorContext(ctx, "could not reload configuration", slog.Any("error", errors.WithStack(err)))
+			}
+		}
+	}()
+}
+
+type configStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statConfig(path string) configStat {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStat{}
+	}
+
+	return configStat{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
+
+// diffConfig returns the paths of the options modified between the given
+// configurations, prefixed by "+" when added, "-" when removed and "~" when changed.
+// Values are omitted as they may be secrets.
+func diffConfig(prev *config, next *config) ([]string, error) {
+	prevValues, err := toGeneric(prev)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	nextValues, err := toGeneric(next)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var changes []string
+	diffValues(&changes, "", prevValues, nextValues)
+
+	slices.Sort(changes)
+
+	return changes, nil
+}
+
+func diffValues(changes *[]string, path string, prev any, next any) {
+	prevMap, prevIsMap := prev.(map[string]any)
+	nextMap, nextIsMap := next.(map[string]any)
+
+	if !prevIsMap || !nextIsMap {
+		if !reflect.DeepEqual(prev, next) {
+			*changes = append(*changes, "~"+path)
+		}
+
+		return
+	}
+
+	for key, prevValue := range prevMap {
+		nextValue, exists := nextMap[key]
+		if !exists {
+			*changes = append(*changes, "-"+joinPath(path, key))
+			continue
+		}
+
+		diffValues(changes, joinPath(path, key), prevValue, nextValue)
+	}
+
+	for key := range nextMap {
+		if _, exists := prevMap[key]; !exists {
+			*changes = append(*changes, "+"+joinPath(path, key))
+		}
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// toGeneric converts the given configuration to its JSON representation.
+func toGeneric(conf *config) (any, error) {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return value, nil
+}
+
+// requiresRestart returns true if the given change is only applied on startup.
+func requiresRestart(change string) bool {
+	path := change[1:]
+
+	for _, prefix := range restartRequired {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestReloader(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+
+	writeConfig := func(data string) {
+		if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	configTemplate := `{
+		"filesystem": { "type": "local", "options": { "dir": %q } },
+		"auth": { "users": { %s } },
+		"cache": { "enabled": true },
+		"mdns": { "enabled": false }
+	}`
+
+	writeConfig(fmt.Sprintf(configTemplate, dir, `"alice": "a"`))
+
+	conf, err := loadConfig(ctx, configPath)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	first, err := newInstance(ctx, conf, nil)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	reloader := newReloader(configPath, first)
+	defer reloader.Close(ctx)
+
+	handler := reloader.Handler()
+
+	assertStatus := func(username string, password string, expected int) {
+		t.Helper()
+
+		req := httptest.NewRequest("PROPFIND", "/", nil)
+		req.SetBasicAuth(username, password)
+		req.Header.Set("Depth", "0")
+
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if e, g := expected, res.Code; e != g {
+			t.Errorf("%s: expected status %v, got %v", username, e, g)
+		}
+	}
+
+	assertStatus("alice", "a", http.StatusMultiStatus)
+	assertStatus("bob", "b", http.StatusUnauthorized)
+
+	// Replace the users
+	writeConfig(fmt.Sprintf(configTemplate, dir, `"bob": "b"`))
+
+	if err := reloader.Reload(ctx); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	assertStatus("alice", "a", http.StatusUnauthorized)
+	assertStatus("bob", "b", http.StatusMultiStatus)
+
+	second := reloader.current.Load()
+
+	if first.fs != second.fs {
+		t.Errorf("expected the filesystem to be reused")
+	}
+
+	if first.lockSystem != second.lockSystem {
+		t.Errorf("expected the lock system to be reused")
+	}
+
+	if first.cacheStore != second.cacheStore {
+		t.Errorf("expected the cache store to be reused")
+	}
+
+	// Invalid configurations are refused
+	writeConfig(`{ "filesystem": { "type": "unknown" } }`)
+
+	if err := reloader.Reload(ctx); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if reloader.current.Load() != second {
+		t.Errorf("expected the current instance to be kept")
+	}
+
+	assertStatus("bob", "b", http.StatusMultiStatus)
+}
+
+func TestDiffConfig(t *testing.T) {
+	prev := &config{
+		Auth: authConfig{
+			Users: map[string]string{"alice": "a", "bob": "b"},
+		},
+		Listener: listenerConfig{Address: ":3000"},
+	}
+
+	next := &config{
+		Auth: authConfig{
+			Users: map[string]string{"alice": "changed", "carol": "c"},
+		},
+		Listener: listenerConfig{Address: ":4000"},
+	}
+
+	changes, err := diffConfig(prev, next)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expected := []string{"+auth.users.carol", "-auth.users.bob", "~auth.users.alice", "~listener.address"}
+	if !slices.Equal(expected, changes) {
+		t.Errorf("changes: expected %v, got %v", expected, changes)
+	}
+
+	if !requiresRestart("~listener.address") {
+		t.Errorf("expected listener changes to require a restart")
+	}
+
+	if requiresRestart("~auth.users.alice") {
+		t.Errorf("expected users changes not to require a restart")
+	}
+}
+
+func TestReloaderClose(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+
+	writeConfig := func(users string) {
+		data := fmt.Sprintf(`{ "filesystem": { "type": "memory" }, "auth": { "users": { %s } }, "mdns": { "enabled": false } }`, users)
+		if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	writeConfig(`"alice": "a"`)
+
+	conf, err := loadConfig(ctx, configPath)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	first, err := newInstance(ctx, conf, nil)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	reloader := newReloader(configPath, first)
+
+	// A request is still served by the first instance
+	if !first.acquire() {
+		t.Fatalf("expected the instance to be acquired")
+	}
+
+	writeConfig(`"bob": "b"`)
+
+	if err := reloader.Reload(ctx); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+		reloader.Close(ctx)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatalf("expected the reloader to wait for the retired instance")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.release()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the reloader to be closed")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultShutdownTimeout is the default delay given to in-flight requests to complete
+const defaultShutdownTimeout = 30 * time.Second
+
+// resources are the server resources released on shutdown, in the reverse
+// order of their registration.
+type resources struct {
+	names   []string
+	closers []io.Closer
+}
+
+// Add registers the given value if it implements [io.Closer].
+func (r *resources) Add(name string, value any) {
+	closer, ok := value.(io.Closer)
+	if !ok {
+		return
+	}
+
+	r.names = append(r.names, name)
+	r.closers = append(r.closers, closer)
+}
+
+// Close releases the registered resources, logging the failures.
+func (r *resources) Close(ctx context.Context) {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		slog.DebugContext(ctx, "closing resource", "name", r.names[i])
+
+		if err := r.closers[i].Close(); err != nil {
+			slog.ErrorContext(ctx, "could not close resource", "name", r.names[i], slog.Any("error", errors.WithStack(err)))
+		}
+	}
+}
+
+// Release closes the registered resources missing from kept, i.e. the ones
+// not reused by the instance replacing the one owning them.
+func (r *resources) Release(ctx context.Context, kept *resources) {
+	var released resources
+
+	for i, closer := range r.closers {
+		if slices.Contains(kept.closers, closer) {
+			continue
+		}
+
+		released.names = append(released.names, r.names[i])
+		released.closers = append(released.closers, closer)
+	}
+
+	released.Close(ctx)
+}
+
+// shutdown stops the given servers, waiting at most timeout for the in-flight
+// requests to complete before interrupting them.
+func shutdown(ctx context.Context, timeout time.Duration, servers ...*http.Server) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.WarnContext(ctx, "interrupting in-flight requests", "timeout", timeout, slog.Any("error", errors.WithStack(err)))
+
+			if err := server.Close(); err != nil {
+				slog.ErrorContext(ctx, "could not close server", slog.Any("error", errors.WithStack(err)))
+			}
+		}
+	}
+}
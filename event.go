@@ -0,0 +1,85 @@
+package webdav
+
+import (
+	"io"
+	"sync"
+)
+
+// EventType is the kind of change reported by an [EventSource].
+type EventType int
+
+const (
+	// EventCreate reports the creation of a file or a directory
+	EventCreate EventType = iota + 1
+	// EventWrite reports the modification of the content or of the attributes of a resource
+	EventWrite
+	// EventRemove reports the removal of a resource and of its descendants
+	EventRemove
+	// EventRename reports the move of a resource and of its descendants from OldName to Name
+	EventRename
+	// EventOverflow reports that changes have been lost, any resource under Name may have changed
+	EventOverflow
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreate:
+		return "create"
+	case EventWrite:
+		return "write"
+	case EventRemove:
+		return "remove"
+	case EventRename:
+		return "rename"
+	case EventOverflow:
+		return "overflow"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a resource of a filesystem.
+type Event struct {
+	Type EventType
+	Name string
+	// OldName is the previous name of the renamed resource
+	OldName string
+}
+
+// EventSource is implemented by filesystems able to report the changes made
+// to their resources by other processes, i.e. to invalidate caches.
+// Removals and moves made through the filesystem itself must not be reported,
+// the middlewares having already applied them. Creations and writes made
+// through the filesystem may be reported, handling them again being harmless.
+type EventSource interface {
+	// Subscribe registers fn to be called for each change until the returned
+	// function is called. The calls to fn are sequential.
+	Subscribe(fn func(Event)) (unsubscribe func())
+}
+
+// Subscribe registers fn to be called for each change of the given filesystem
+// if it implements [EventSource], until the returned subscription is closed.
+func Subscribe(fs FileSystem, fn func(Event)) io.Closer {
+	source, ok := fs.(EventSource)
+	if !ok {
+		return &subscription{}
+	}
+
+	return &subscription{unsubscribe: source.Subscribe(fn)}
+}
+
+type subscription struct {
+	unsubscribe func()
+	once        sync.Once
+}
+
+// Close implements [io.Closer].
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		if s.unsubscribe != nil {
+			s.unsubscribe()
+		}
+	})
+
+	return nil
+}
@@ -127,6 +127,95 @@ var filesystemBenchmarks = []filesystemBenchmark{
 			})
 		},
 	},
+	{
+		Name: "Readdir_Wide_1000",
+		Run: func(b *testing.B, fs webdav.FileSystem) {
+			ctx := context.Background()
+			dir := "/bench_readdir_wide"
+
+			// Setup: Create a directory with many children ONCE
+			createTree(b, fs, dir, 1, 1000)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				f, err := fs.OpenFile(ctx, dir, os.O_RDONLY, 0)
+				if err != nil {
+					b.Fatalf("%+v", err)
+				}
+
+				if _, err := f.Readdir(-1); err != nil {
+					b.Fatalf("%+v", err)
+				}
+
+				f.Close()
+			}
+		},
+	},
+	{
+		Name: "Rename_Wide_1000",
+		Run: func(b *testing.B, fs webdav.FileSystem) {
+			benchmarkRename(b, fs, "/bench_rename_wide", 1, 1000)
+		},
+	},
+	{
+		Name: "Rename_Deep_100",
+		Run: func(b *testing.B, fs webdav.FileSystem) {
+			benchmarkRename(b, fs, "/bench_rename_deep", 100, 1)
+		},
+	},
+}
+
+// benchmarkRename measures the renaming of a directory tree back and forth.
+func benchmarkRename(b *testing.B, fs webdav.FileSystem, dir string, depth int, width int) {
+	ctx := context.Background()
+
+	names := [2]string{dir, dir + "_renamed"}
+
+	// Setup: Create the tree ONCE, the previous runs leaving it renamed
+	if err := fs.RemoveAll(ctx, names[1]); err != nil {
+		b.Fatalf("%+v", err)
+	}
+
+	createTree(b, fs, dir, depth, width)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := fs.Rename(ctx, names[i%2], names[(i+1)%2]); err != nil {
+			b.Fatalf("%+v", err)
+		}
+	}
+}
+
+// createTree creates a tree of the given depth under dir, each level holding
+// the given number of empty files beside the next one. The existing tree, if
+// any, is replaced.
+func createTree(b *testing.B, fs webdav.FileSystem, dir string, depth int, width int) {
+	ctx := context.Background()
+
+	if err := fs.RemoveAll(ctx, dir); err != nil {
+		b.Fatalf("%+v", err)
+	}
+
+	for level := 0; level < depth; level++ {
+		if err := fs.Mkdir(ctx, dir, 0755); err != nil {
+			b.Fatalf("%+v", err)
+		}
+
+		for i := 0; i < width; i++ {
+			f, err := fs.OpenFile(ctx, fmt.Sprintf("%s/file_%d", dir, i), os.O_RDWR|os.O_CREATE, 0644)
+			if err != nil {
+				b.Fatalf("%+v", err)
+			}
+
+			if err := f.Close(); err != nil {
+				b.Fatalf("%+v", err)
+			}
+		}
+
+		dir = fmt.Sprintf("%s/level_%d", dir, level)
+	}
 }
 
 func RunTestSuite(b *testing.B, fs webdav.FileSystem) {
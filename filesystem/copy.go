@@ -0,0 +1,129 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// Copy duplicates the named resource of src, and its descendants, to the
+// dstName resource of dst. The destination must not exist.
+// Dead properties held by the source backend are copied if the destination
+// backend is able to hold them too.
+func Copy(ctx context.Context, src webdav.FileSystem, srcName string, dst webdav.FileSystem, dstName string) error {
+	info, err := src.Stat(ctx, srcName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if info.IsDir() {
+		if err := copyDir(ctx, src, srcName, dst, dstName, info); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		if err := copyFile(ctx, src, srcName, dst, dstName, info); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := copyProperties(ctx, src, srcName, dst, dstName); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func copyDir(ctx context.Context, src webdav.FileSystem, srcName string, dst webdav.FileSystem, dstName string, info os.FileInfo) error {
+	if err := dst.Mkdir(ctx, dstName, info.Mode().Perm()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	dir, err := src.OpenFile(ctx, srcName, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	children, err := dir.Readdir(-1)
+	if err != nil {
+		dir.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := dir.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, c := range children {
+		if err := Copy(ctx, src, path.Join(srcName, c.Name()), dst, path.Join(dstName, c.Name())); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func copyFile(ctx context.Context, src webdav.FileSystem, srcName string, dst webdav.FileSystem, dstName string, info os.FileInfo) error {
+	in, err := src.OpenFile(ctx, srcName, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer in.Close()
+
+	out, err := dst.OpenFile(ctx, dstName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func copyProperties(ctx context.Context, src webdav.FileSystem, srcName string, dst webdav.FileSystem, dstName string) error {
+	srcProps, ok := src.(PropertiesFileSystem)
+	if !ok {
+		return nil
+	}
+
+	dstProps, ok := dst.(PropertiesFileSystem)
+	if !ok {
+		return nil
+	}
+
+	props, err := srcProps.GetProperties(ctx, srcName)
+	if err != nil {
+		if errors.Is(err, ErrNotSupported) {
+			return nil
+		}
+
+		return errors.WithStack(err)
+	}
+
+	if len(props) == 0 {
+		return nil
+	}
+
+	patch := webdav.Proppatch{
+		Props: slices.Collect(maps.Values(props)),
+	}
+
+	if err := dstProps.PatchProperties(ctx, dstName, []webdav.Proppatch{patch}); err != nil && !errors.Is(err, ErrNotSupported) {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
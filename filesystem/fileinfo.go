@@ -0,0 +1,74 @@
+package filesystem
+
+import (
+	"os"
+	"time"
+)
+
+type namedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+// Name implements [os.FileInfo].
+func (i *namedFileInfo) Name() string {
+	return i.name
+}
+
+// WithName returns the given file information under another name, i.e. to
+// expose a resource at another path than the one of its backend.
+func WithName(info os.FileInfo, name string) os.FileInfo {
+	return &namedFileInfo{
+		FileInfo: info,
+		name:     name,
+	}
+}
+
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+// IsDir implements [os.FileInfo].
+func (i *dirInfo) IsDir() bool {
+	return true
+}
+
+// ModTime implements [os.FileInfo].
+func (i *dirInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+// Mode implements [os.FileInfo].
+func (i *dirInfo) Mode() os.FileMode {
+	return os.ModeDir | 0555
+}
+
+// Name implements [os.FileInfo].
+func (i *dirInfo) Name() string {
+	return i.name
+}
+
+// Size implements [os.FileInfo].
+func (i *dirInfo) Size() int64 {
+	return 0
+}
+
+// Sys implements [os.FileInfo].
+func (i *dirInfo) Sys() any {
+	return nil
+}
+
+// NewDirInfo returns the information of a read-only directory existing
+// without backend, i.e. the parent directory of mount points.
+func NewDirInfo(name string, modTime time.Time) os.FileInfo {
+	return &dirInfo{
+		name:    name,
+		modTime: modTime,
+	}
+}
+
+var (
+	_ os.FileInfo = &namedFileInfo{}
+	_ os.FileInfo = &dirInfo{}
+)
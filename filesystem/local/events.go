@@ -0,0 +1,280 @@
+package local
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+)
+
+// ownChangeDelay is the time during which the event matching a change made
+// through the filesystem is still expected once it is completed
+const ownChangeDelay = time.Second
+
+// notifier dispatches the changes reported by the directory watcher to the
+// subscribers of the filesystem.
+type notifier struct {
+	mu          sync.Mutex
+	watcher     *watcher
+	starting    bool
+	subscribers map[int]func(webdav.Event)
+	nextID      int
+	closed      bool
+	// Removals and moves in progress or recently made through the filesystem
+	own []*ownChange
+}
+
+// ownChange is a removal or a move made through the filesystem, already
+// applied by the middlewares: the event reporting it is not dispatched.
+// Creations and writes are dispatched, invalidating them being harmless.
+type ownChange struct {
+	event webdav.Event
+	// Number of operations in progress
+	pending int
+	// Time until which the matching event is expected, once completed
+	until time.Time
+}
+
+// Subscribe implements [webdav.EventSource].
+// The directory is watched as long as functions are registered, if enabled
+// and supported by the platform. Changes below the symbolic links are not
+// reported.
+func (fs *FileSystem) Subscribe(fn func(webdav.Event)) func() {
+	n := &fs.events
+
+	n.mu.Lock()
+
+	if n.subscribers == nil {
+		n.subscribers = map[int]func(webdav.Event){}
+	}
+
+	id := n.nextID
+	n.nextID++
+
+	n.subscribers[id] = fn
+
+	start := fs.watch && n.watcher == nil && !n.starting && !n.closed
+	if start {
+		n.starting = true
+	}
+
+	n.mu.Unlock()
+
+	// Watching the directory tree walks it, the lock is not held meanwhile
+	if start {
+		fs.startWatcher()
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			n.mu.Lock()
+
+			delete(n.subscribers, id)
+
+			var w *watcher
+			if len(n.subscribers) == 0 {
+				w = n.watcher
+				n.watcher = nil
+			}
+
+			n.mu.Unlock()
+
+			if w != nil {
+				w.Close()
+			}
+		})
+	}
+}
+
+// startWatcher watches the directory, unless the filesystem has been closed
+// or all the subscriptions cancelled in the meantime.
+func (fs *FileSystem) startWatcher() {
+	n := &fs.events
+
+	w, err := newWatcher(fs.dir, fs.dispatch)
+
+	n.mu.Lock()
+
+	n.starting = false
+
+	if err != nil {
+		n.mu.Unlock()
+		slog.Warn("could not watch directory, changes made by other processes will not be reported", "dir", fs.dir, slog.Any("error", errors.WithStack(err)))
+		return
+	}
+
+	if n.closed || len(n.subscribers) == 0 {
+		n.mu.Unlock()
+		w.Close()
+		return
+	}
+
+	n.watcher = w
+
+	n.mu.Unlock()
+}
+
+// Close implements [io.Closer].
+// The directory watcher, if any, is stopped.
+func (fs *FileSystem) Close() error {
+	n := &fs.events
+
+	n.mu.Lock()
+	w := n.watcher
+	n.watcher = nil
+	n.closed = true
+	n.mu.Unlock()
+
+	if w == nil {
+		return nil
+	}
+
+	return w.Close()
+}
+
+// dispatch reports the given event to the subscribers, unless it results
+// from a removal or a move made through the filesystem.
+func (fs *FileSystem) dispatch(e webdav.Event) {
+	n := &fs.events
+
+	n.mu.Lock()
+
+	if n.isOwn(e) {
+		n.mu.Unlock()
+		return
+	}
+
+	subscribers := slices.Collect(maps.Values(n.subscribers))
+
+	n.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
+
+// beginRemove registers the removal of the given native path made through
+// the filesystem, returning the function to call once it is completed.
+func (fs *FileSystem) beginRemove(filename string) func() {
+	name, ok := fs.relName(filename)
+	if !ok {
+		return func() {}
+	}
+
+	return fs.beginChange(webdav.Event{Type: webdav.EventRemove, Name: name})
+}
+
+// beginRename registers the move of the given native paths made through
+// the filesystem, returning the function to call once it is completed.
+func (fs *FileSystem) beginRename(oldFilename string, newFilename string) func() {
+	oldName, ok := fs.relName(oldFilename)
+	if !ok {
+		return func() {}
+	}
+
+	newName, ok := fs.relName(newFilename)
+	if !ok {
+		return func() {}
+	}
+
+	return fs.beginChange(webdav.Event{Type: webdav.EventRename, Name: newName, OldName: oldName})
+}
+
+// beginChange registers a change made through the filesystem, reported by
+// the given event.
+func (fs *FileSystem) beginChange(e webdav.Event) func() {
+	n := &fs.events
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.watcher == nil && !n.starting {
+		return func() {}
+	}
+
+	change := &ownChange{event: e, pending: 1}
+	n.own = append(n.own, change)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			change.pending--
+			change.until = time.Now().Add(ownChangeDelay)
+		})
+	}
+}
+
+// isOwn returns true if the given event reports a removal or a move made
+// through the filesystem. The event of a removal is preceded by the ones of
+// the descendants of the removed resource. The changes whose event has been
+// received, or expired, are forgotten.
+func (n *notifier) isOwn(e webdav.Event) bool {
+	now := time.Now()
+
+	n.own = slices.DeleteFunc(n.own, func(change *ownChange) bool {
+		return change.pending == 0 && now.After(change.until)
+	})
+
+	for i, change := range n.own {
+		expected := change.event
+
+		if e == expected {
+			n.own = slices.Delete(n.own, i, i+1)
+			return true
+		}
+
+		if e.Type == webdav.EventRemove && expected.Type == webdav.EventRemove && strings.HasPrefix(e.Name, expected.Name+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// relName returns the name of the given native path relative to the root
+// directory, true if it is under the latter.
+func (fs *FileSystem) relName(filename string) (string, bool) {
+	for _, root := range []string{fs.dir, fs.realDir()} {
+		if !isUnder(filename, root) {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, filename)
+		if err != nil {
+			return "", false
+		}
+
+		return path.Clean("/" + filepath.ToSlash(rel)), true
+	}
+
+	return "", false
+}
+
+// realDir returns the root directory with its symbolic links resolved.
+func (fs *FileSystem) realDir() string {
+	real, err := filepath.EvalSymlinks(fs.dir)
+	if err != nil {
+		return fs.dir
+	}
+
+	return real
+}
+
+var (
+	_ webdav.EventSource = &FileSystem{}
+	_ io.Closer          = &FileSystem{}
+)
@@ -0,0 +1,321 @@
+package local
+
+import (
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// tempPrefix is the name prefix of the temporary files, omitted from listings
+const tempPrefix = ".webdav-tmp-"
+
+// File is an open file of the local filesystem. Written files are
+// temporary files renamed over the original ones when closed, if enabled.
+type File struct {
+	fs       *FileSystem
+	name     string
+	filename string
+	file     *os.File
+
+	// Whether the file is a temporary file replacing the original one once closed
+	temp bool
+	// Whether the original file is read until the temporary file is created
+	// by the first write
+	deferred bool
+	// File replaced by the temporary file, nil if it did not exist
+	replaced os.FileInfo
+	append   bool
+	created  bool
+	written  bool
+}
+
+// Close implements webdav.File.
+func (f *File) Close() error {
+	if f.temp {
+		return f.commit()
+	}
+
+	if f.fs.sync && f.written {
+		if err := f.file.Sync(); err != nil {
+			f.file.Close()
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	if f.created {
+		return f.fs.syncDir(filepath.Dir(f.filename))
+	}
+
+	return nil
+}
+
+// commit renames the temporary file over the original one, with the
+// permissions, the ownership and the dead properties of the latter.
+func (f *File) commit() error {
+	tempName := f.file.Name()
+
+	if f.fs.sync {
+		if err := f.file.Sync(); err != nil {
+			f.abort()
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := f.file.Close(); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+
+	if err := f.copyAttrs(tempName); err != nil {
+		os.Remove(tempName)
+		return errors.WithStack(err)
+	}
+
+	if err := os.Rename(tempName, f.filename); err != nil {
+		os.Remove(tempName)
+		return err
+	}
+
+	return f.fs.syncDir(filepath.Dir(f.filename))
+}
+
+// copyAttrs applies the attributes of the replaced file, or those configured
+// for the created files, to the temporary file.
+func (f *File) copyAttrs(tempName string) error {
+	if f.replaced == nil {
+		return f.fs.setAttrs(tempName, f.fs.fileMode)
+	}
+
+	if err := os.Chmod(tempName, f.replaced.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if uid, gid, ok := fileOwner(f.replaced); ok && (uid != os.Getuid() || gid != os.Getgid()) {
+		// Only privileged processes can give their files away
+		if err := os.Lchown(tempName, uid, gid); err != nil && !errors.Is(err, os.ErrPermission) {
+			return errors.WithStack(err)
+		}
+	}
+
+	data, err := getXattr(f.filename, propertiesXattr)
+	if err != nil {
+		if errors.Is(err, filesystem.ErrNotSupported) {
+			return nil
+		}
+
+		return errors.WithStack(err)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := setXattr(tempName, propertiesXattr, data); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// startTemp replaces the original file by a temporary file with its content,
+// at the same offset.
+func (f *File) startTemp() error {
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	temp, err := createTemp(filepath.Dir(f.filename), f.replaced.Mode().Perm())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := copyContent(temp, f.filename); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return errors.WithStack(err)
+	}
+
+	if _, err := temp.Seek(offset, io.SeekStart); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return errors.WithStack(err)
+	}
+
+	f.file.Close()
+
+	f.file = temp
+	f.temp = true
+	f.deferred = false
+
+	return nil
+}
+
+// abort closes and removes the temporary file.
+func (f *File) abort() {
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
+
+// Read implements webdav.File.
+func (f *File) Read(p []byte) (n int, err error) {
+	return f.file.Read(p)
+}
+
+// Readdir implements webdav.File.
+// Temporary files and symbolic links denied by the filesystem policy are
+// omitted, the allowed symbolic links being described by their target.
+func (f *File) Readdir(count int) ([]fs.FileInfo, error) {
+	if count <= 0 {
+		children, err := f.file.Readdir(count)
+		if err != nil {
+			return nil, err
+		}
+
+		return f.visible(children), nil
+	}
+
+	var result []fs.FileInfo
+	for len(result) < count {
+		children, err := f.file.Readdir(count - len(result))
+
+		result = append(result, f.visible(children)...)
+
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(result) > 0 {
+				return result, nil
+			}
+
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// visible returns the given children to list.
+func (f *File) visible(children []fs.FileInfo) []fs.FileInfo {
+	visible := make([]fs.FileInfo, 0, len(children))
+
+	for _, child := range children {
+		if strings.HasPrefix(child.Name(), tempPrefix) {
+			continue
+		}
+
+		if child.Mode()&os.ModeSymlink == 0 {
+			visible = append(visible, child)
+			continue
+		}
+
+		if f.fs.symlinks == SymlinkDeny {
+			continue
+		}
+
+		real, err := f.fs.realPath(filepath.Join(f.filename, child.Name()))
+		if err != nil {
+			continue
+		}
+
+		info, err := os.Stat(real)
+		if err != nil {
+			// Dangling symbolic link
+			continue
+		}
+
+		visible = append(visible, filesystem.WithName(info, child.Name()))
+	}
+
+	return visible
+}
+
+// Seek implements webdav.File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+// Stat implements webdav.File.
+func (f *File) Stat() (fs.FileInfo, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.temp {
+		return filesystem.WithName(info, path.Base(path.Clean("/"+f.name))), nil
+	}
+
+	return info, nil
+}
+
+// Write implements webdav.File.
+func (f *File) Write(p []byte) (n int, err error) {
+	if f.deferred {
+		if err := f.startTemp(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	if f.append {
+		if _, err := f.file.Seek(0, io.SeekEnd); err != nil {
+			return 0, err
+		}
+	}
+
+	f.written = true
+
+	return f.file.Write(p)
+}
+
+var _ webdav.File = &File{}
+
+// createTemp creates a new temporary file in the given directory.
+func createTemp(dir string, mode os.FileMode) (*os.File, error) {
+	for {
+		name := filepath.Join(dir, tempPrefix+strconv.FormatUint(rand.Uint64(), 36))
+
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	}
+}
+
+// copyContent copies the content of the given file at the beginning of the
+// temporary one.
+func copyContent(temp *os.File, filename string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer src.Close()
+
+	if _, err := io.Copy(temp, src); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
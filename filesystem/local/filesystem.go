@@ -1,7 +1,342 @@
 package local
 
-import "golang.org/x/net/webdav"
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
 
-func NewFileSystem(dirPath string) webdav.FileSystem {
-	return webdav.Dir(dirPath)
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// propertiesXattr is the extended attribute holding the dead properties of a file.
+const propertiesXattr = "user.webdav.deadprops"
+
+// writeFlags are the open flags implying a modification of the file
+const writeFlags = os.O_WRONLY | os.O_APPEND | os.O_RDWR | os.O_TRUNC | os.O_CREATE
+
+// FileSystem is a local filesystem backend storing dead properties
+// as extended attributes when the underlying filesystem supports them.
+type FileSystem struct {
+	dir          string
+	atomicWrites bool
+	sync         bool
+	symlinks     SymlinkPolicy
+	fileMode     os.FileMode
+	dirMode      os.FileMode
+	uid          int
+	gid          int
+	watch        bool
+	events       notifier
+}
+
+// Mkdir implements [webdav.FileSystem].
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	filename, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if fs.dirMode != 0 {
+		perm = fs.dirMode
+	}
+
+	if err := os.Mkdir(filename, perm); err != nil {
+		return err
+	}
+
+	if err := fs.setAttrs(filename, fs.dirMode); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return fs.syncDir(filepath.Dir(filename))
+}
+
+// OpenFile implements [webdav.FileSystem].
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	filename, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	exists := err == nil
+
+	switch {
+	case exists && info.IsDir():
+		if flag&writeFlags != 0 && flag != os.O_RDWR {
+			return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+		}
+
+		// PROPPATCH requests open their target read-write, the directory
+		// is opened read-only instead
+		flag = os.O_RDONLY
+
+	case exists && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, os.ErrExist
+
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, os.ErrNotExist
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 && fs.atomicWrites {
+		return fs.openTemp(name, filename, flag, perm, info)
+	}
+
+	file, err := os.OpenFile(filename, flag, fs.createMode(perm))
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists && flag&os.O_CREATE != 0 {
+		if err := fs.setAttrs(filename, fs.fileMode); err != nil {
+			file.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return &File{
+		fs:       fs,
+		name:     name,
+		filename: filename,
+		file:     file,
+		created:  !exists,
+		written:  flag&os.O_TRUNC != 0,
+	}, nil
+}
+
+// openTemp opens a temporary file beside the given one, renamed over it once
+// closed. Files neither created nor truncated are read until their first
+// write, which copies their content to the temporary file: opening them
+// without writing, i.e. for PROPPATCH requests, leaves them untouched.
+func (fs *FileSystem) openTemp(name string, filename string, flag int, perm os.FileMode, info os.FileInfo) (*File, error) {
+	file := &File{
+		fs:       fs,
+		name:     name,
+		filename: filename,
+		replaced: info,
+		append:   flag&os.O_APPEND != 0,
+		created:  info == nil,
+	}
+
+	if info != nil && flag&os.O_TRUNC == 0 {
+		original, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		file.file = original
+		file.deferred = true
+
+		return file, nil
+	}
+
+	mode := fs.createMode(perm)
+	if info != nil {
+		mode = info.Mode().Perm()
+	}
+
+	temp, err := createTemp(filepath.Dir(filename), mode)
+	if err != nil {
+		return nil, err
+	}
+
+	file.file = temp
+	file.temp = true
+	file.written = true
+
+	return file, nil
+}
+
+// RemoveAll implements [webdav.FileSystem].
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	filename, err := fs.resolveParent(name)
+	if err != nil {
+		return err
+	}
+
+	if filename == fs.dir {
+		// Prohibit removing the root directory
+		return os.ErrInvalid
+	}
+
+	defer fs.beginRemove(filename)()
+
+	if err := os.RemoveAll(filename); err != nil {
+		return err
+	}
+
+	return fs.syncDir(filepath.Dir(filename))
+}
+
+// Rename implements [webdav.FileSystem].
+func (fs *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	oldFilename, err := fs.resolveParent(oldName)
+	if err != nil {
+		return err
+	}
+
+	newFilename, err := fs.resolveParent(newName)
+	if err != nil {
+		return err
+	}
+
+	if oldFilename == fs.dir || newFilename == fs.dir {
+		// Prohibit renaming from or to the root directory
+		return os.ErrInvalid
+	}
+
+	defer fs.beginRename(oldFilename, newFilename)()
+
+	if err := os.Rename(oldFilename, newFilename); err != nil {
+		return err
+	}
+
+	if err := fs.syncDir(filepath.Dir(oldFilename)); err != nil {
+		return err
+	}
+
+	if filepath.Dir(oldFilename) == filepath.Dir(newFilename) {
+		return nil
+	}
+
+	return fs.syncDir(filepath.Dir(newFilename))
+}
+
+// Stat implements [webdav.FileSystem].
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	filename, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Stat(filename)
+}
+
+// resolve returns the native path of the named file, its symbolic links
+// being resolved according to the filesystem policy.
+func (fs *FileSystem) resolve(name string) (string, error) {
+	filename := fs.join(name)
+	if filename == "" {
+		return "", os.ErrNotExist
+	}
+
+	return fs.realPath(filename)
+}
+
+// resolveParent returns the native path of the named file, the symbolic
+// links of its parent directories being resolved according to the filesystem
+// policy. The file itself is not followed if it is a symbolic link.
+func (fs *FileSystem) resolveParent(name string) (string, error) {
+	filename := fs.join(name)
+	if filename == "" {
+		return "", os.ErrNotExist
+	}
+
+	if filename == fs.dir {
+		return filename, nil
+	}
+
+	parent, err := fs.realPath(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parent, filepath.Base(filename)), nil
+}
+
+// join returns the native path of the named file, following the same rules
+// as [webdav.Dir], or an empty string if the name is invalid.
+func (fs *FileSystem) join(name string) string {
+	if filepath.Separator != '/' && strings.ContainsRune(name, filepath.Separator) || strings.Contains(name, "\x00") {
+		return ""
+	}
+
+	return filepath.Join(fs.dir, filepath.FromSlash(path.Clean("/"+name)))
 }
+
+// createMode returns the permissions of a created file.
+func (fs *FileSystem) createMode(perm os.FileMode) os.FileMode {
+	if fs.fileMode != 0 {
+		return fs.fileMode
+	}
+
+	return perm
+}
+
+// setAttrs applies the configured permissions, if any, and ownership to
+// the created file, the former bypassing the umask.
+func (fs *FileSystem) setAttrs(filename string, mode os.FileMode) error {
+	if mode != 0 {
+		if err := os.Chmod(filename, mode); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if fs.uid != -1 || fs.gid != -1 {
+		if err := os.Lchown(filename, fs.uid, fs.gid); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// syncDir flushes the entries of the given directory to the disk, if enabled.
+func (fs *FileSystem) syncDir(dir string) error {
+	if !fs.sync {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer d.Close()
+
+	// Some platforms and filesystems do not support syncing directories
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// NewFileSystem returns a filesystem serving the given directory.
+func NewFileSystem(dir string, funcs ...OptionFunc) *FileSystem {
+	opts := NewFileSystemOptions(funcs...)
+
+	if dir == "" {
+		dir = "."
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	return &FileSystem{
+		dir:          filepath.Clean(dir),
+		atomicWrites: opts.AtomicWrites,
+		sync:         opts.Sync,
+		symlinks:     opts.Symlinks,
+		fileMode:     opts.FileMode,
+		dirMode:      opts.DirMode,
+		uid:          opts.UID,
+		gid:          opts.GID,
+		watch:        opts.Watch,
+	}
+}
+
+var (
+	_ webdav.FileSystem               = &FileSystem{}
+	_ filesystem.PropertiesFileSystem = &FileSystem{}
+)
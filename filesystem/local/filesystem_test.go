@@ -1,10 +1,18 @@
 package local
 
 import (
+	"context"
+	"encoding/xml"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"testing"
+	"time"
 
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
 	"github.com/bornholm/go-webdav/filesystem/bench"
 	"github.com/bornholm/go-webdav/filesystem/testsuite"
 	"github.com/bornholm/go-webdav/litmus"
@@ -27,6 +35,372 @@ func BenchmarkFileSystem(b *testing.B) {
 	bench.RunTestSuite(b, fs)
 }
 
+func TestFileSystemAtomicWrites(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	fs := NewFileSystem(dir, WithSync(true))
+
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "before")
+
+	prop := webdav.Property{XMLName: xml.Name{Space: "urn:test", Local: "color"}, InnerXML: []byte("blue")}
+
+	err := fs.PatchProperties(ctx, "/file.txt", []webdav.Proppatch{{Props: []webdav.Property{prop}}})
+	propsSupported := !errors.Is(err, filesystem.ErrNotSupported)
+	if err != nil && propsSupported {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	file, err := fs.OpenFile(ctx, "/file.txt", os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := io.WriteString(file, "after"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Readers see the previous content until the file is closed
+	if e, g := "before", readFile(t, filepath.Join(dir, "file.txt")); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	// Temporary files are not listed
+	if e, g := []string{"file.txt"}, testsuite.ReaddirNames(t, ctx, fs, "/"); !slices.Equal(e, g) {
+		t.Errorf("readdir '/': expected %v, got %v", e, g)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "after", readFile(t, filepath.Join(dir, "file.txt")); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	// Dead properties are kept
+	if propsSupported {
+		props, err := fs.GetProperties(ctx, "/file.txt")
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := "blue", string(props[prop.XMLName].InnerXML); e != g {
+			t.Errorf("property: expected %v, got %v", e, g)
+		}
+	}
+
+	// Appended content is added to the previous one
+	file, err = fs.OpenFile(ctx, "/file.txt", os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := io.WriteString(file, "wards"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "afterwards", readFile(t, filepath.Join(dir, "file.txt")); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, len(entries); e != g {
+		t.Errorf("len(entries): expected %v, got %v", e, g)
+	}
+}
+
+func TestFileSystemAtomicWritesUntouched(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file.txt")
+
+	fs := NewFileSystem(dir)
+
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "before")
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filename, past, past); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	previous, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// PROPPATCH requests open their target read-write without writing it
+	file, err := fs.OpenFile(ctx, "/file.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	current, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !os.SameFile(previous, current) {
+		t.Errorf("expected the file not to be replaced")
+	}
+
+	if e, g := past, current.ModTime(); !e.Equal(g) {
+		t.Errorf("modification time: expected %v, got %v", e, g)
+	}
+
+	// The first write starts at the current offset
+	file, err = fs.OpenFile(ctx, "/file.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	data := make([]byte, 2)
+	if _, err := io.ReadFull(file, data); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := io.WriteString(file, "XY"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "beXYre", readFile(t, filename); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+}
+
+func TestFileSystemSymlinks(t *testing.T) {
+	ctx := context.Background()
+
+	outside := t.TempDir()
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	links := map[string]string{
+		"inside":   filepath.Join(dir, "data"),
+		"outside":  outside,
+		"dangling": filepath.Join(outside, "missing.txt"),
+	}
+
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	type testCase struct {
+		Policy   SymlinkPolicy
+		Listed   []string
+		Allowed  []string
+		Rejected []string
+	}
+
+	testCases := []testCase{
+		{
+			Policy:   SymlinkDeny,
+			Listed:   []string{"data"},
+			Allowed:  []string{"/data"},
+			Rejected: []string{"/inside", "/outside/secret.txt", "/dangling"},
+		},
+		{
+			Policy:   SymlinkFollowInside,
+			Listed:   []string{"data", "inside"},
+			Allowed:  []string{"/data", "/inside"},
+			Rejected: []string{"/outside/secret.txt", "/dangling"},
+		},
+		{
+			Policy:  SymlinkFollow,
+			Listed:  []string{"data", "inside", "outside"},
+			Allowed: []string{"/data", "/inside", "/outside/secret.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.Policy), func(t *testing.T) {
+			fs := NewFileSystem(dir, WithSymlinkPolicy(tc.Policy))
+
+			if e, g := tc.Listed, testsuite.ReaddirNames(t, ctx, fs, "/"); !slices.Equal(e, g) {
+				t.Errorf("readdir '/': expected %v, got %v", e, g)
+			}
+
+			for _, name := range tc.Allowed {
+				if _, err := fs.Stat(ctx, name); err != nil {
+					t.Errorf("stat '%s': %+v", name, errors.WithStack(err))
+				}
+			}
+
+			for _, name := range tc.Rejected {
+				if _, err := fs.Stat(ctx, name); !errors.Is(err, os.ErrPermission) {
+					t.Errorf("stat '%s': expected error '%v', got '%v'", name, os.ErrPermission, err)
+				}
+
+				if _, err := fs.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE, 0644); !errors.Is(err, os.ErrPermission) {
+					t.Errorf("create '%s': expected error '%v', got '%v'", name, os.ErrPermission, err)
+				}
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "missing.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the dangling symbolic link target not to be created")
+	}
+}
+
+func TestFileSystemModes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	fs := NewFileSystem(dir, WithFileMode(0640), WithDirMode(0750))
+
+	if err := fs.Mkdir(ctx, "/dir", 0777); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testsuite.WriteFileContent(t, ctx, fs, "/dir/file.txt", "hello")
+
+	for name, mode := range map[string]os.FileMode{"dir": 0750, "dir/file.txt": 0640} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := mode, info.Mode().Perm(); e != g {
+			t.Errorf("'%s' mode: expected %v, got %v", name, e, g)
+		}
+	}
+
+	// Replaced files keep their permissions
+	if err := os.Chmod(filepath.Join(dir, "dir/file.txt"), 0600); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testsuite.WriteFileContent(t, ctx, fs, "/dir/file.txt", "world")
+
+	info, err := os.Stat(filepath.Join(dir, "dir/file.txt"))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := os.FileMode(0600), info.Mode().Perm(); e != g {
+		t.Errorf("mode: expected %v, got %v", e, g)
+	}
+}
+
+func TestFileSystemEvents(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("changes are only watched on linux")
+	}
+
+	dir := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	fs := NewFileSystem(dir)
+	defer fs.Close()
+
+	events := make(chan gowebdav.Event, 100)
+
+	unsubscribe := fs.Subscribe(func(e gowebdav.Event) {
+		events <- e
+	})
+	defer unsubscribe()
+
+	next := func() gowebdav.Event {
+		t.Helper()
+
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event received")
+			return gowebdav.Event{}
+		}
+	}
+
+	expect := func(expected gowebdav.Event) {
+		t.Helper()
+
+		if e := next(); e != expected {
+			t.Fatalf("event: expected %+v, got %+v", expected, e)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "dir", "file.txt"), []byte("foo"), 0644); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expect(gowebdav.Event{Type: gowebdav.EventCreate, Name: "/dir/file.txt"})
+	expect(gowebdav.Event{Type: gowebdav.EventWrite, Name: "/dir/file.txt"})
+
+	if err := os.Rename(filepath.Join(dir, "dir"), filepath.Join(dir, "moved")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expect(gowebdav.Event{Type: gowebdav.EventRename, Name: "/moved", OldName: "/dir"})
+
+	// Directories are still watched once moved
+	if err := os.Remove(filepath.Join(dir, "moved", "file.txt")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expect(gowebdav.Event{Type: gowebdav.EventRemove, Name: "/moved/file.txt"})
+
+	// Writes made through the filesystem are reported, moves and removals
+	// are not
+	testsuite.WriteFileContent(t, context.Background(), fs, "/moved/own.txt", "bar")
+
+	expect(gowebdav.Event{Type: gowebdav.EventWrite, Name: "/moved/own.txt"})
+
+	if err := fs.Rename(context.Background(), "/moved", "/renamed"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testsuite.WriteFileContent(t, context.Background(), fs, "/renamed/removed.txt", "qux")
+
+	expect(gowebdav.Event{Type: gowebdav.EventWrite, Name: "/renamed/removed.txt"})
+
+	if err := fs.RemoveAll(context.Background(), "/renamed/removed.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Changes made by other processes right after are reported
+	if err := os.Remove(filepath.Join(dir, "renamed", "own.txt")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expect(gowebdav.Event{Type: gowebdav.EventRemove, Name: "/renamed/own.txt"})
+
+	if err := os.Rename(filepath.Join(dir, "renamed"), filepath.Join(dir, "moved")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expect(gowebdav.Event{Type: gowebdav.EventRename, Name: "/moved", OldName: "/renamed"})
+}
+
 func createFileSystem(t testing.TB) webdav.FileSystem {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -43,7 +417,18 @@ func createFileSystem(t testing.TB) webdav.FileSystem {
 		t.Fatalf("%+v", errors.WithStack(err))
 	}
 
-	fs := webdav.Dir(dir)
+	fs := NewFileSystem(dir)
 
 	return fs
 }
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	return string(data)
+}
@@ -2,6 +2,7 @@ package local
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/bornholm/go-webdav/filesystem"
 	"github.com/go-playground/validator/v10"
@@ -18,10 +19,27 @@ func init() {
 
 type Options struct {
 	Dir string `mapstructure:"dir" validate:"required"`
+	// Write the opened files in place, instead of renaming temporary files over them
+	DisableAtomicWrites bool `mapstructure:"disableAtomicWrites"`
+	// Flush the written files and the modified directories to the disk
+	Sync bool `mapstructure:"sync"`
+	// Symbolic links policy: "deny", "inside" (default) or "follow"
+	Symlinks string `mapstructure:"symlinks" validate:"omitempty,oneof=deny inside follow"`
+	// Permissions of the created files and directories, in octal notation (i.e. "0640")
+	FileMode string `mapstructure:"fileMode"`
+	DirMode  string `mapstructure:"dirMode"`
+	// Owner of the created files and directories, unchanged if -1
+	UID int `mapstructure:"uid" validate:"gte=-1"`
+	GID int `mapstructure:"gid" validate:"gte=-1"`
+	// Do not report the changes made to the directory by other processes
+	DisableWatch bool `mapstructure:"disableWatch"`
 }
 
 func CreateFileSystemFromOptions(options any) (webdav.FileSystem, error) {
-	opts := Options{}
+	opts := Options{
+		UID: -1,
+		GID: -1,
+	}
 
 	if err := mapstructure.Decode(options, &opts); err != nil {
 		return nil, errors.Wrapf(err, "could not parse '%s' filesystem options", Type)
@@ -36,7 +54,50 @@ func CreateFileSystemFromOptions(options any) (webdav.FileSystem, error) {
 		return nil, errors.Wrapf(err, "could not create directory '%s'", opts.Dir)
 	}
 
-	fs := webdav.Dir(opts.Dir)
+	funcs := []OptionFunc{
+		WithAtomicWrites(!opts.DisableAtomicWrites),
+		WithSync(opts.Sync),
+		WithOwner(opts.UID, opts.GID),
+		WithWatch(!opts.DisableWatch),
+	}
+
+	if opts.Symlinks != "" {
+		funcs = append(funcs, WithSymlinkPolicy(SymlinkPolicy(opts.Symlinks)))
+	}
+
+	if opts.FileMode != "" {
+		mode, err := parseMode(opts.FileMode)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse file mode")
+		}
+
+		funcs = append(funcs, WithFileMode(mode))
+	}
+
+	if opts.DirMode != "" {
+		mode, err := parseMode(opts.DirMode)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse directory mode")
+		}
+
+		funcs = append(funcs, WithDirMode(mode))
+	}
+
+	fs := NewFileSystem(opts.Dir, funcs...)
 
 	return fs, nil
 }
+
+// parseMode parses permissions in octal notation.
+func parseMode(raw string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if os.FileMode(mode)&^os.ModePerm != 0 {
+		return 0, errors.Errorf("invalid permissions '%s'", raw)
+	}
+
+	return os.FileMode(mode), nil
+}
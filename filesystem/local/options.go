@@ -0,0 +1,101 @@
+package local
+
+import "os"
+
+// SymlinkPolicy defines how symbolic links found under the root directory are handled.
+type SymlinkPolicy string
+
+const (
+	// SymlinkDeny rejects the paths traversing a symbolic link, omitted from listings
+	SymlinkDeny SymlinkPolicy = "deny"
+	// SymlinkFollowInside follows the symbolic links resolving under the root directory
+	SymlinkFollowInside SymlinkPolicy = "inside"
+	// SymlinkFollow follows every symbolic link, even outside of the root directory
+	SymlinkFollow SymlinkPolicy = "follow"
+)
+
+type FileSystemOptions struct {
+	// AtomicWrites writes the opened files to a temporary file, renamed over
+	// the original one once closed, so that readers never see partial content
+	AtomicWrites bool
+	// Sync flushes the written files and the modified directories to the disk
+	Sync bool
+	// Symlinks is the policy applied to the symbolic links
+	Symlinks SymlinkPolicy
+	// FileMode are the permissions of the created files, the requested ones
+	// filtered by the umask being used if zero
+	FileMode os.FileMode
+	// DirMode are the permissions of the created directories, the requested
+	// ones filtered by the umask being used if zero
+	DirMode os.FileMode
+	// UID and GID own the created files and directories, unchanged if -1
+	UID int
+	GID int
+	// Watch reports the changes made to the directory by other processes to
+	// the subscribers of the filesystem, on the supported platforms
+	Watch bool
+}
+
+type OptionFunc func(opts *FileSystemOptions)
+
+func WithAtomicWrites(enabled bool) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.AtomicWrites = enabled
+	}
+}
+
+func WithSync(enabled bool) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.Sync = enabled
+	}
+}
+
+func WithSymlinkPolicy(policy SymlinkPolicy) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.Symlinks = policy
+	}
+}
+
+func WithFileMode(mode os.FileMode) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.FileMode = mode
+	}
+}
+
+func WithDirMode(mode os.FileMode) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.DirMode = mode
+	}
+}
+
+func WithOwner(uid int, gid int) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.UID = uid
+		opts.GID = gid
+	}
+}
+
+func WithWatch(enabled bool) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.Watch = enabled
+	}
+}
+
+func NewFileSystemOptions(funcs ...OptionFunc) *FileSystemOptions {
+	opts := &FileSystemOptions{
+		AtomicWrites: true,
+		Sync:         false,
+		Symlinks:     SymlinkFollowInside,
+		FileMode:     0,
+		DirMode:      0,
+		UID:          -1,
+		GID:          -1,
+		Watch:        true,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
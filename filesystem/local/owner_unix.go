@@ -0,0 +1,18 @@
+//go:build unix
+
+package local
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user and group owning the given file.
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
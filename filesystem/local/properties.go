@@ -0,0 +1,71 @@
+package local
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// GetProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	filename, err := fs.resolveProperties(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := getXattr(filename, propertiesXattr)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := filesystem.UnmarshalProperties(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return props, nil
+}
+
+// PatchProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	filename, err := fs.resolveProperties(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := getXattr(filename, propertiesXattr)
+	if err != nil {
+		return err
+	}
+
+	props, err := filesystem.UnmarshalProperties(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	filesystem.ApplyProppatches(props, patches)
+
+	if len(props) == 0 {
+		return removeXattr(filename, propertiesXattr)
+	}
+
+	data, err = filesystem.MarshalProperties(props)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return setXattr(filename, propertiesXattr, data)
+}
+
+// resolveProperties returns the native path of the file holding the
+// properties of the named resource.
+func (fs *FileSystem) resolveProperties(name string) (string, error) {
+	if fs.join(name) == "" {
+		return "", errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	return fs.resolve(name)
+}
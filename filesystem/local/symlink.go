@@ -0,0 +1,132 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed to resolve a path
+const maxSymlinks = 255
+
+// realPath applies the symbolic links policy to the given native path,
+// returning it with its symbolic links resolved, unless they are denied.
+func (fs *FileSystem) realPath(filename string) (string, error) {
+	switch fs.symlinks {
+	case SymlinkDeny:
+		if err := fs.assertNoSymlink(filename); err != nil {
+			return "", err
+		}
+
+		return filename, nil
+
+	case SymlinkFollow:
+		return resolveSymlinks(filename, 0)
+
+	default:
+		root, err := filepath.EvalSymlinks(fs.dir)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		real, err := resolveSymlinks(filename, 0)
+		if err != nil {
+			return "", err
+		}
+
+		if !isUnder(real, root) {
+			return "", errors.Wrapf(os.ErrPermission, "'%s' resolves outside of the root directory", filename)
+		}
+
+		return real, nil
+	}
+}
+
+// assertNoSymlink returns an error if the given native path traverses a
+// symbolic link below the root directory.
+func (fs *FileSystem) assertNoSymlink(filename string) error {
+	rel, err := filepath.Rel(fs.dir, filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if rel == "." {
+		return nil
+	}
+
+	current := fs.dir
+	for _, segment := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, segment)
+
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			// The remaining segments do not exist either
+			return nil
+		}
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return errors.Wrapf(os.ErrPermission, "'%s' is a symbolic link", current)
+		}
+	}
+
+	return nil
+}
+
+// resolveSymlinks returns the given native path with its symbolic links
+// resolved, its missing trailing segments being kept as is.
+// Dangling symbolic links are resolved to their target.
+func resolveSymlinks(filename string, depth int) (string, error) {
+	if depth > maxSymlinks {
+		return "", &os.PathError{Op: "resolve", Path: filename, Err: syscall.ELOOP}
+	}
+
+	real, err := filepath.EvalSymlinks(filename)
+	if err == nil {
+		return real, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", errors.WithStack(err)
+	}
+
+	if info, lstatErr := os.Lstat(filename); lstatErr == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(filename)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(filename), target)
+		}
+
+		return resolveSymlinks(target, depth+1)
+	}
+
+	parent := filepath.Dir(filename)
+	if parent == filename {
+		return filename, nil
+	}
+
+	realParent, err := resolveSymlinks(parent, depth)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(realParent, filepath.Base(filename)), nil
+}
+
+// isUnder returns true if the native path name is dir or one of its descendants.
+func isUnder(name string, dir string) bool {
+	if name == dir {
+		return true
+	}
+
+	return strings.HasPrefix(name, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,285 @@
+//go:build linux
+
+package local
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// watchMask are the inotify events watched on each directory
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR | unix.IN_EXCL_UNLINK
+
+// watcher reports the changes of a directory tree using inotify, each of its
+// directories being watched.
+type watcher struct {
+	root string
+	file *os.File
+	fd   int
+	emit func(webdav.Event)
+	done chan struct{}
+
+	// Names of the watched directories, by watch descriptor
+	watches map[int]string
+	// Last reported write, consecutive duplicates being omitted
+	lastWrite string
+}
+
+// movedFrom is the first half of a move, waiting for its counterpart.
+type movedFrom struct {
+	name  string
+	isDir bool
+}
+
+func newWatcher(root string, emit func(webdav.Event)) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	w := &watcher{
+		root: root,
+		// Non-blocking descriptors are handled by the runtime poller,
+		// closing the file interrupts the pending reads
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		emit:    emit,
+		done:    make(chan struct{}),
+		watches: map[int]string{},
+	}
+
+	// The root directory itself may be a symbolic link
+	wd, err := unix.InotifyAddWatch(fd, root, watchMask)
+	if err != nil {
+		w.file.Close()
+		return nil, errors.Wrapf(err, "could not watch '%s'", root)
+	}
+
+	w.watches[wd] = "/"
+	w.watchTree("/", false)
+
+	go w.run()
+
+	return w, nil
+}
+
+// Close stops watching the directory tree.
+func (w *watcher) Close() error {
+	err := w.file.Close()
+
+	<-w.done
+
+	return errors.WithStack(err)
+}
+
+func (w *watcher) run() {
+	defer close(w.done)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				slog.Error("could not read directory changes", "dir", w.root, slog.Any("error", errors.WithStack(err)))
+			}
+
+			return
+		}
+
+		w.process(buf[:n])
+	}
+}
+
+// process reports the events read at once. A move whose counterpart is not
+// part of them crosses the boundaries of the directory tree.
+func (w *watcher) process(buf []byte) {
+	moves := map[uint32]movedFrom{}
+	order := []uint32{}
+
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + unix.SizeofInotifyEvent
+		offset = nameStart + int(raw.Len)
+
+		if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+			w.report(webdav.Event{Type: webdav.EventOverflow, Name: "/"})
+			continue
+		}
+
+		if raw.Mask&unix.IN_IGNORED != 0 {
+			delete(w.watches, int(raw.Wd))
+			continue
+		}
+
+		dir, exists := w.watches[int(raw.Wd)]
+		if !exists {
+			continue
+		}
+
+		name := dir
+		if raw.Len > 0 {
+			name = path.Join(dir, strings.TrimRight(string(buf[nameStart:offset]), "\x00"))
+		}
+
+		isDir := raw.Mask&unix.IN_ISDIR != 0
+		isTemp := strings.HasPrefix(path.Base(name), tempPrefix)
+
+		switch {
+		case raw.Mask&unix.IN_MOVED_FROM != 0:
+			moves[raw.Cookie] = movedFrom{name: name, isDir: isDir}
+			order = append(order, raw.Cookie)
+
+		case raw.Mask&unix.IN_MOVED_TO != 0:
+			from, paired := moves[raw.Cookie]
+			delete(moves, raw.Cookie)
+
+			switch {
+			case isTemp:
+				if paired && !strings.HasPrefix(path.Base(from.name), tempPrefix) {
+					w.report(webdav.Event{Type: webdav.EventRemove, Name: from.name})
+				}
+
+			case paired && strings.HasPrefix(path.Base(from.name), tempPrefix):
+				// Temporary file renamed over its target
+				w.report(webdav.Event{Type: webdav.EventWrite, Name: name})
+
+			case paired:
+				w.renameWatches(from.name, name)
+				w.report(webdav.Event{Type: webdav.EventRename, Name: name, OldName: from.name})
+
+			default:
+				w.report(webdav.Event{Type: webdav.EventCreate, Name: name})
+
+				if isDir {
+					w.watchTree(name, true)
+				}
+			}
+
+		case isTemp:
+			// Temporary files are reported once renamed over their target
+
+		case raw.Mask&unix.IN_CREATE != 0:
+			w.report(webdav.Event{Type: webdav.EventCreate, Name: name})
+
+			if isDir {
+				w.watchTree(name, true)
+			}
+
+		case raw.Mask&unix.IN_DELETE != 0:
+			w.report(webdav.Event{Type: webdav.EventRemove, Name: name})
+
+		case raw.Mask&(unix.IN_MODIFY|unix.IN_ATTRIB|unix.IN_CLOSE_WRITE) != 0:
+			if name == w.lastWrite {
+				continue
+			}
+
+			w.report(webdav.Event{Type: webdav.EventWrite, Name: name})
+			w.lastWrite = name
+		}
+	}
+
+	for _, cookie := range order {
+		from, pending := moves[cookie]
+		if !pending || strings.HasPrefix(path.Base(from.name), tempPrefix) {
+			continue
+		}
+
+		// Moved out of the directory tree
+		if from.isDir {
+			w.unwatchTree(from.name)
+		}
+
+		w.report(webdav.Event{Type: webdav.EventRemove, Name: from.name})
+	}
+}
+
+// report emits the given event.
+func (w *watcher) report(e webdav.Event) {
+	if e.Type != webdav.EventWrite {
+		w.lastWrite = ""
+	}
+
+	w.emit(e)
+}
+
+// watchTree watches the named directory and its descendants, the symbolic
+// links being ignored. The entries of the created directories are reported.
+func (w *watcher) watchTree(name string, created bool) {
+	root := filepath.Join(w.root, filepath.FromSlash(name))
+
+	err := filepath.WalkDir(root, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may have been removed in the meantime
+			return nil
+		}
+
+		rel, err := filepath.Rel(w.root, filename)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		entryName := path.Clean("/" + filepath.ToSlash(rel))
+
+		if created && filename != root && !strings.HasPrefix(entry.Name(), tempPrefix) {
+			w.report(webdav.Event{Type: webdav.EventCreate, Name: entryName})
+		}
+
+		if !entry.IsDir() || entryName == "/" {
+			return nil
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, filename, watchMask|unix.IN_DONT_FOLLOW)
+		if err != nil {
+			if errors.Is(err, unix.ENOSPC) {
+				// The remaining directories can not be watched either
+				slog.Warn("inotify watches limit reached, changes made by other processes in the remaining directories will not be reported, see fs.inotify.max_user_watches", "dir", filename, "watches", len(w.watches))
+				return filepath.SkipAll
+			}
+
+			return nil
+		}
+
+		w.watches[wd] = entryName
+
+		return nil
+	})
+	if err != nil {
+		slog.Error("could not watch directory", "dir", root, slog.Any("error", errors.WithStack(err)))
+	}
+}
+
+// unwatchTree stops watching the named directory and its descendants.
+func (w *watcher) unwatchTree(name string) {
+	for wd, dir := range w.watches {
+		if dir != name && !strings.HasPrefix(dir, name+"/") {
+			continue
+		}
+
+		unix.InotifyRmWatch(w.fd, uint32(wd))
+		delete(w.watches, wd)
+	}
+}
+
+// renameWatches updates the names of the watched directories moved from
+// oldName to newName.
+func (w *watcher) renameWatches(oldName string, newName string) {
+	for wd, dir := range w.watches {
+		switch {
+		case dir == oldName:
+			w.watches[wd] = newName
+		case strings.HasPrefix(dir, oldName+"/"):
+			w.watches[wd] = newName + strings.TrimPrefix(dir, oldName)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package local
+
+import (
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// watcher is not supported on this platform.
+type watcher struct{}
+
+func newWatcher(root string, emit func(webdav.Event)) (*watcher, error) {
+	return nil, errors.WithStack(filesystem.ErrNotSupported)
+}
+
+// Close stops watching the directory tree.
+func (w *watcher) Close() error {
+	return nil
+}
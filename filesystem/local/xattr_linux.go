@@ -0,0 +1,61 @@
+//go:build linux
+
+package local
+
+import (
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+func getXattr(filename string, attr string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(filename, attr, nil)
+		if err != nil {
+			return nil, convXattrError(err)
+		}
+
+		data := make([]byte, size)
+
+		size, err = unix.Getxattr(filename, attr, data)
+		if err == nil {
+			return data[:size], nil
+		}
+
+		// The attribute grew between the two calls
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+
+		return nil, convXattrError(err)
+	}
+}
+
+func setXattr(filename string, attr string, data []byte) error {
+	if err := unix.Setxattr(filename, attr, data, 0); err != nil {
+		return convXattrError(err)
+	}
+
+	return nil
+}
+
+func removeXattr(filename string, attr string) error {
+	if err := unix.Removexattr(filename, attr); err != nil && !errors.Is(err, unix.ENODATA) {
+		return convXattrError(err)
+	}
+
+	return nil
+}
+
+func convXattrError(err error) error {
+	switch {
+	case errors.Is(err, unix.ENODATA):
+		return nil
+	case errors.Is(err, unix.ENOTSUP), errors.Is(err, unix.EPERM):
+		// Extended attributes are not supported by the underlying filesystem
+		// or can not be set on this kind of file
+		return errors.WithStack(filesystem.ErrNotSupported)
+	default:
+		return errors.WithStack(err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package local
+
+import (
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+func getXattr(filename string, attr string) ([]byte, error) {
+	return nil, errors.WithStack(filesystem.ErrNotSupported)
+}
+
+func setXattr(filename string, attr string, data []byte) error {
+	return errors.WithStack(filesystem.ErrNotSupported)
+}
+
+func removeXattr(filename string, attr string) error {
+	return errors.WithStack(filesystem.ErrNotSupported)
+}
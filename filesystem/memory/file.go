@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// File is an open file or directory of the in-memory filesystem.
+type File struct {
+	fs     *FileSystem
+	name   string
+	node   *node
+	flag   int
+	offset int64
+	closed bool
+
+	// Remaining directory entries, loaded on the first Readdir call
+	entries []os.FileInfo
+	loaded  bool
+}
+
+// Close implements webdav.File.
+func (f *File) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	f.closed = true
+
+	return nil
+}
+
+// Read implements webdav.File.
+func (f *File) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.node.isDir() {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EISDIR}
+	}
+
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EBADF}
+	}
+
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+// Readdir implements webdav.File.
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+
+	if !f.node.isDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+
+	if !f.loaded {
+		f.entries = f.readEntries()
+		f.loaded = true
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+
+	return entries, nil
+}
+
+func (f *File) readEntries() []os.FileInfo {
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	entries := make([]os.FileInfo, 0, len(f.node.children))
+	for name, child := range f.node.children {
+		entries = append(entries, newFileInfo(path.Join(f.name, name), child))
+	}
+
+	slices.SortFunc(entries, func(a, b os.FileInfo) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries
+}
+
+// Seek implements webdav.File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	f.fs.mu.RLock()
+	size := int64(len(f.node.data))
+	f.fs.mu.RUnlock()
+
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = size + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+
+	if abs < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+
+	f.offset = abs
+
+	return abs, nil
+}
+
+// Stat implements webdav.File.
+func (f *File) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	return newFileInfo(f.name, f.node), nil
+}
+
+// Write implements webdav.File.
+func (f *File) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+	}
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+
+	end := f.offset + int64(len(p))
+
+	if err := f.fs.reserveSpace(f.node, f.name, end-int64(len(f.node.data))); err != nil {
+		return 0, err
+	}
+
+	if end > int64(len(f.node.data)) {
+		if end > int64(cap(f.node.data)) {
+			data := make([]byte, end, max(end, 2*int64(cap(f.node.data))))
+			copy(data, f.node.data)
+			f.node.data = data
+		} else {
+			f.node.data = f.node.data[:end]
+		}
+	}
+
+	n := copy(f.node.data[f.offset:], p)
+	f.offset += int64(n)
+	f.node.modTime = f.fs.clock()
+
+	return n, nil
+}
+
+var _ webdav.File = &File{}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return path.Base(fi.name) }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any           { return nil }
+
+var _ os.FileInfo = &fileInfo{}
+
+func newFileInfo(name string, n *node) *fileInfo {
+	return &fileInfo{
+		name:    name,
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
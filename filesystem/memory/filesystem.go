@@ -0,0 +1,368 @@
+package memory
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// writeFlags are the open flags implying a modification of the file
+const writeFlags = os.O_WRONLY | os.O_APPEND | os.O_RDWR | os.O_TRUNC | os.O_CREATE
+
+// FileSystem is a [webdav.FileSystem] holding its files and directories in memory.
+type FileSystem struct {
+	mu   sync.RWMutex
+	root *node
+	// Total size of the files content
+	used int64
+	// Number of files and directories, the root excluded
+	entries int
+
+	capacity   int64
+	maxEntries int
+	clock      func() time.Time
+}
+
+// node is a file or a directory of the filesystem.
+type node struct {
+	mode    os.FileMode
+	modTime time.Time
+	data    []byte
+	// Children of the directory, nil for files
+	children map[string]*node
+	props    map[xml.Name]webdav.Property
+	// Whether the node has been removed from the filesystem, while still
+	// being referenced by open files
+	detached bool
+}
+
+func (n *node) isDir() bool {
+	return n.mode.IsDir()
+}
+
+// Mkdir implements webdav.FileSystem.
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = clean(name)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent, base, err := fs.lookupParent("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := parent.children[base]; exists {
+		return os.ErrExist
+	}
+
+	if err := fs.reserveEntry("mkdir", name); err != nil {
+		return err
+	}
+
+	now := fs.clock()
+
+	parent.children[base] = &node{
+		mode:     os.ModeDir | perm.Perm(),
+		modTime:  now,
+		children: map[string]*node{},
+	}
+	parent.modTime = now
+
+	return nil
+}
+
+// OpenFile implements webdav.FileSystem.
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = clean(name)
+
+	if flag&writeFlags == 0 {
+		fs.mu.RLock()
+		defer fs.mu.RUnlock()
+	} else {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+	}
+
+	n := fs.lookup(name)
+
+	switch {
+	case n == nil:
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+
+		parent, base, err := fs.lookupParent("open", name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fs.reserveEntry("open", name); err != nil {
+			return nil, err
+		}
+
+		now := fs.clock()
+
+		n = &node{
+			mode:    perm.Perm(),
+			modTime: now,
+		}
+
+		parent.children[base] = n
+		parent.modTime = now
+
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, os.ErrExist
+
+	case n.isDir():
+		// PROPPATCH requests open their target read-write, the directory
+		// is opened read-only instead
+		if flag&writeFlags != 0 && flag != os.O_RDWR {
+			return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+		}
+
+		flag = os.O_RDONLY
+
+	case flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		fs.used -= int64(len(n.data))
+		n.data = nil
+		n.modTime = fs.clock()
+	}
+
+	file := &File{
+		fs:   fs,
+		name: name,
+		node: n,
+		flag: flag,
+	}
+
+	return file, nil
+}
+
+// RemoveAll implements webdav.FileSystem.
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	name = clean(name)
+
+	if name == "/" {
+		return os.ErrPermission
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent, base, err := fs.lookupParent("remove", name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	n, exists := parent.children[base]
+	if !exists {
+		return nil
+	}
+
+	delete(parent.children, base)
+	parent.modTime = fs.clock()
+
+	fs.detach(n)
+
+	return nil
+}
+
+// Rename implements webdav.FileSystem.
+// An existing destination is replaced, as with [os.Rename].
+func (fs *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	oldName = clean(oldName)
+	newName = clean(newName)
+
+	if oldName == "/" || newName == "/" {
+		return os.ErrPermission
+	}
+
+	if oldName == newName {
+		return nil
+	}
+
+	if strings.HasPrefix(newName, oldName+"/") {
+		// A directory can not be moved into itself
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: syscall.EINVAL}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	oldParent, oldBase, err := fs.lookupParent("rename", oldName)
+	if err != nil {
+		return err
+	}
+
+	n, exists := oldParent.children[oldBase]
+	if !exists {
+		return os.ErrNotExist
+	}
+
+	newParent, newBase, err := fs.lookupParent("rename", newName)
+	if err != nil {
+		return err
+	}
+
+	if replaced, exists := newParent.children[newBase]; exists {
+		switch {
+		case n.isDir() && !replaced.isDir():
+			return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: syscall.ENOTDIR}
+		case !n.isDir() && replaced.isDir():
+			return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: syscall.EISDIR}
+		case replaced.isDir() && len(replaced.children) > 0:
+			return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: syscall.ENOTEMPTY}
+		}
+
+		fs.detach(replaced)
+	}
+
+	now := fs.clock()
+
+	delete(oldParent.children, oldBase)
+	oldParent.modTime = now
+
+	newParent.children[newBase] = n
+	newParent.modTime = now
+
+	return nil
+}
+
+// Stat implements webdav.FileSystem.
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = clean(name)
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	n := fs.lookup(name)
+	if n == nil {
+		return nil, os.ErrNotExist
+	}
+
+	return newFileInfo(name, n), nil
+}
+
+// Usage returns the total size of the files content, in bytes, and the
+// number of files and directories.
+func (fs *FileSystem) Usage() (int64, int) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return fs.used, fs.entries
+}
+
+// lookup returns the node of the given cleaned name, or nil if it does not exist.
+func (fs *FileSystem) lookup(name string) *node {
+	n := fs.root
+
+	if name == "/" {
+		return n
+	}
+
+	for _, segment := range strings.Split(name[1:], "/") {
+		if n.children == nil {
+			return nil
+		}
+
+		n = n.children[segment]
+		if n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
+
+// lookupParent returns the directory holding the given cleaned name and the base name of the latter.
+func (fs *FileSystem) lookupParent(op string, name string) (*node, string, error) {
+	parent := fs.lookup(path.Dir(name))
+	if parent == nil {
+		return nil, "", os.ErrNotExist
+	}
+
+	if !parent.isDir() {
+		return nil, "", &os.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+
+	return parent, path.Base(name), nil
+}
+
+// reserveEntry checks that a new file or directory can be created and
+// accounts for it.
+func (fs *FileSystem) reserveEntry(op string, name string) error {
+	if fs.maxEntries > 0 && fs.entries >= fs.maxEntries {
+		return &os.PathError{Op: op, Path: name, Err: syscall.ENOSPC}
+	}
+
+	fs.entries++
+
+	return nil
+}
+
+// reserveSpace checks that the content of a file can grow by the given
+// size and accounts for it. Detached nodes are not accounted for.
+func (fs *FileSystem) reserveSpace(n *node, name string, growth int64) error {
+	if n.detached || growth <= 0 {
+		return nil
+	}
+
+	if fs.capacity > 0 && fs.used+growth > fs.capacity {
+		return &os.PathError{Op: "write", Path: name, Err: syscall.ENOSPC}
+	}
+
+	fs.used += growth
+
+	return nil
+}
+
+// detach releases the space used by the given node and its descendants,
+// removed from the filesystem.
+func (fs *FileSystem) detach(n *node) {
+	n.detached = true
+
+	fs.entries--
+	fs.used -= int64(len(n.data))
+
+	for _, child := range n.children {
+		fs.detach(child)
+	}
+}
+
+func NewFileSystem(funcs ...OptionFunc) *FileSystem {
+	opts := NewFileSystemOptions(funcs...)
+
+	return &FileSystem{
+		root:       newRoot(opts.Clock()),
+		capacity:   opts.Capacity,
+		maxEntries: opts.MaxEntries,
+		clock:      opts.Clock,
+	}
+}
+
+var _ webdav.FileSystem = &FileSystem{}
+
+func newRoot(modTime time.Time) *node {
+	return &node{
+		mode:     os.ModeDir | 0755,
+		modTime:  modTime,
+		children: map[string]*node{},
+	}
+}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
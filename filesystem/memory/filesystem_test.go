@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/filesystem/bench"
+	"github.com/bornholm/go-webdav/filesystem/testsuite"
+	"github.com/bornholm/go-webdav/litmus"
+	"github.com/pkg/errors"
+)
+
+func TestFileSystem(t *testing.T) {
+	fs := NewFileSystem()
+	testsuite.TestFileSystem(t, fs)
+}
+
+func TestLitmus(t *testing.T) {
+	fs := NewFileSystem()
+	litmus.RunTestSuite(t, fs)
+}
+
+func BenchmarkFileSystem(b *testing.B) {
+	fs := NewFileSystem()
+	bench.RunTestSuite(b, fs)
+}
+
+func TestFileSystemLimits(t *testing.T) {
+	ctx := context.Background()
+
+	fs := NewFileSystem(WithCapacity(10), WithMaxEntries(2))
+
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "0123456789")
+
+	file, err := fs.OpenFile(ctx, "/file.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := io.WriteString(file, "a"); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("expected error '%v', got '%v'", syscall.ENOSPC, err)
+	}
+
+	file.Close()
+
+	if err := fs.Mkdir(ctx, "/dir", 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := fs.Mkdir(ctx, "/other", 0755); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("expected error '%v', got '%v'", syscall.ENOSPC, err)
+	}
+
+	// Removed resources release their space
+	if err := fs.RemoveAll(ctx, "/file.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if used, entries := fs.Usage(); used != 0 || entries != 1 {
+		t.Errorf("usage: expected (0, 1), got (%d, %d)", used, entries)
+	}
+
+	testsuite.WriteFileContent(t, ctx, fs, "/dir/file.txt", "0123456789")
+}
+
+func TestFileSystemSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	fs := NewFileSystem()
+
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "before")
+
+	snapshot := fs.Snapshot()
+
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "after")
+	testsuite.WriteFileContent(t, ctx, fs, "/other.txt", "other")
+
+	fs.Restore(snapshot)
+
+	if e, g := "before", testsuite.ReadFileContent(t, ctx, fs, "/file.txt"); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	if _, err := fs.Stat(ctx, "/other.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected error '%v', got '%v'", os.ErrNotExist, err)
+	}
+
+	// The snapshot is not modified by the restored filesystem
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "modified")
+
+	fs.Restore(snapshot)
+
+	if e, g := "before", testsuite.ReadFileContent(t, ctx, fs, "/file.txt"); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	if used, entries := fs.Usage(); used != int64(len("before")) || entries != 1 {
+		t.Errorf("usage: expected (%d, 1), got (%d, %d)", len("before"), used, entries)
+	}
+}
+
+func TestFileSystemClock(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	fs := NewFileSystem(WithClock(func() time.Time {
+		return now
+	}))
+
+	if err := fs.Mkdir(ctx, "/dir", 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	now = now.Add(time.Hour)
+
+	testsuite.WriteFileContent(t, ctx, fs, "/dir/file.txt", "hello")
+
+	for _, name := range []string{"/dir", "/dir/file.txt"} {
+		info, err := fs.Stat(ctx, name)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if !info.ModTime().Equal(now) {
+			t.Errorf("'%s' modification time: expected %v, got %v", name, now, info.ModTime())
+		}
+	}
+}
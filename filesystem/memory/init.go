@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+const Type filesystem.Type = "memory"
+
+func init() {
+	filesystem.Register(Type, CreateFileSystemFromOptions)
+}
+
+type Options struct {
+	// Maximum total size of the files content, in bytes, unlimited if zero
+	Capacity int64 `mapstructure:"capacity" validate:"gte=0"`
+	// Maximum number of files and directories, unlimited if zero
+	MaxEntries int `mapstructure:"maxEntries" validate:"gte=0"`
+}
+
+func CreateFileSystemFromOptions(options any) (webdav.FileSystem, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' filesystem options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate memory filesystem options")
+	}
+
+	fs := NewFileSystem(
+		WithCapacity(opts.Capacity),
+		WithMaxEntries(opts.MaxEntries),
+	)
+
+	return fs, nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import "time"
+
+type FileSystemOptions struct {
+	// Capacity is the maximum total size of the files content, in bytes,
+	// unlimited if zero
+	Capacity int64
+	// MaxEntries is the maximum number of files and directories, unlimited if zero
+	MaxEntries int
+	// Clock returns the modification time of the created or modified resources
+	Clock func() time.Time
+}
+
+type OptionFunc func(opts *FileSystemOptions)
+
+func WithCapacity(capacity int64) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.Capacity = capacity
+	}
+}
+
+func WithMaxEntries(maxEntries int) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.MaxEntries = maxEntries
+	}
+}
+
+func WithClock(clock func() time.Time) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.Clock = clock
+	}
+}
+
+func NewFileSystemOptions(funcs ...OptionFunc) *FileSystemOptions {
+	opts := &FileSystemOptions{
+		Capacity:   0,
+		MaxEntries: 0,
+		Clock:      time.Now,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
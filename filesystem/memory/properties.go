@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"encoding/xml"
+	"maps"
+	"os"
+
+	"github.com/bornholm/go-webdav/filesystem"
+	"golang.org/x/net/webdav"
+)
+
+// GetProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	name = clean(name)
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	n := fs.lookup(name)
+	if n == nil {
+		return nil, os.ErrNotExist
+	}
+
+	props := maps.Clone(n.props)
+	if props == nil {
+		props = make(map[xml.Name]webdav.Property)
+	}
+
+	return props, nil
+}
+
+// PatchProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	name = clean(name)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	n := fs.lookup(name)
+	if n == nil {
+		return os.ErrNotExist
+	}
+
+	if n.props == nil {
+		n.props = make(map[xml.Name]webdav.Property)
+	}
+
+	filesystem.ApplyProppatches(n.props, patches)
+
+	return nil
+}
+
+var _ filesystem.PropertiesFileSystem = &FileSystem{}
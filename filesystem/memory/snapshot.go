@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+)
+
+// Snapshot is a copy of the content of a [FileSystem] at a given time,
+// unaffected by its later modifications.
+type Snapshot struct {
+	root    *node
+	used    int64
+	entries int
+}
+
+// Snapshot returns a copy of the files, directories and dead properties of the filesystem.
+func (fs *FileSystem) Snapshot() *Snapshot {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return &Snapshot{
+		root:    copyNode(fs.root),
+		used:    fs.used,
+		entries: fs.entries,
+	}
+}
+
+// Restore replaces the content of the filesystem with the given snapshot,
+// which can be restored again. Files opened before are detached from the filesystem.
+func (fs *FileSystem) Restore(snapshot *Snapshot) {
+	root := copyNode(snapshot.root)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.detach(fs.root)
+
+	fs.root = root
+	fs.used = snapshot.used
+	fs.entries = snapshot.entries
+}
+
+// copyNode returns a deep copy of the given node and its descendants.
+func copyNode(n *node) *node {
+	copied := &node{
+		mode:    n.mode,
+		modTime: n.modTime,
+		data:    slices.Clone(n.data),
+		props:   maps.Clone(n.props),
+	}
+
+	if n.children != nil {
+		copied.children = make(map[string]*node, len(n.children))
+		for name, child := range n.children {
+			copied.children[name] = copyNode(child)
+		}
+	}
+
+	return copied
+}
@@ -0,0 +1,39 @@
+package mount
+
+import (
+	"io"
+	"path"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// Subscribe implements [webdav.EventSource].
+// The changes of the mounted filesystems implementing [webdav.EventSource]
+// are reported with their names in the mount table, the ones shadowed by
+// another mount being omitted.
+func (fs *FileSystem) Subscribe(fn func(webdav.Event)) func() {
+	subscriptions := make([]io.Closer, 0, len(fs.mounts))
+
+	for _, m := range fs.mounts {
+		subscriptions = append(subscriptions, webdav.Subscribe(m.FileSystem, func(e webdav.Event) {
+			e.Name = path.Join(m.Path, e.Name)
+			if e.OldName != "" {
+				e.OldName = path.Join(m.Path, e.OldName)
+			}
+
+			if mount, _, ok := fs.resolve(e.Name); !ok || mount.Path != m.Path {
+				return
+			}
+
+			fn(e)
+		}))
+	}
+
+	return func() {
+		for _, s := range subscriptions {
+			s.Close()
+		}
+	}
+}
+
+var _ webdav.EventSource = &FileSystem{}
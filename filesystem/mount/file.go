@@ -0,0 +1,149 @@
+package mount
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// File is a [webdav.File] whose directory listings contain the mount points
+// of its descendants. Files without backend are virtual read-only directories.
+type File struct {
+	ctx  context.Context
+	name string
+	file webdav.File
+	fs   *FileSystem
+
+	// Remaining directory entries, loaded on the first Readdir call
+	entries []fs.FileInfo
+	loaded  bool
+}
+
+// Close implements webdav.File.
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+
+	return f.file.Close()
+}
+
+// Read implements webdav.File.
+func (f *File) Read(p []byte) (n int, err error) {
+	if f.file == nil {
+		return 0, errors.WithStack(os.ErrInvalid)
+	}
+
+	return f.file.Read(p)
+}
+
+// Readdir implements webdav.File.
+// Entries of the backend shadowed by mount points are replaced by them.
+func (f *File) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.loaded {
+		entries, err := f.readEntries()
+		if err != nil {
+			return nil, err
+		}
+
+		f.entries = entries
+		f.loaded = true
+	}
+
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+
+	return entries, nil
+}
+
+func (f *File) readEntries() ([]fs.FileInfo, error) {
+	var entries []fs.FileInfo
+
+	if f.file != nil {
+		children, err := f.file.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = children
+	}
+
+	mounted := f.fs.mountedChildren(f.name)
+
+	entries = slices.DeleteFunc(entries, func(info fs.FileInfo) bool {
+		return slices.Contains(mounted, info.Name())
+	})
+
+	for _, child := range mounted {
+		info, err := f.fs.Stat(f.ctx, path.Join(f.name, child))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		entries = append(entries, info)
+	}
+
+	return entries, nil
+}
+
+// Seek implements webdav.File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.file == nil {
+		return 0, nil
+	}
+
+	return f.file.Seek(offset, whence)
+}
+
+// Stat implements webdav.File.
+func (f *File) Stat() (fs.FileInfo, error) {
+	return f.fs.Stat(f.ctx, f.name)
+}
+
+// Write implements webdav.File.
+func (f *File) Write(p []byte) (n int, err error) {
+	if f.file == nil {
+		return 0, errors.WithStack(os.ErrPermission)
+	}
+
+	return f.file.Write(p)
+}
+
+// DeadProps implements webdav.DeadPropsHolder.
+func (f *File) DeadProps() (map[xml.Name]webdav.Property, error) {
+	holder, ok := f.file.(webdav.DeadPropsHolder)
+	if !ok {
+		return nil, nil
+	}
+
+	return holder.DeadProps()
+}
+
+// Patch implements webdav.DeadPropsHolder.
+func (f *File) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return gowebdav.PatchDeadProps(f.file, patches)
+}
+
+var (
+	_ webdav.File            = &File{}
+	_ webdav.DeadPropsHolder = &File{}
+)
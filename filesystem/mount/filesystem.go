@@ -0,0 +1,409 @@
+package mount
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// writeFlags are the open flags implying a modification of the file
+const writeFlags = os.O_WRONLY | os.O_APPEND | os.O_RDWR | os.O_TRUNC | os.O_CREATE
+
+// Mount is a filesystem served under a path prefix.
+type Mount struct {
+	Path       string
+	FileSystem webdav.FileSystem
+}
+
+// FileSystem is a mount table, routing each operation to the filesystem
+// mounted on the longest prefix of the targeted path.
+// Parent directories of the mount points missing from the underlying
+// filesystems are exposed as read-only directories listing them.
+type FileSystem struct {
+	// Mounts sorted by decreasing path length
+	mounts           []Mount
+	copyAcrossMounts bool
+	createdAt        time.Time
+}
+
+// Mkdir implements webdav.FileSystem.
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = clean(name)
+
+	if fs.isMountPoint(name) {
+		return os.ErrExist
+	}
+
+	mount, rel, ok := fs.resolve(name)
+	if !ok {
+		if fs.hasMountsUnder(name) {
+			return os.ErrExist
+		}
+
+		// Virtual directories can only hold mount points
+		return os.ErrPermission
+	}
+
+	if err := mount.FileSystem.Mkdir(ctx, rel, perm); err != nil {
+		if errors.Is(err, os.ErrNotExist) && fs.hasMountsUnder(name) {
+			return os.ErrExist
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// OpenFile implements webdav.FileSystem.
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = clean(name)
+
+	mount, rel, ok := fs.resolve(name)
+	if ok {
+		file, err := mount.FileSystem.OpenFile(ctx, rel, flag, perm)
+		if err == nil {
+			if rel != "/" && !fs.hasMountsUnder(name) {
+				return file, nil
+			}
+
+			return &File{ctx: ctx, name: name, file: file, fs: fs}, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) || !fs.hasMountsUnder(name) {
+			return nil, err
+		}
+	} else if !fs.hasMountsUnder(name) {
+		return nil, os.ErrNotExist
+	}
+
+	if flag&writeFlags != 0 {
+		return nil, os.ErrPermission
+	}
+
+	return &File{ctx: ctx, name: name, fs: fs}, nil
+}
+
+// RemoveAll implements webdav.FileSystem.
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	name = clean(name)
+
+	if fs.isMountPoint(name) || fs.hasMountsUnder(name) {
+		return os.ErrPermission
+	}
+
+	mount, rel, ok := fs.resolve(name)
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	return mount.FileSystem.RemoveAll(ctx, rel)
+}
+
+// Rename implements webdav.FileSystem.
+// Resources are moved across mounts by copying them when enabled, an error
+// wrapping [syscall.EXDEV] being returned otherwise.
+func (fs *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	oldName = clean(oldName)
+	newName = clean(newName)
+
+	oldMount, oldRel, newMount, newRel, err := fs.resolveRename(oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	if oldMount.Path == newMount.Path {
+		return oldMount.FileSystem.Rename(ctx, oldRel, newRel)
+	}
+
+	if !fs.copyAcrossMounts {
+		return crossDeviceError(oldName, newName)
+	}
+
+	if err := filesystem.Copy(ctx, oldMount.FileSystem, oldRel, newMount.FileSystem, newRel); err != nil {
+		// Do not leave a partial copy behind
+		if _, statErr := newMount.FileSystem.Stat(ctx, newRel); statErr == nil {
+			_ = newMount.FileSystem.RemoveAll(ctx, newRel)
+		}
+
+		return errors.WithStack(err)
+	}
+
+	if err := oldMount.FileSystem.RemoveAll(ctx, oldRel); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// CheckRename implements [webdav.RenameChecker].
+func (fs *FileSystem) CheckRename(ctx context.Context, oldName string, newName string) error {
+	oldName = clean(oldName)
+	newName = clean(newName)
+
+	oldMount, _, newMount, _, err := fs.resolveRename(oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	if oldMount.Path != newMount.Path && !fs.copyAcrossMounts {
+		return crossDeviceError(oldName, newName)
+	}
+
+	return nil
+}
+
+// CopyFile implements [webdav.FileCopier].
+// Files can only be copied this way within a mount.
+func (fs *FileSystem) CopyFile(ctx context.Context, src string, dst string) error {
+	srcMount, srcRel, ok := fs.resolve(clean(src))
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	dstMount, dstRel, ok := fs.resolve(clean(dst))
+	if !ok || srcMount.Path != dstMount.Path {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	copier, ok := srcMount.FileSystem.(gowebdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	return copier.CopyFile(ctx, srcRel, dstRel)
+}
+
+func (fs *FileSystem) resolveRename(oldName string, newName string) (*Mount, string, *Mount, string, error) {
+	for _, name := range []string{oldName, newName} {
+		if fs.isMountPoint(name) || fs.hasMountsUnder(name) {
+			return nil, "", nil, "", os.ErrPermission
+		}
+	}
+
+	oldMount, oldRel, ok := fs.resolve(oldName)
+	if !ok {
+		return nil, "", nil, "", os.ErrNotExist
+	}
+
+	newMount, newRel, ok := fs.resolve(newName)
+	if !ok {
+		// Virtual directories can only hold mount points
+		return nil, "", nil, "", os.ErrPermission
+	}
+
+	return oldMount, oldRel, newMount, newRel, nil
+}
+
+// Stat implements webdav.FileSystem.
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = clean(name)
+
+	mount, rel, ok := fs.resolve(name)
+	if ok {
+		info, err := mount.FileSystem.Stat(ctx, rel)
+		if err == nil {
+			if rel == "/" {
+				// Mount points are named after their path
+				return filesystem.WithName(info, path.Base(name)), nil
+			}
+
+			return info, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) || !fs.hasMountsUnder(name) {
+			return nil, err
+		}
+	} else if !fs.hasMountsUnder(name) {
+		return nil, os.ErrNotExist
+	}
+
+	return filesystem.NewDirInfo(path.Base(name), fs.createdAt), nil
+}
+
+// GetProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	backend, rel, err := fs.resolveProperties(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.GetProperties(ctx, rel)
+}
+
+// PatchProperties implements [filesystem.PropertiesFileSystem].
+func (fs *FileSystem) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	backend, rel, err := fs.resolveProperties(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return backend.PatchProperties(ctx, rel, patches)
+}
+
+// resolveProperties returns the backend holding the properties of the named
+// resource, or [filesystem.ErrNotSupported] if it is not able to.
+func (fs *FileSystem) resolveProperties(ctx context.Context, name string) (filesystem.PropertiesFileSystem, string, error) {
+	mount, rel, ok := fs.resolve(clean(name))
+	if !ok {
+		return nil, "", errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	backend, ok := mount.FileSystem.(filesystem.PropertiesFileSystem)
+	if !ok {
+		return nil, "", errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	// Virtual directories do not hold properties
+	if _, err := mount.FileSystem.Stat(ctx, rel); errors.Is(err, os.ErrNotExist) {
+		return nil, "", errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	return backend, rel, nil
+}
+
+// Close implements [io.Closer].
+// Each mounted filesystem implementing [io.Closer] is closed.
+func (fs *FileSystem) Close() error {
+	var err error
+
+	for _, m := range fs.mounts {
+		closer, ok := m.FileSystem.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = errors.Wrapf(closeErr, "could not close filesystem mounted on '%s'", m.Path)
+		}
+	}
+
+	return err
+}
+
+// resolve returns the mount holding the given cleaned name and the name
+// relative to it, or false if none does.
+func (fs *FileSystem) resolve(name string) (*Mount, string, bool) {
+	for i, m := range fs.mounts {
+		if !isUnder(name, m.Path) {
+			continue
+		}
+
+		return &fs.mounts[i], clean(strings.TrimPrefix(name, m.Path)), true
+	}
+
+	return nil, "", false
+}
+
+// isMountPoint returns true if a filesystem is mounted on the given cleaned name.
+func (fs *FileSystem) isMountPoint(name string) bool {
+	for _, m := range fs.mounts {
+		if m.Path == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasMountsUnder returns true if a filesystem is mounted on a descendant of
+// the given cleaned name.
+func (fs *FileSystem) hasMountsUnder(name string) bool {
+	for _, m := range fs.mounts {
+		if m.Path != name && isUnder(m.Path, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mountedChildren returns the names of the direct children of the given
+// cleaned name leading to mount points.
+func (fs *FileSystem) mountedChildren(name string) []string {
+	children := make([]string, 0)
+
+	for _, m := range fs.mounts {
+		if m.Path == name || !isUnder(m.Path, name) {
+			continue
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(m.Path, name), "/")
+		child, _, _ := strings.Cut(rel, "/")
+
+		if !slices.Contains(children, child) {
+			children = append(children, child)
+		}
+	}
+
+	slices.Sort(children)
+
+	return children
+}
+
+// NewFileSystem creates a mount table serving the given filesystems.
+// A filesystem mounted on "/" serves the paths not matching any other mount.
+func NewFileSystem(mounts []Mount, funcs ...OptionFunc) (*FileSystem, error) {
+	opts := NewOptions(funcs...)
+
+	sorted := make([]Mount, 0, len(mounts))
+
+	for _, m := range mounts {
+		if m.FileSystem == nil {
+			return nil, errors.Errorf("no filesystem mounted on '%s'", m.Path)
+		}
+
+		m.Path = clean(m.Path)
+
+		if slices.ContainsFunc(sorted, func(other Mount) bool { return other.Path == m.Path }) {
+			return nil, errors.Errorf("multiple filesystems mounted on '%s'", m.Path)
+		}
+
+		sorted = append(sorted, m)
+	}
+
+	slices.SortStableFunc(sorted, func(a, b Mount) int {
+		return len(b.Path) - len(a.Path)
+	})
+
+	return &FileSystem{
+		mounts:           sorted,
+		copyAcrossMounts: opts.CopyAcrossMounts,
+		createdAt:        time.Now(),
+	}, nil
+}
+
+var (
+	_ webdav.FileSystem               = &FileSystem{}
+	_ filesystem.PropertiesFileSystem = &FileSystem{}
+	_ io.Closer                       = &FileSystem{}
+	_ gowebdav.FileCopier             = &FileSystem{}
+)
+
+func crossDeviceError(oldName string, newName string) error {
+	return errors.WithStack(&os.LinkError{Op: "rename", Old: oldName, New: newName, Err: syscall.EXDEV})
+}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+// isUnder returns true if name is dir or one of its descendants.
+func isUnder(name string, dir string) bool {
+	if dir == "/" || name == dir {
+		return true
+	}
+
+	return strings.HasPrefix(name, dir+"/")
+}
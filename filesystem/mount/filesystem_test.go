@@ -0,0 +1,227 @@
+package mount
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem/local"
+	"github.com/bornholm/go-webdav/filesystem/testsuite"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestFileSystem(t *testing.T) {
+	fs, err := NewFileSystem([]Mount{
+		{Path: "/", FileSystem: local.NewFileSystem(t.TempDir())},
+		{Path: "/mnt/data", FileSystem: local.NewFileSystem(t.TempDir())},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testsuite.TestFileSystem(t, fs)
+}
+
+func TestFileSystemMounts(t *testing.T) {
+	ctx := context.Background()
+
+	homeDir := t.TempDir()
+	archiveDir := t.TempDir()
+
+	mounts := []Mount{
+		{Path: "/home", FileSystem: local.NewFileSystem(homeDir)},
+		{Path: "/archive/2024", FileSystem: local.NewFileSystem(archiveDir)},
+	}
+
+	fs, err := NewFileSystem(mounts)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Parent directories list the mount points
+	if e, g := []string{"archive", "home"}, testsuite.ReaddirNames(t, ctx, fs, "/"); !slices.Equal(e, g) {
+		t.Errorf("readdir '/': expected %v, got %v", e, g)
+	}
+
+	if e, g := []string{"2024"}, testsuite.ReaddirNames(t, ctx, fs, "/archive"); !slices.Equal(e, g) {
+		t.Errorf("readdir '/archive': expected %v, got %v", e, g)
+	}
+
+	info, err := fs.Stat(ctx, "/archive/2024")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "2024", info.Name(); e != g {
+		t.Errorf("info.Name(): expected %v, got %v", e, g)
+	}
+
+	// Operations are routed to the mounted filesystems
+	testsuite.WriteFileContent(t, ctx, fs, "/home/file.txt", "hello")
+
+	if _, err := os.Stat(filepath.Join(homeDir, "file.txt")); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	// Virtual directories and mount points can not be modified
+	if err := fs.Mkdir(ctx, "/other", 0755); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("mkdir '/other': expected error '%v', got '%v'", os.ErrPermission, err)
+	}
+
+	if err := fs.Mkdir(ctx, "/archive", 0755); !errors.Is(err, os.ErrExist) {
+		t.Errorf("mkdir '/archive': expected error '%v', got '%v'", os.ErrExist, err)
+	}
+
+	if err := fs.RemoveAll(ctx, "/home"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("remove '/home': expected error '%v', got '%v'", os.ErrPermission, err)
+	}
+
+	if _, err := fs.OpenFile(ctx, "/archive/file.txt", os.O_WRONLY|os.O_CREATE, 0644); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("create '/archive/file.txt': expected error '%v', got '%v'", os.ErrNotExist, err)
+	}
+
+	// Resources can not be renamed across mounts
+	if err := fs.CheckRename(ctx, "/home/file.txt", "/archive/2024/file.txt"); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("expected error '%v', got '%v'", syscall.EXDEV, err)
+	}
+
+	if err := fs.Rename(ctx, "/home/file.txt", "/archive/2024/file.txt"); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("expected error '%v', got '%v'", syscall.EXDEV, err)
+	}
+
+	if err := fs.CheckRename(ctx, "/home/file.txt", "/home/renamed.txt"); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	// Unless copies are allowed
+	fs, err = NewFileSystem(mounts, WithCopyAcrossMounts(true))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := fs.Mkdir(ctx, "/home/dir", 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	testsuite.WriteFileContent(t, ctx, fs, "/home/dir/file.txt", "hello")
+
+	if err := fs.CheckRename(ctx, "/home/dir", "/archive/2024/dir"); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	if err := fs.Rename(ctx, "/home/dir", "/archive/2024/dir"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := os.Stat(filepath.Join(homeDir, "dir")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected error '%v', got '%v'", os.ErrNotExist, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(archiveDir, "dir", "file.txt"))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "hello", string(data); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+}
+
+func TestFileSystemShadowing(t *testing.T) {
+	rootDir := t.TempDir()
+
+	// The mount point shadows the directory of the root filesystem
+	if err := os.Mkdir(filepath.Join(rootDir, "data"), 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := os.WriteFile(filepath.Join(rootDir, "file.txt"), nil, 0644); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	fs, err := NewFileSystem([]Mount{
+		{Path: "/", FileSystem: local.NewFileSystem(rootDir)},
+		{Path: "/data", FileSystem: local.NewFileSystem(t.TempDir())},
+		{Path: "/mnt/other", FileSystem: local.NewFileSystem(t.TempDir())},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := []string{"data", "file.txt", "mnt"}, testsuite.ReaddirNames(t, context.Background(), fs, "/"); !slices.Equal(e, g) {
+		t.Errorf("readdir '/': expected %v, got %v", e, g)
+	}
+
+	if _, err := NewFileSystem([]Mount{
+		{Path: "/data", FileSystem: local.NewFileSystem(rootDir)},
+		{Path: "/data/", FileSystem: local.NewFileSystem(rootDir)},
+	}); err == nil {
+		t.Errorf("expected an error for duplicated mount points")
+	}
+}
+
+func TestFileSystemEvents(t *testing.T) {
+	root := &eventSource{FileSystem: webdav.NewMemFS()}
+	data := &eventSource{FileSystem: webdav.NewMemFS()}
+
+	fs, err := NewFileSystem([]Mount{
+		{Path: "/", FileSystem: root},
+		{Path: "/data", FileSystem: data},
+		{Path: "/mnt/other", FileSystem: webdav.NewMemFS()},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	var events []gowebdav.Event
+
+	unsubscribe := fs.Subscribe(func(e gowebdav.Event) {
+		events = append(events, e)
+	})
+
+	root.emit(gowebdav.Event{Type: gowebdav.EventCreate, Name: "/file.txt"})
+	// Shadowed by the mount point
+	root.emit(gowebdav.Event{Type: gowebdav.EventWrite, Name: "/data/file.txt"})
+	data.emit(gowebdav.Event{Type: gowebdav.EventRename, Name: "/new.txt", OldName: "/old.txt"})
+	data.emit(gowebdav.Event{Type: gowebdav.EventOverflow, Name: "/"})
+
+	expected := []gowebdav.Event{
+		{Type: gowebdav.EventCreate, Name: "/file.txt"},
+		{Type: gowebdav.EventRename, Name: "/data/new.txt", OldName: "/data/old.txt"},
+		{Type: gowebdav.EventOverflow, Name: "/data"},
+	}
+
+	if !slices.Equal(expected, events) {
+		t.Errorf("events: expected %v, got %v", expected, events)
+	}
+
+	unsubscribe()
+
+	if root.fn != nil || data.fn != nil {
+		t.Errorf("expected subscriptions to be cancelled")
+	}
+}
+
+type eventSource struct {
+	webdav.FileSystem
+	fn func(gowebdav.Event)
+}
+
+func (s *eventSource) Subscribe(fn func(gowebdav.Event)) func() {
+	s.fn = fn
+
+	return func() {
+		s.fn = nil
+	}
+}
+
+func (s *eventSource) emit(e gowebdav.Event) {
+	if s.fn != nil {
+		s.fn(e)
+	}
+}
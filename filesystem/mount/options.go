@@ -0,0 +1,27 @@
+package mount
+
+type Options struct {
+	// CopyAcrossMounts moves resources across mounts by copying them to the
+	// destination mount then removing them, such moves being rejected otherwise
+	CopyAcrossMounts bool
+}
+
+type OptionFunc func(opts *Options)
+
+func WithCopyAcrossMounts(enabled bool) OptionFunc {
+	return func(opts *Options) {
+		opts.CopyAcrossMounts = enabled
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		CopyAcrossMounts: false,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,99 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// PropertiesFileSystem is implemented by backends able to store dead
+// properties alongside their files.
+//
+// Implementations must return [ErrNotSupported] when the given resource can not
+// hold properties, in which case callers are expected to fall back to another storage.
+// Once [PatchProperties] returned an [UnsupportedPropertiesError] for a resource,
+// [GetProperties] must return [ErrNotSupported] for it too.
+type PropertiesFileSystem interface {
+	webdav.FileSystem
+	GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error)
+	PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error
+}
+
+// UnsupportedPropertiesError is returned by [PropertiesFileSystem] implementations
+// no longer able to hold the properties of a resource. It matches [ErrNotSupported],
+// the properties held until then being handed over to the fallback storage.
+type UnsupportedPropertiesError struct {
+	Properties map[xml.Name]webdav.Property
+}
+
+// Error implements error.
+func (e *UnsupportedPropertiesError) Error() string {
+	return ErrNotSupported.Error()
+}
+
+// Unwrap returns [ErrNotSupported].
+func (e *UnsupportedPropertiesError) Unwrap() error {
+	return ErrNotSupported
+}
+
+// ApplyProppatches applies the given patches to the props map.
+func ApplyProppatches(props map[xml.Name]webdav.Property, patches []webdav.Proppatch) {
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			if patch.Remove {
+				delete(props, prop.XMLName)
+				continue
+			}
+
+			props[prop.XMLName] = prop
+		}
+	}
+}
+
+// MarshalProperties encodes the given properties in a format suitable
+// for backends storing them as opaque values (object metadata, extended attributes...).
+func MarshalProperties(props map[xml.Name]webdav.Property) ([]byte, error) {
+	list := make([]webdav.Property, 0, len(props))
+	for _, p := range props {
+		list = append(list, p)
+	}
+
+	slices.SortFunc(list, func(a, b webdav.Property) int {
+		if c := strings.Compare(a.XMLName.Space, b.XMLName.Space); c != 0 {
+			return c
+		}
+		return strings.Compare(a.XMLName.Local, b.XMLName.Local)
+	})
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return data, nil
+}
+
+// UnmarshalProperties decodes properties encoded with [MarshalProperties].
+func UnmarshalProperties(data []byte) (map[xml.Name]webdav.Property, error) {
+	props := make(map[xml.Name]webdav.Property)
+
+	if len(data) == 0 {
+		return props, nil
+	}
+
+	var list []webdav.Property
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, p := range list {
+		props[p.XMLName] = p
+	}
+
+	return props, nil
+}
@@ -30,7 +30,9 @@ type File struct {
 	obj *minio.Object
 
 	// Writer Mode (PUT)
-	isWriter   bool
+	isWriter bool
+	// Whether the object is uploaded even if nothing is written
+	truncate   bool
 	pipeWriter *io.PipeWriter
 	done       chan error
 }
@@ -96,9 +98,42 @@ func (f *File) Write(p []byte) (int, error) {
 	if !f.isWriter {
 		return 0, errors.New("file opened for reading")
 	}
+	if f.pipeWriter == nil {
+		if err := f.startUpload(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
 	return f.pipeWriter.Write(p)
 }
 
+// startUpload starts streaming the written content to the object, its dead
+// properties being preserved.
+func (f *File) startUpload() error {
+	userMetadata := f.fs.propertiesMetadata(f.ctx, f.key)
+
+	pr, pw := io.Pipe()
+	if !f.fs.trackUpload(pr) {
+		return errors.WithStack(os.ErrClosed)
+	}
+
+	f.pipeWriter = pw
+	f.done = make(chan error, 1)
+
+	go func() {
+		defer f.fs.untrackUpload(pr)
+		defer close(f.done)
+		_, err := f.fs.client.PutObject(f.ctx, f.fs.bucket, f.key, pr, -1, minio.PutObjectOptions{
+			ContentType:  "application/octet-stream",
+			PartSize:     5 * 1024 * 1024,
+			UserMetadata: userMetadata,
+		})
+		_ = pr.CloseWithError(err)
+		f.done <- err
+	}()
+
+	return nil
+}
+
 func (f *File) Read(p []byte) (int, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -141,6 +176,18 @@ func (f *File) Close() error {
 		return nil
 	}
 
+	if f.pipeWriter == nil {
+		// Opening the object read-write, i.e. to patch its properties,
+		// leaves it untouched
+		if !f.truncate {
+			return nil
+		}
+
+		if err := f.startUpload(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	if err := f.pipeWriter.Close(); err != nil {
 		return errors.WithStack(err)
 	}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/pkg/errors"
@@ -22,6 +23,12 @@ const (
 type FileSystem struct {
 	client *minio.Client
 	bucket string
+
+	// Pending streaming uploads
+	mu      sync.Mutex
+	closed  bool
+	uploads map[*io.PipeReader]struct{}
+	wg      sync.WaitGroup
 }
 
 // Mkdir implements webdav.FileSystem.
@@ -66,26 +73,20 @@ func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm o
 	}
 
 	if flag&os.O_RDWR != 0 || flag&os.O_WRONLY != 0 || flag&os.O_CREATE != 0 || flag&os.O_TRUNC != 0 {
-		pr, pw := io.Pipe()
-		file := &File{
-			ctx:        ctx,
-			fs:         f,
-			name:       name,
-			key:        name,
-			isWriter:   true,
-			pipeWriter: pw,
-			done:       make(chan error, 1),
+		if f.isClosed() {
+			return nil, errors.WithStack(os.ErrClosed)
 		}
 
-		go func() {
-			defer close(file.done)
-			_, err := f.client.PutObject(ctx, f.bucket, name, pr, -1, minio.PutObjectOptions{
-				ContentType: "application/octet-stream",
-				PartSize:    5 * 1024 * 1024,
-			})
-			_ = pr.CloseWithError(err)
-			file.done <- err
-		}()
+		// The object is uploaded once written, or once closed if it is
+		// created or truncated
+		file := &File{
+			ctx:      ctx,
+			fs:       f,
+			name:     name,
+			key:      name,
+			isWriter: true,
+			truncate: flag&(os.O_CREATE|os.O_TRUNC) != 0,
+		}
 
 		return file, nil
 	}
@@ -242,8 +243,23 @@ func (f *FileSystem) Rename(ctx context.Context, oldName string, newName string)
 	}
 
 	if stat.IsDir() {
-		if err := f.Mkdir(ctx, newName, os.ModePerm); err != nil && !errors.Is(err, os.ErrExist) {
-			return errors.WithStack(err)
+		// Copy the directory marker, if any, to keep its metadata
+		dest := minio.CopyDestOptions{
+			Bucket: f.bucket,
+			Object: newName + separator,
+		}
+		src := minio.CopySrcOptions{
+			Bucket: f.bucket,
+			Object: oldName + separator,
+		}
+		if _, err := f.client.CopyObject(ctx, dest, src); err != nil {
+			if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+				return errors.WithStack(err)
+			}
+
+			if err := f.Mkdir(ctx, newName, os.ModePerm); err != nil && !errors.Is(err, os.ErrExist) {
+				return errors.WithStack(err)
+			}
 		}
 
 		fileInfos, err := readdir(ctx, f.client, f.bucket, oldName, -1)
@@ -351,15 +367,64 @@ func (f *FileSystem) checkParent(ctx context.Context, name string) error {
 	return nil
 }
 
+// Close aborts the pending uploads and waits for their multipart uploads to be
+// discarded. Opening a file for writing afterwards fails with [os.ErrClosed].
+func (f *FileSystem) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	for pr := range f.uploads {
+		_ = pr.CloseWithError(os.ErrClosed)
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+
+	return nil
+}
+
+// isClosed returns true if the filesystem is closed.
+func (f *FileSystem) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.closed
+}
+
+// trackUpload registers the upload reading from pr, returning false if
+// the filesystem is closed.
+func (f *FileSystem) trackUpload(pr *io.PipeReader) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return false
+	}
+
+	f.uploads[pr] = struct{}{}
+	f.wg.Add(1)
+
+	return true
+}
+
+func (f *FileSystem) untrackUpload(pr *io.PipeReader) {
+	f.mu.Lock()
+	delete(f.uploads, pr)
+	f.mu.Unlock()
+
+	f.wg.Done()
+}
+
 // NewFileSystem creates a new S3 filesystem with the given client and bucket
 func NewFileSystem(client *minio.Client, bucket string) *FileSystem {
 	return &FileSystem{
-		client: client,
-		bucket: bucket,
+		client:  client,
+		bucket:  bucket,
+		uploads: make(map[*io.PipeReader]struct{}),
 	}
 }
 
 var _ webdav.FileSystem = &FileSystem{}
+var _ io.Closer = &FileSystem{}
 
 func clean(name string) string {
 	name = strings.Trim(name, separator)
@@ -0,0 +1,221 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"maps"
+
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// propertiesMetadataKey is the user metadata key holding the dead properties
+	// of an object, as returned (canonicalized) by the S3 API.
+	propertiesMetadataKey = "Webdav-Deadprops"
+	// fallbackMetadataKey marks the objects whose dead properties outgrew
+	// their user metadata and are held by the fallback storage.
+	fallbackMetadataKey = "Webdav-Deadprops-Fallback"
+	// maxUserMetadataSize is the maximum size of the user metadata of an
+	// object, keys and values included.
+	maxUserMetadataSize = 2 * 1024
+	// maxCopyObjectSize is the maximum size of an object copied onto itself
+	// to update its metadata.
+	maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+)
+
+// GetProperties implements [filesystem.PropertiesFileSystem].
+func (f *FileSystem) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	_, info, err := f.statPropertiesObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !holdsProperties(info) {
+		return nil, errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	props, err := decodeProperties(info.UserMetadata[propertiesMetadataKey])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return props, nil
+}
+
+// PatchProperties implements [filesystem.PropertiesFileSystem].
+// Objects too large to be copied onto themselves, and the ones whose
+// properties do not fit in their user metadata, do not hold properties.
+func (f *FileSystem) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	key, info, err := f.statPropertiesObject(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !holdsProperties(info) {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	props, err := decodeProperties(info.UserMetadata[propertiesMetadataKey])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	previous := maps.Clone(props)
+
+	filesystem.ApplyProppatches(props, patches)
+
+	metadata := make(map[string]string, len(info.UserMetadata)+1)
+	for k, v := range info.UserMetadata {
+		if k == propertiesMetadataKey {
+			continue
+		}
+
+		metadata[k] = v
+	}
+
+	if len(props) > 0 {
+		encoded, err := encodeProperties(props)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		metadata[propertiesMetadataKey] = encoded
+	}
+
+	if metadataSize(metadata) > maxUserMetadataSize {
+		// The properties are handed over to the fallback storage
+		delete(metadata, propertiesMetadataKey)
+		metadata[fallbackMetadataKey] = "true"
+
+		if err := f.replaceMetadata(ctx, key, metadata); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return errors.WithStack(&filesystem.UnsupportedPropertiesError{Properties: previous})
+	}
+
+	if err := f.replaceMetadata(ctx, key, metadata); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// replaceMetadata replaces the user metadata of the given object. Objects
+// metadata can not be updated in place, the object is copied onto itself instead.
+func (f *FileSystem) replaceMetadata(ctx context.Context, key string, metadata map[string]string) error {
+	dest := minio.CopyDestOptions{
+		Bucket:          f.bucket,
+		Object:          key,
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+	}
+	src := minio.CopySrcOptions{
+		Bucket: f.bucket,
+		Object: key,
+	}
+	if _, err := f.client.CopyObject(ctx, dest, src); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// holdsProperties returns true if the given object holds its dead properties
+// in its user metadata.
+func holdsProperties(info minio.ObjectInfo) bool {
+	if _, exists := info.UserMetadata[fallbackMetadataKey]; exists {
+		return false
+	}
+
+	return info.Size <= maxCopyObjectSize
+}
+
+// metadataSize returns the size of the given user metadata, as accounted by S3.
+func metadataSize(metadata map[string]string) int {
+	size := 0
+	for k, v := range metadata {
+		size += len(k) + len(v)
+	}
+
+	return size
+}
+
+// statPropertiesObject returns the key and informations of the object holding
+// the dead properties of the named resource, either the object itself or
+// its directory marker.
+func (f *FileSystem) statPropertiesObject(ctx context.Context, name string) (string, minio.ObjectInfo, error) {
+	name = clean(name)
+
+	// The bucket root and directories without marker have no object to hold properties
+	if name == separator {
+		return "", minio.ObjectInfo{}, errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	for _, key := range []string{name, name + separator} {
+		info, err := f.client.StatObject(ctx, f.bucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				continue
+			}
+
+			return "", minio.ObjectInfo{}, errors.WithStack(err)
+		}
+
+		return key, info, nil
+	}
+
+	return "", minio.ObjectInfo{}, errors.WithStack(filesystem.ErrNotSupported)
+}
+
+// propertiesMetadata returns the user metadata holding the dead properties of the given
+// object, if any, so that they can be preserved when the object is overwritten.
+func (f *FileSystem) propertiesMetadata(ctx context.Context, key string) map[string]string {
+	info, err := f.client.StatObject(ctx, f.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil
+	}
+
+	metadata := make(map[string]string)
+	for _, key := range []string{propertiesMetadataKey, fallbackMetadataKey} {
+		if value, exists := info.UserMetadata[key]; exists {
+			metadata[key] = value
+		}
+	}
+
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	return metadata
+}
+
+func encodeProperties(props map[xml.Name]webdav.Property) (string, error) {
+	data, err := filesystem.MarshalProperties(props)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	// User metadata values are transmitted as HTTP headers
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decodeProperties(value string) (map[xml.Name]webdav.Property, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	props, err := filesystem.UnmarshalProperties(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return props, nil
+}
+
+var _ filesystem.PropertiesFileSystem = &FileSystem{}
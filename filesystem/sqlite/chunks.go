@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"crypto/sha256"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// hashFunc is the SQL function returning the hash of a chunk
+const hashFunc = "webdav_sha256"
+
+// chunkHash returns the hash identifying the given chunk data.
+func chunkHash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// chunk is a part of the content of a file.
+type chunk struct {
+	start int64
+	data  []byte
+}
+
+// writeChunks stores the given data at the given offset of the named file,
+// the chunks it overlaps being replaced. The gap between the end of the file
+// and the offset, if any, is filled with zeros. It must be called within a
+// transaction.
+func writeChunks(conn *sqlite.Conn, name string, offset int64, data []byte, chunkSize int) error {
+	var size int64
+	err := sqlitex.Execute(conn, `
+		SELECT size FROM files WHERE path = ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{name},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			size = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	start := min(offset, size)
+	end := offset + int64(len(data))
+
+	// The last chunk of the file is completed rather than followed by
+	// another partial one
+	var overlapped []chunk
+	err = sqlitex.Execute(conn, `
+		SELECT fc.start, c.data
+		FROM file_chunks fc
+		JOIN chunks c ON c.hash = fc.hash
+		WHERE fc.path = ? AND fc.start < ? AND (fc.start + fc.size > ? OR (fc.start + fc.size = ? AND fc.size < ?))
+		ORDER BY fc.start
+	`, &sqlitex.ExecOptions{
+		Args: []any{name, end, start, start, chunkSize},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			c := chunk{
+				start: stmt.ColumnInt64(0),
+				data:  make([]byte, stmt.ColumnLen(1)),
+			}
+
+			stmt.ColumnBytes(1, c.data)
+			overlapped = append(overlapped, c)
+
+			return nil
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	from, to := start, end
+	if len(overlapped) > 0 {
+		first, last := overlapped[0], overlapped[len(overlapped)-1]
+		from = min(from, first.start)
+		to = max(to, last.start+int64(len(last.data)))
+	}
+
+	err = sqlitex.Execute(conn, `
+		DELETE FROM file_chunks WHERE path = ? AND start >= ? AND start < ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{name, from, to},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	written := chunk{start: offset, data: data}
+
+	for pos := from; pos < to; pos += int64(chunkSize) {
+		piece := make([]byte, min(int64(chunkSize), to-pos))
+
+		for _, c := range overlapped {
+			c.copyTo(piece, pos)
+		}
+
+		written.copyTo(piece, pos)
+
+		hash := chunkHash(piece)
+
+		err := sqlitex.Execute(conn, `
+			INSERT INTO chunks (hash, data) VALUES (?, ?) ON CONFLICT (hash) DO NOTHING
+		`, &sqlitex.ExecOptions{
+			Args: []any{hash, piece},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		err = sqlitex.Execute(conn, `
+			INSERT INTO file_chunks (path, start, size, hash) VALUES (?, ?, ?, ?)
+		`, &sqlitex.ExecOptions{
+			Args: []any{name, pos, len(piece), hash},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	err = sqlitex.Execute(conn, `
+		UPDATE files SET size = max(size, ?), mtime = ? WHERE path = ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{end, time.Now().Unix(), name},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// copyTo copies the part of the chunk overlapping the given buffer, starting
+// at the given offset of the file.
+func (c chunk) copyTo(buf []byte, offset int64) {
+	from := max(c.start, offset)
+	to := min(c.start+int64(len(c.data)), offset+int64(len(buf)))
+
+	if from >= to {
+		return
+	}
+
+	copy(buf[from-offset:to-offset], c.data[from-c.start:to-c.start])
+}
+
+// readChunk reads the content of the named file at the given offset, up to
+// the end of the chunk holding it.
+func readChunk(conn *sqlite.Conn, name string, offset int64, p []byte) (int, error) {
+	var (
+		start int64
+		size  int64
+		rowID int64
+		found bool
+	)
+
+	err := sqlitex.Execute(conn, `
+		SELECT fc.start, fc.size, c.rowid
+		FROM file_chunks fc
+		JOIN chunks c ON c.hash = fc.hash
+		WHERE fc.path = ? AND fc.start <= ?
+		ORDER BY fc.start DESC
+		LIMIT 1
+	`, &sqlitex.ExecOptions{
+		Args: []any{name, offset},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			start = stmt.ColumnInt64(0)
+			size = stmt.ColumnInt64(1)
+			rowID = stmt.ColumnInt64(2)
+			found = true
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if !found || offset >= start+size {
+		return 0, io.EOF
+	}
+
+	blob, err := conn.OpenBlob("", "chunks", "data", rowID, false)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer blob.Close()
+
+	if _, err := blob.Seek(offset-start, io.SeekStart); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	toRead := min(int64(len(p)), start+size-offset)
+
+	n, err := io.ReadFull(blob, p[:toRead])
+	if err != nil {
+		return n, errors.WithStack(err)
+	}
+
+	return n, nil
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
@@ -27,15 +25,15 @@ type File struct {
 	mode    os.FileMode
 	offset  int64
 
-	temp *os.File
+	// Written data not stored yet, starting at pendingOffset
+	pending       []byte
+	pendingOffset int64
 }
 
 // Close implements webdav.File.
 func (f *File) Close() error {
-	if f.temp != nil {
-		if err := f.moveTempFileToBlob(); err != nil {
-			return errors.WithStack(err)
-		}
+	if err := f.flush(true); err != nil {
+		return errors.WithStack(err)
 	}
 
 	return nil
@@ -57,92 +55,32 @@ func (f *File) Read(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
-	// Get a fresh connection for this read operation
-	conn, err := f.fs.pool.Take(f.ctx)
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	defer f.fs.pool.Put(conn)
-
-	// Check if the file has content
-	var hasContent bool
-	err = sqlitex.Execute(conn, `
-		SELECT 1 FROM file_contents WHERE path = ?
-	`, &sqlitex.ExecOptions{
-		Args: []interface{}{f.name},
-		ResultFunc: func(stmt *sqlite.Stmt) error {
-			hasContent = true
-			return nil
-		},
-	})
-	if err != nil {
+	// Make the pending writes readable
+	if err := f.flush(true); err != nil {
 		return 0, errors.WithStack(err)
 	}
 
-	// No content to read
-	if !hasContent {
-		return 0, io.EOF
-	}
-
-	// Get the rowid for the blob
-	var rowid int64
-	var found bool
-	err = sqlitex.Execute(conn, `
-		SELECT rowid FROM file_contents WHERE path = ?
-	`, &sqlitex.ExecOptions{
-		Args: []interface{}{f.name},
-		ResultFunc: func(stmt *sqlite.Stmt) error {
-			rowid = stmt.ColumnInt64(0)
-			found = true
-			return nil
-		},
-	})
+	// Get a fresh connection for this read operation
+	conn, err := f.fs.pool.Take(f.ctx)
 	if err != nil {
 		return 0, errors.WithStack(err)
 	}
 
-	if !found {
-		return 0, io.EOF
-	}
-
-	// Calculate how much we can read
-	toRead := int64(len(p))
-	if f.offset+toRead > f.size {
-		toRead = f.size - f.offset
-	}
-
-	// Nothing to read
-	if toRead <= 0 {
-		return 0, io.EOF
-	}
+	defer f.fs.pool.Put(conn)
 
-	// Open the blob for reading within this operation
-	blob, err := conn.OpenBlob("", "file_contents", "content", rowid, false)
+	// Only the chunk holding the current offset is read
+	n, err = readChunk(conn, f.name, f.offset, p[:min(int64(len(p)), f.size-f.offset)])
 	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-	defer blob.Close()
-
-	// Seek to the correct position
-	if _, err := blob.Seek(f.offset, io.SeekStart); err != nil {
-		return 0, errors.WithStack(err)
-	}
+		if errors.Is(err, io.EOF) {
+			return n, io.EOF
+		}
 
-	// Read directly into the buffer
-	n, err = blob.Read(p[:toRead])
-	if err != nil && err != io.EOF {
 		return n, errors.WithStack(err)
 	}
 
 	// Update offset
 	f.offset += int64(n)
 
-	// Handle EOF condition
-	if int64(n) < toRead {
-		return n, io.EOF
-	}
-
 	return n, nil
 }
 
@@ -193,28 +131,16 @@ func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 	defer f.fs.pool.Put(conn)
 
 	var entries []os.FileInfo
-	prefix := f.name
-	if prefix != "/" {
-		prefix += "/"
-	}
 
-	// Improved query to better filter direct children
+	// Direct children are found through the parent index
 	err = sqlitex.Execute(conn, `
-		SELECT path, is_dir, mode, size, mtime FROM files 
-		WHERE path LIKE ? AND path != ?
+		SELECT path, is_dir, mode, size, mtime FROM files
+		WHERE parent = ?
 	`, &sqlitex.ExecOptions{
-		Args: []interface{}{prefix + "%", f.name},
+		Args: []interface{}{f.name},
 		ResultFunc: func(stmt *sqlite.Stmt) error {
-			path := stmt.ColumnText(0)
-
-			// Skip entries that are not direct children
-			relPath := strings.TrimPrefix(path, prefix)
-			if relPath == "" || strings.Contains(relPath, "/") {
-				return nil
-			}
-
 			info := &fileInfo{
-				name:    path,
+				name:    stmt.ColumnText(0),
 				isDir:   stmt.ColumnInt(1) == 1,
 				mode:    os.FileMode(stmt.ColumnInt64(2)),
 				size:    stmt.ColumnInt64(3),
@@ -243,7 +169,9 @@ func (f *File) Stat() (os.FileInfo, error) {
 	}, nil
 }
 
-// Write implements webdav.File with a reliable streaming approach for large files.
+// Write implements webdav.File.
+// The written data is buffered and stored a chunk at a time, so that
+// sequential writes are streamed into the database.
 func (f *File) Write(p []byte) (n int, err error) {
 	if f.isDir {
 		return 0, &os.PathError{
@@ -257,33 +185,47 @@ func (f *File) Write(p []byte) (n int, err error) {
 		return 0, os.ErrPermission
 	}
 
-	if f.temp == nil {
-		tempDir, err := os.MkdirTemp("", "calli-*")
-		if err != nil {
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = f.size
+	}
+
+	// Only contiguous writes are buffered together
+	if len(f.pending) > 0 && f.offset != f.pendingOffset+int64(len(f.pending)) {
+		if err := f.flush(true); err != nil {
 			return 0, errors.WithStack(err)
 		}
+	}
 
-		tempName := filepath.Join(tempDir, "file")
+	if len(f.pending) == 0 {
+		f.pendingOffset = f.offset
+	}
 
-		temp, err := os.OpenFile(tempName, os.O_CREATE|os.O_RDWR, 0644)
-		if err != nil {
+	f.pending = append(f.pending, p...)
+	f.offset += int64(len(p))
+	f.size = max(f.size, f.offset)
+	f.modTime = time.Now()
+
+	if len(f.pending) >= f.fs.chunkSize {
+		if err := f.flush(false); err != nil {
 			return 0, errors.WithStack(err)
 		}
-
-		f.temp = temp
 	}
 
-	return f.temp.Write(p)
+	return len(p), nil
 }
 
-func (f *File) moveTempFileToBlob() error {
-	defer func() {
-		f.temp.Close()
-		os.RemoveAll(f.temp.Name())
-		f.temp = nil
-	}()
+// flush stores the buffered data. Unless all is true, the data following
+// the last chunk boundary is kept buffered.
+func (f *File) flush(all bool) error {
+	size := len(f.pending)
+	if !all {
+		size -= int((f.pendingOffset + int64(size)) % int64(f.fs.chunkSize))
+	}
+
+	if size <= 0 {
+		return nil
+	}
 
-	// Get a fresh connection for this read operation
 	conn, err := f.fs.pool.Take(f.ctx)
 	if err != nil {
 		return errors.WithStack(err)
@@ -291,75 +233,15 @@ func (f *File) moveTempFileToBlob() error {
 	defer f.fs.pool.Put(conn)
 
 	err = withImmediate(conn, func() error {
-		if err := f.temp.Close(); err != nil {
-			return errors.WithStack(err)
-		}
-
-		file, err := os.Open(f.temp.Name())
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		defer file.Close()
-
-		var rowID int64
-
-		err = sqlitex.Execute(conn, `
-			SELECT fc.rowid
-			FROM file_contents fc
-			JOIN files f ON fc.path = f.path
-			WHERE f.path = ?
-		`, &sqlitex.ExecOptions{
-			Args: []any{f.name},
-			ResultFunc: func(stmt *sqlite.Stmt) error {
-				rowID = stmt.ColumnInt64(0)
-				return nil
-			},
-		})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		stat, err := file.Stat()
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		err = sqlitex.Execute(conn, `
-			UPDATE file_contents SET content = zeroblob(?) WHERE path = ?
-		`, &sqlitex.ExecOptions{
-			Args: []any{stat.Size(), f.name},
-		})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		err = sqlitex.Execute(conn, `
-			UPDATE files SET size = ? WHERE path = ?
-		`, &sqlitex.ExecOptions{
-			Args: []any{stat.Size(), f.name},
-		})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		blob, err := conn.OpenBlob("", "file_contents", "content", rowID, true)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		defer blob.Close()
-
-		if _, err := io.Copy(blob, file); err != nil {
-			return errors.WithStack(err)
-		}
-
-		return nil
+		return writeChunks(conn, f.name, f.pendingOffset, f.pending[:size], f.fs.chunkSize)
 	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	f.pending = append(f.pending[:0], f.pending[size:]...)
+	f.pendingOffset += int64(size)
+
 	return nil
 }
 
@@ -372,21 +254,28 @@ func (f *File) truncate() error {
 	}
 	defer f.fs.pool.Put(conn)
 
-	// Update file size to 0
-	err = sqlitex.Execute(conn, `
-		UPDATE files SET size = 0, mtime = ? WHERE path = ?
-	`, &sqlitex.ExecOptions{
-		Args: []interface{}{time.Now().Unix(), f.name},
-	})
-	if err != nil {
-		return errors.WithStack(err)
-	}
+	err = withImmediate(conn, func() error {
+		// Update file size to 0
+		err := sqlitex.Execute(conn, `
+			UPDATE files SET size = 0, mtime = ? WHERE path = ?
+		`, &sqlitex.ExecOptions{
+			Args: []interface{}{time.Now().Unix(), f.name},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-	// Replace content with empty blob
-	err = sqlitex.Execute(conn, `
-		UPDATE file_contents SET content = zeroblob(0) WHERE path = ?
-	`, &sqlitex.ExecOptions{
-		Args: []interface{}{f.name},
+		// Release its chunks
+		err = sqlitex.Execute(conn, `
+			DELETE FROM file_chunks WHERE path = ?
+		`, &sqlitex.ExecOptions{
+			Args: []interface{}{f.name},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -395,6 +284,7 @@ func (f *File) truncate() error {
 	// Update file info
 	f.size = 0
 	f.modTime = time.Now()
+	f.pending = nil
 
 	return nil
 }
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	gowebdav "github.com/bornholm/go-webdav"
 	"github.com/pkg/errors"
 	"golang.org/x/net/webdav"
 	"zombiezen.com/go/sqlite"
@@ -18,7 +20,8 @@ import (
 )
 
 type FileSystem struct {
-	pool *sqlitemigration.Pool
+	pool      *sqlitemigration.Pool
+	chunkSize int
 }
 
 // fileInfo implements os.FileInfo for a file or directory in the SQLite filesystem
@@ -69,10 +72,10 @@ func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) e
 	defer f.pool.Put(conn)
 
 	err = sqlitex.Execute(conn, `
-		INSERT INTO files (path, is_dir, mode, size, mtime)
-		VALUES (?, 1, ?, 0, ?)
+		INSERT INTO files (path, parent, is_dir, mode, size, mtime)
+		VALUES (?, ?, 1, ?, 0, ?)
 	`, &sqlitex.ExecOptions{
-		Args: []interface{}{name, uint32(perm), time.Now().Unix()},
+		Args: []interface{}{name, parent, uint32(perm), time.Now().Unix()},
 	})
 
 	return errors.WithStack(err)
@@ -111,21 +114,10 @@ func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm o
 			defer f.pool.Put(conn)
 
 			err = sqlitex.Execute(conn, `
-				INSERT INTO files (path, is_dir, mode, size, mtime)
-				VALUES (?, 0, ?, 0, ?)
+				INSERT INTO files (path, parent, is_dir, mode, size, mtime)
+				VALUES (?, ?, 0, ?, 0, ?)
 			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name, uint32(perm), time.Now().Unix()},
-			})
-			if err != nil {
-				return nil, errors.WithStack(err)
-			}
-
-			// Create empty content
-			err = sqlitex.Execute(conn, `
-				INSERT INTO file_contents (path, content)
-				VALUES (?, zeroblob(0))
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name},
+				Args: []interface{}{name, parent, uint32(perm), time.Now().Unix()},
 			})
 			if err != nil {
 				return nil, errors.WithStack(err)
@@ -143,7 +135,13 @@ func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm o
 
 	// Check if trying to open a directory with write flags
 	if info.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
-		return nil, errors.New("cannot write to directory")
+		// PROPPATCH requests open their target read-write, the directory
+		// is opened read-only instead
+		if flag != os.O_RDWR {
+			return nil, errors.New("cannot write to directory")
+		}
+
+		flag = os.O_RDONLY
 	}
 
 	// Create file object
@@ -174,9 +172,13 @@ func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm o
 func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
 	name = cleanPath(name)
 
+	if name == "/" {
+		// Prohibit removing the root directory
+		return os.ErrInvalid
+	}
+
 	// Check if path exists
-	info, err := f.Stat(ctx, name)
-	if err != nil {
+	if _, err := f.Stat(ctx, name); err != nil {
 		if os.IsNotExist(err) {
 			return nil
 		}
@@ -189,62 +191,37 @@ func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
 	}
 	defer f.pool.Put(conn)
 
-	// Use a transaction to make sure all operations are atomic
-	removeErr := func() error {
-		// Use Save for automatic transaction management
-		defer sqlitex.Save(conn)(&err)
+	from, to := descendants(name)
 
-		if info.IsDir() {
-			// For directories, we need to remove all children as well
-			err = sqlitex.Execute(conn, `
-				DELETE FROM files 
-				WHERE path = ? OR path LIKE ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name, strings.TrimSuffix(name, "/") + "/" + "%"},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
-
-			// Also remove any associated file contents
-			err = sqlitex.Execute(conn, `
-				DELETE FROM file_contents
-				WHERE path = ? OR path LIKE ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name, strings.TrimSuffix(name, "/") + "/" + "%"},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		} else {
-			// For files, remove from both tables
-
-			// Delete from file_contents
-			err = sqlitex.Execute(conn, `
-				DELETE FROM file_contents
-				WHERE path = ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
+	// Use a transaction to make sure all operations are atomic
+	err = withSave(conn, func() error {
+		// The resource and its descendants, if any, are selected by range
+		// on the primary key, their chunks being released along with them
+		err := sqlitex.Execute(conn, `
+			DELETE FROM files
+			WHERE path = $name OR (path > $from AND path < $to)
+		`, &sqlitex.ExecOptions{
+			Named: map[string]any{"$name": name, "$from": from, "$to": to},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-			// Delete from files
-			err = sqlitex.Execute(conn, `
-				DELETE FROM files
-				WHERE path = ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{name},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
+		// And their dead properties
+		err = sqlitex.Execute(conn, `
+			DELETE FROM properties
+			WHERE path = $name OR (path > $from AND path < $to)
+		`, &sqlitex.ExecOptions{
+			Named: map[string]any{"$name": name, "$from": from, "$to": to},
+		})
+		if err != nil {
+			return errors.WithStack(err)
 		}
+
 		return nil
-	}()
+	})
 
-	return removeErr
+	return errors.WithStack(err)
 }
 
 // Rename implements webdav.FileSystem.
@@ -252,14 +229,18 @@ func (f *FileSystem) Rename(ctx context.Context, oldName string, newName string)
 	oldName = cleanPath(oldName)
 	newName = cleanPath(newName)
 
+	if oldName == "/" || newName == "/" || strings.HasPrefix(newName, oldName+"/") {
+		// Prohibit renaming the root directory or a directory into itself
+		return os.ErrInvalid
+	}
+
 	// Check if old path exists
-	oldInfo, err := f.Stat(ctx, oldName)
-	if err != nil {
+	if _, err := f.Stat(ctx, oldName); err != nil {
 		return err
 	}
 
 	// Check if new path exists
-	_, err = f.Stat(ctx, newName)
+	_, err := f.Stat(ctx, newName)
 	if err == nil {
 		return os.ErrExist
 	} else if !os.IsNotExist(err) {
@@ -284,103 +265,121 @@ func (f *FileSystem) Rename(ctx context.Context, oldName string, newName string)
 	}
 	defer f.pool.Put(conn)
 
-	renameErr := func() error {
-		// Use Save for automatic transaction management
-		defer sqlitex.Save(conn)(&err)
+	from, to := descendants(oldName)
+
+	err = withSave(conn, func() error {
+		// The resource and its descendants are moved at once by replacing
+		// the prefix of their paths, their chunks following them
+		err := sqlitex.Execute(conn, `
+			UPDATE files
+			SET
+				path = $new || substr(path, length($old) + 1),
+				parent = CASE WHEN path = $old THEN $parent ELSE $new || substr(parent, length($old) + 1) END
+			WHERE path = $old OR (path > $from AND path < $to)
+		`, &sqlitex.ExecOptions{
+			Named: map[string]any{"$old": oldName, "$new": newName, "$parent": newParent, "$from": from, "$to": to},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-		if oldInfo.IsDir() {
-			// For directories, we need to update the path for all children as well
+		// Update dead properties
+		err = sqlitex.Execute(conn, `
+			UPDATE properties
+			SET path = $new || substr(path, length($old) + 1)
+			WHERE path = $old OR (path > $from AND path < $to)
+		`, &sqlitex.ExecOptions{
+			Named: map[string]any{"$old": oldName, "$new": newName, "$from": from, "$to": to},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-			// Update the directory itself
-			err = sqlitex.Execute(conn, `
-				UPDATE files 
-				SET path = ?
-				WHERE path = ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{newName, oldName},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
+		return nil
+	})
 
-			// Find all children and update their paths
-			stmt, err := conn.Prepare(`SELECT path FROM files WHERE path LIKE ?`)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			defer stmt.Finalize()
+	return errors.WithStack(err)
+}
 
-			stmt.BindText(1, oldName+"/"+"%")
+// CopyFile implements [gowebdav.FileCopier].
+// The chunks of the source file are shared with the destination rather than
+// read and written again.
+func (f *FileSystem) CopyFile(ctx context.Context, src string, dst string) error {
+	src = cleanPath(src)
+	dst = cleanPath(dst)
 
-			var childPaths []string
-			for {
-				if hasRow, err := stmt.Step(); err != nil {
-					return errors.WithStack(err)
-				} else if !hasRow {
-					break
-				}
+	if src == dst {
+		return nil
+	}
 
-				childPaths = append(childPaths, stmt.ColumnText(0))
-			}
+	conn, err := f.pool.Take(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.pool.Put(conn)
 
-			for _, oldPath := range childPaths {
-				newPath := strings.Replace(oldPath, oldName, newName, 1)
+	err = withImmediate(conn, func() error {
+		var (
+			size  int64
+			found bool
+		)
+
+		err := sqlitex.Execute(conn, `
+			SELECT size FROM files WHERE path = ? AND is_dir = 0
+		`, &sqlitex.ExecOptions{
+			Args: []any{src},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				size = stmt.ColumnInt64(0)
+				found = true
+				return nil
+			},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-				err = sqlitex.Execute(conn, `
-					UPDATE files 
-					SET path = ?
-					WHERE path = ?
-				`, &sqlitex.ExecOptions{
-					Args: []interface{}{newPath, oldPath},
-				})
-				if err != nil {
-					return errors.WithStack(err)
-				}
+		if !found {
+			return os.ErrNotExist
+		}
 
-				// Update file contents if needed
-				err = sqlitex.Execute(conn, `
-					UPDATE file_contents 
-					SET path = ?
-					WHERE path = ?
-				`, &sqlitex.ExecOptions{
-					Args: []interface{}{newPath, oldPath},
-				})
-				if err != nil {
-					return errors.WithStack(err)
-				}
-			}
-		} else {
-			// For files, just update the specific path
+		err = sqlitex.Execute(conn, `
+			UPDATE files SET size = ?, mtime = ? WHERE path = ? AND is_dir = 0
+		`, &sqlitex.ExecOptions{
+			Args: []any{size, time.Now().Unix(), dst},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
 
-			// Update file metadata
-			err = sqlitex.Execute(conn, `
-				UPDATE files 
-				SET path = ?
-				WHERE path = ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{newName, oldName},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
+		if conn.Changes() == 0 {
+			return os.ErrNotExist
+		}
 
-			// Update file content
-			err = sqlitex.Execute(conn, `
-				UPDATE file_contents 
-				SET path = ?
-				WHERE path = ?
-			`, &sqlitex.ExecOptions{
-				Args: []interface{}{newName, oldName},
-			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
+		err = sqlitex.Execute(conn, `
+			DELETE FROM file_chunks WHERE path = ?
+		`, &sqlitex.ExecOptions{
+			Args: []any{dst},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// The chunk references are duplicated at once, the triggers keeping
+		// the reference counts of the chunks up to date
+		err = sqlitex.Execute(conn, `
+			INSERT INTO file_chunks (path, start, size, hash)
+			SELECT $dst, start, size, hash FROM file_chunks WHERE path = $src
+		`, &sqlitex.ExecOptions{
+			Named: map[string]any{"$dst": dst, "$src": src},
+		})
+		if err != nil {
+			return errors.WithStack(err)
 		}
 
 		return nil
-	}()
+	})
 
-	return renameErr
+	return errors.WithStack(err)
 }
 
 // Stat implements webdav.FileSystem.
@@ -434,6 +433,12 @@ func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error)
 	return info, nil
 }
 
+// descendants returns the bounds, both excluded, of the paths of the
+// descendants of the named directory, '0' following '/'.
+func descendants(name string) (string, string) {
+	return name + "/", name + "0"
+}
+
 // Helper function to clean and normalize paths
 func cleanPath(name string) string {
 	if name == "" {
@@ -452,7 +457,18 @@ func cleanPath(name string) string {
 	return name
 }
 
-func NewFileSystem(dbPath string) *FileSystem {
+// Close releases the underlying database connections.
+func (f *FileSystem) Close() error {
+	if err := f.pool.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func NewFileSystem(dbPath string, funcs ...OptionFunc) *FileSystem {
+	opts := NewFileSystemOptions(funcs...)
+
 	schema := sqlitemigration.Schema{
 		Migrations: []string{
 			`CREATE TABLE IF NOT EXISTS files (
@@ -469,6 +485,62 @@ func NewFileSystem(dbPath string) *FileSystem {
 					content BLOB              -- File content
 				);
 			`,
+			`CREATE TABLE IF NOT EXISTS properties (
+					path TEXT NOT NULL,             -- File path
+					namespace TEXT NOT NULL,        -- Property XML namespace
+					local TEXT NOT NULL,            -- Property XML local name
+					lang TEXT NOT NULL DEFAULT '',  -- Property xml:lang attribute
+					inner_xml BLOB,                 -- Property raw XML value
+					PRIMARY KEY (path, namespace, local)
+				);
+			`,
+			// Split the files content into chunks shared by the files
+			fmt.Sprintf(`CREATE TABLE chunks (
+					hash BLOB PRIMARY KEY,          -- SHA-256 of the chunk data
+					data BLOB NOT NULL,             -- Chunk data
+					refs INTEGER NOT NULL DEFAULT 0 -- Number of files chunks referencing it
+				);
+				CREATE TABLE file_chunks (
+					path TEXT NOT NULL REFERENCES files(path) ON DELETE CASCADE ON UPDATE CASCADE,
+					start INTEGER NOT NULL,         -- Offset of the chunk in the file
+					size INTEGER NOT NULL,          -- Chunk size in bytes
+					hash BLOB NOT NULL REFERENCES chunks(hash),
+					PRIMARY KEY (path, start)
+				);
+				CREATE INDEX idx_file_chunks_hash ON file_chunks(hash);
+				CREATE TRIGGER file_chunks_insert AFTER INSERT ON file_chunks BEGIN
+					UPDATE chunks SET refs = refs + 1 WHERE hash = NEW.hash;
+				END;
+				CREATE TRIGGER file_chunks_delete AFTER DELETE ON file_chunks BEGIN
+					UPDATE chunks SET refs = refs - 1 WHERE hash = OLD.hash;
+					DELETE FROM chunks WHERE hash = OLD.hash AND refs <= 0;
+				END;
+				CREATE TEMP TABLE migrated_chunks AS
+					WITH RECURSIVE parts(path, start, size) AS (
+						SELECT path, 0, length(content) FROM file_contents WHERE length(content) > 0
+						UNION ALL
+						SELECT path, start + %[1]d, size FROM parts WHERE start + %[1]d < size
+					)
+					SELECT parts.path, parts.start, substr(file_contents.content, parts.start + 1, %[1]d) AS data
+					FROM parts JOIN file_contents ON file_contents.path = parts.path;
+				INSERT OR IGNORE INTO chunks (hash, data) SELECT %[2]s(data), data FROM migrated_chunks;
+				INSERT INTO file_chunks (path, start, size, hash) SELECT path, start, length(data), %[2]s(data) FROM migrated_chunks;
+				DROP TABLE migrated_chunks;
+				DROP TABLE file_contents;
+			`, DefaultChunkSize, hashFunc),
+			// Index the files by parent directory, the primary key already
+			// indexing the paths. The parent of a path is obtained by trimming
+			// its last component, i.e. its trailing characters other than '/'.
+			`ALTER TABLE files ADD COLUMN parent TEXT;
+				UPDATE files
+				SET parent = CASE
+					WHEN rtrim(path, replace(path, '/', '')) = '/' THEN '/'
+					ELSE rtrim(rtrim(path, replace(path, '/', '')), '/')
+				END
+				WHERE path != '/';
+				DROP INDEX IF EXISTS idx_parent_path;
+				CREATE INDEX idx_files_parent ON files(parent);
+			`,
 		},
 		RepeatableMigration: fmt.Sprintf(`INSERT OR IGNORE INTO files (path, is_dir, mode, size, mtime) VALUES ('/', 1, 493, 0, %d)`, time.Now().Unix()),
 	}
@@ -476,7 +548,26 @@ func NewFileSystem(dbPath string) *FileSystem {
 	pool := sqlitemigration.NewPool(dbPath, schema, sqlitemigration.Options{
 		Flags: sqlite.OpenCreate | sqlite.OpenReadWrite | sqlite.OpenWAL,
 		PrepareConn: func(conn *sqlite.Conn) error {
-			return sqlitex.ExecScript(conn, `PRAGMA foreign_keys = ON; PRAGMA auto_vacuum=FULL; PRAGMA busy_timeout = 5000;`)
+			// The chunks hash function is used by the migrations
+			if err := conn.CreateFunction(hashFunc, &sqlite.FunctionImpl{
+				NArgs:         1,
+				Deterministic: true,
+				Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+					return sqlite.BlobValue(chunkHash(args[0].Blob())), nil
+				},
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			// Pragmas are executed one by one, foreign keys not being
+			// enabled within a transaction
+			for _, pragma := range []string{`PRAGMA foreign_keys = ON;`, `PRAGMA auto_vacuum = FULL;`, `PRAGMA busy_timeout = 5000;`} {
+				if err := sqlitex.ExecuteTransient(conn, pragma, nil); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			return nil
 		},
 		OnError: func(e error) {
 			log.Printf("%+v", e)
@@ -484,8 +575,11 @@ func NewFileSystem(dbPath string) *FileSystem {
 	})
 
 	return &FileSystem{
-		pool: pool,
+		pool:      pool,
+		chunkSize: opts.ChunkSize,
 	}
 }
 
 var _ webdav.FileSystem = &FileSystem{}
+var _ io.Closer = &FileSystem{}
+var _ gowebdav.FileCopier = &FileSystem{}
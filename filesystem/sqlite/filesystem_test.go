@@ -1,15 +1,24 @@
 package sqlite
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	gowebdav "github.com/bornholm/go-webdav"
 	"github.com/bornholm/go-webdav/filesystem/bench"
 	"github.com/bornholm/go-webdav/filesystem/testsuite"
 	"github.com/bornholm/go-webdav/litmus"
+	"github.com/bornholm/go-webdav/middleware/logger"
 	"github.com/pkg/errors"
 	"golang.org/x/net/webdav"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
 )
 
 func TestFileSystem(t *testing.T) {
@@ -22,12 +31,51 @@ func TestLitmus(t *testing.T) {
 	litmus.RunTestSuite(t, fs)
 }
 
+func TestFileSystemClose(t *testing.T) {
+	ctx := context.Background()
+
+	dbPath := createDatabasePath(t)
+	fs := NewFileSystem(dbPath)
+
+	// Closing the wrapping middlewares closes the backend
+	wrapped := logger.NewFileSystem(fs, slog.Default())
+
+	if err := wrapped.Mkdir(ctx, "/dir", 0755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := gowebdav.Close(wrapped); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := fs.Stat(ctx, "/dir"); err == nil {
+		t.Errorf("expected an error after closing the filesystem")
+	}
+
+	// Reopen the database to ensure that the changes are persisted
+	reopened := NewFileSystem(dbPath)
+	defer reopened.Close()
+
+	info, err := reopened.Stat(ctx, "/dir")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !info.IsDir() {
+		t.Errorf("expected '/dir' to be a directory")
+	}
+}
+
 func BenchmarkFileSystem(b *testing.B) {
 	fs := createFileSystem(b)
 	bench.RunTestSuite(b, fs)
 }
 
 func createFileSystem(t testing.TB) webdav.FileSystem {
+	return NewFileSystem(createDatabasePath(t))
+}
+
+func createDatabasePath(t testing.TB) string {
 	cwd, err := os.Getwd()
 	if err != nil {
 		t.Fatalf("%+v", errors.WithStack(err))
@@ -46,7 +94,305 @@ func createFileSystem(t testing.TB) webdav.FileSystem {
 		}
 	}
 
+	return dbPath
+}
+
+func TestFileSystemChunks(t *testing.T) {
+	ctx := context.Background()
+
+	fs := NewFileSystem(createDatabasePath(t), WithChunkSize(4))
+	defer fs.Close()
+
+	writeFile := func(name string, content string) {
+		file, err := fs.OpenFile(ctx, name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		// Written in small pieces to exercise the buffering
+		for i := 0; i < len(content); i += 3 {
+			if _, err := file.Write([]byte(content[i:min(i+3, len(content))])); err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+		}
+
+		if err := file.Close(); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	readFile := func(name string) string {
+		file, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		return string(data)
+	}
+
+	countChunks := func() int {
+		conn, err := fs.pool.Take(ctx)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		defer fs.pool.Put(conn)
+
+		count, err := sqlitex.ResultInt(conn.Prep(`SELECT count(*) FROM chunks`))
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		return count
+	}
+
+	writeFile("/a.txt", "0123456789abcdef01")
+
+	if e, g := "0123456789abcdef01", readFile("/a.txt"); e != g {
+		t.Errorf("content: expected '%s', got '%s'", e, g)
+	}
+
+	// "0123", "4567", "89ab", "cdef", "01"
+	if e, g := 5, countChunks(); e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	// Identical content is stored once
+	writeFile("/b.txt", "0123456789abcdef01")
+
+	if e, g := 5, countChunks(); e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	// Overwrite in place and extend the file beyond its end
+	file, err := fs.OpenFile(ctx, "/b.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := file.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := file.Write([]byte("XY")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := file.Seek(2, io.SeekEnd); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := file.Write([]byte("Z")); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "012345XY89abcdef01\x00\x00Z", readFile("/b.txt"); e != g {
+		t.Errorf("content: expected %q, got %q", e, g)
+	}
+
+	info, err := fs.Stat(ctx, "/b.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := int64(21), info.Size(); e != g {
+		t.Errorf("size: expected %d, got %d", e, g)
+	}
+
+	// "45XY", "01\x00\x00" and "Z" are added
+	if e, g := 8, countChunks(); e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	// The chunks are released with the last file referencing them,
+	// "4567" and "01" here
+	if err := fs.RemoveAll(ctx, "/a.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 6, countChunks(); e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	if err := fs.RemoveAll(ctx, "/b.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 0, countChunks(); e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+}
+
+func TestFileSystemCopyFile(t *testing.T) {
+	ctx := context.Background()
+
+	fs := NewFileSystem(createDatabasePath(t), WithChunkSize(4))
+	defer fs.Close()
+
+	countChunks := func() (int, int) {
+		conn, err := fs.pool.Take(ctx)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		defer fs.pool.Put(conn)
+
+		var count, refs int
+
+		err = sqlitex.Execute(conn, `SELECT count(*), coalesce(sum(refs), 0) FROM chunks`, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				count = stmt.ColumnInt(0)
+				refs = stmt.ColumnInt(1)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		return count, refs
+	}
+
+	testsuite.WriteFileContent(t, ctx, fs, "/a.txt", "0123456789")
+	testsuite.WriteFileContent(t, ctx, fs, "/b.txt", "previous content")
+
+	// "0123", "4567", "89" and "prev", "ious", " con", "tent"
+	count, _ := countChunks()
+
+	if e, g := 7, count; e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	if err := fs.CopyFile(ctx, "/a.txt", "/b.txt"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "0123456789", testsuite.ReadFileContent(t, ctx, fs, "/b.txt"); e != g {
+		t.Errorf("content: expected '%s', got '%s'", e, g)
+	}
+
+	info, err := fs.Stat(ctx, "/b.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := int64(10), info.Size(); e != g {
+		t.Errorf("size: expected %d, got %d", e, g)
+	}
+
+	// The chunks of the source are shared and the previous ones released
+	var refs int
+	count, refs = countChunks()
+
+	if e, g := 3, count; e != g {
+		t.Errorf("chunks: expected %d, got %d", e, g)
+	}
+
+	if e, g := 6, refs; e != g {
+		t.Errorf("refs: expected %d, got %d", e, g)
+	}
+
+	if err := fs.CopyFile(ctx, "/a.txt", "/missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("copy to missing file: expected os.ErrNotExist, got %+v", err)
+	}
+
+	if err := fs.CopyFile(ctx, "/missing.txt", "/b.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("copy of missing file: expected os.ErrNotExist, got %+v", err)
+	}
+
+	if e, g := "0123456789", testsuite.ReadFileContent(t, ctx, fs, "/b.txt"); e != g {
+		t.Errorf("content: expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestFileSystemMigration(t *testing.T) {
+	ctx := context.Background()
+
+	dbPath := createDatabasePath(t)
+
+	// Database created before the content was split into chunks
+	conn, err := sqlite.OpenConn(dbPath, sqlite.OpenCreate|sqlite.OpenReadWrite)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	content := strings.Repeat("0123456789", DefaultChunkSize/4)
+
+	err = sqlitex.ExecuteScript(conn, `
+		CREATE TABLE files (path TEXT PRIMARY KEY, is_dir INTEGER NOT NULL, mode INTEGER NOT NULL, size INTEGER NOT NULL, mtime INTEGER NOT NULL);
+		CREATE INDEX idx_parent_path ON files(path);
+		CREATE TABLE file_contents (path TEXT PRIMARY KEY REFERENCES files(path) ON DELETE CASCADE, content BLOB);
+		CREATE TABLE properties (path TEXT NOT NULL, namespace TEXT NOT NULL, local TEXT NOT NULL, lang TEXT NOT NULL DEFAULT '', inner_xml BLOB, PRIMARY KEY (path, namespace, local));
+		INSERT INTO files VALUES ('/', 1, 493, 0, 0), ('/file.txt', 0, 420, $size, 0), ('/empty.txt', 0, 420, 0, 0), ('/dir', 1, 493, 0, 0), ('/dir/sub', 1, 493, 0, 0);
+		INSERT INTO file_contents VALUES ('/file.txt', CAST($content AS BLOB)), ('/empty.txt', zeroblob(0));
+		PRAGMA user_version = 4;
+	`, &sqlitex.ExecOptions{
+		Named: map[string]any{
+			"$size":    len(content),
+			"$content": content,
+		},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
 	fs := NewFileSystem(dbPath)
+	defer fs.Close()
+
+	for name, expected := range map[string]string{"/file.txt": content, "/empty.txt": ""} {
+		file, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
 
-	return fs
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if string(data) != expected {
+			t.Errorf("'%s': expected %d bytes of migrated content, got %d", name, len(expected), len(data))
+		}
+	}
+
+	// The parent directories are indexed
+	for name, expected := range map[string][]string{"/": {"dir", "empty.txt", "file.txt"}, "/dir": {"sub"}, "/dir/sub": {}} {
+		dir, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		entries, err := dir.Readdir(-1)
+		dir.Close()
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		slices.Sort(names)
+
+		if !slices.Equal(expected, names) {
+			t.Errorf("'%s': expected entries %v, got %v", name, expected, names)
+		}
+	}
 }
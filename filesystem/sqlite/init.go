@@ -16,6 +16,8 @@ func init() {
 
 type Options struct {
 	Path string `mapstructure:"path" validate:"required"`
+	// Size of the chunks the files content is split into, in bytes
+	ChunkSize int `mapstructure:"chunkSize" validate:"gte=0"`
 }
 
 func CreateFileSystemFromOptions(options any) (webdav.FileSystem, error) {
@@ -30,7 +32,13 @@ func CreateFileSystemFromOptions(options any) (webdav.FileSystem, error) {
 		return nil, errors.Wrap(err, "could not validate sqlite filesystem options")
 	}
 
-	fs := NewFileSystem(opts.Path)
+	funcs := []OptionFunc{}
+
+	if opts.ChunkSize > 0 {
+		funcs = append(funcs, WithChunkSize(opts.ChunkSize))
+	}
+
+	fs := NewFileSystem(opts.Path, funcs...)
 
 	return fs, nil
 }
@@ -0,0 +1,31 @@
+package sqlite
+
+// DefaultChunkSize is the default size of the chunks holding the files content
+const DefaultChunkSize = 256 * 1024
+
+type FileSystemOptions struct {
+	// ChunkSize is the size of the chunks the files content is split into,
+	// identical chunks being stored once. Changing it only applies to the
+	// content written afterwards.
+	ChunkSize int
+}
+
+type OptionFunc func(opts *FileSystemOptions)
+
+func WithChunkSize(size int) OptionFunc {
+	return func(opts *FileSystemOptions) {
+		opts.ChunkSize = size
+	}
+}
+
+func NewFileSystemOptions(funcs ...OptionFunc) *FileSystemOptions {
+	opts := &FileSystemOptions{
+		ChunkSize: DefaultChunkSize,
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
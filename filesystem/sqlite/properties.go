@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// GetProperties implements [filesystem.PropertiesFileSystem].
+func (f *FileSystem) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	name = cleanPath(name)
+
+	conn, err := f.pool.Take(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.pool.Put(conn)
+
+	props := make(map[xml.Name]webdav.Property)
+
+	err = sqlitex.Execute(conn, `
+		SELECT namespace, local, lang, inner_xml FROM properties
+		WHERE path = ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{name},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			xmlName := xml.Name{
+				Space: stmt.ColumnText(0),
+				Local: stmt.ColumnText(1),
+			}
+
+			innerXML := make([]byte, stmt.ColumnLen(3))
+			stmt.ColumnBytes(3, innerXML)
+
+			props[xmlName] = webdav.Property{
+				XMLName:  xmlName,
+				Lang:     stmt.ColumnText(2),
+				InnerXML: innerXML,
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return props, nil
+}
+
+// PatchProperties implements [filesystem.PropertiesFileSystem].
+func (f *FileSystem) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	name = cleanPath(name)
+
+	conn, err := f.pool.Take(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.pool.Put(conn)
+
+	err = withImmediate(conn, func() error {
+		for _, patch := range patches {
+			for _, prop := range patch.Props {
+				if patch.Remove {
+					err := sqlitex.Execute(conn, `
+						DELETE FROM properties
+						WHERE path = ? AND namespace = ? AND local = ?
+					`, &sqlitex.ExecOptions{
+						Args: []any{name, prop.XMLName.Space, prop.XMLName.Local},
+					})
+					if err != nil {
+						return errors.WithStack(err)
+					}
+
+					continue
+				}
+
+				err := sqlitex.Execute(conn, `
+					INSERT OR REPLACE INTO properties (path, namespace, local, lang, inner_xml)
+					VALUES (?, ?, ?, ?, ?)
+				`, &sqlitex.ExecOptions{
+					Args: []any{name, prop.XMLName.Space, prop.XMLName.Local, prop.Lang, prop.InnerXML},
+				})
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+var _ filesystem.PropertiesFileSystem = &FileSystem{}
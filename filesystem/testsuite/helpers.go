@@ -0,0 +1,78 @@
+package testsuite
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// WriteFileContent creates or replaces the named file with the given content,
+// failing the test on error.
+func WriteFileContent(t testing.TB, ctx context.Context, fs webdav.FileSystem, name string, content string) {
+	t.Helper()
+
+	file, err := fs.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := io.WriteString(file, content); err != nil {
+		file.Close()
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+}
+
+// ReadFileContent returns the content of the named file, failing the test on error.
+func ReadFileContent(t testing.TB, ctx context.Context, fs webdav.FileSystem, name string) string {
+	t.Helper()
+
+	file, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	return string(data)
+}
+
+// ReaddirNames returns the sorted names of the children of the named
+// directory, failing the test on error.
+func ReaddirNames(t testing.TB, ctx context.Context, fs webdav.FileSystem, name string) []string {
+	t.Helper()
+
+	dir, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer dir.Close()
+
+	children, err := dir.Readdir(-1)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	names := make([]string, 0, len(children))
+	for _, c := range children {
+		names = append(names, c.Name())
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	wd "golang.org/x/net/webdav"
+)
+
+// copyResponseWriter duplicates the dead properties of a copied collection
+// once the COPY request has succeeded, before the response status is sent.
+// The resources being already copied, a failure is logged without changing
+// the response status.
+type copyResponseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	copier      webdav.DeadPropsCopier
+	prefix      string
+	logger      Logger
+	wroteHeader bool
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (w *copyResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.wroteHeader = true
+
+	if status != http.StatusCreated && status != http.StatusNoContent {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if err := w.copyDeadProps(); err != nil && w.logger != nil {
+		w.logger(w.r, errors.Wrap(err, "could not copy dead properties"))
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements [http.ResponseWriter].
+func (w *copyResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *copyResponseWriter) copyDeadProps() error {
+	src, err := stripPrefix(w.r.URL.Path, w.prefix)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	destination, err := url.Parse(w.r.Header.Get("Destination"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	dst, err := stripPrefix(destination.Path, w.prefix)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Depth is either "0" or "infinity" (the default) for COPY requests
+	recursive := w.r.Header.Get("Depth") != "0"
+
+	// Collections are usually requested with a trailing slash
+	src, dst = path.Clean(src), path.Clean(dst)
+
+	if err := w.copier.CopyDeadProps(w.r.Context(), src, dst, recursive); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+var _ http.ResponseWriter = &copyResponseWriter{}
+
+func stripPrefix(p string, prefix string) (string, error) {
+	if prefix == "" {
+		return p, nil
+	}
+
+	if r := strings.TrimPrefix(p, prefix); len(r) < len(p) {
+		return r, nil
+	}
+
+	return p, errors.Errorf("path '%s' does not match prefix '%s'", p, prefix)
+}
+
+// copyFileSystem opens the files of a COPY request so that the content of
+// the copied files is duplicated by the filesystem rather than read and
+// written again by the webdav handler.
+type copyFileSystem struct {
+	wd.FileSystem
+	copier webdav.FileCopier
+}
+
+// OpenFile implements [wd.FileSystem].
+func (fs *copyFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (wd.File, error) {
+	file, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &copyFile{
+		File:   file,
+		ctx:    ctx,
+		name:   name,
+		copier: fs.copier,
+	}, nil
+}
+
+// copyFile is a file opened by a [copyFileSystem]. The webdav handler
+// transfers the content of a copied file with [io.Copy], which hands the
+// source to the destination through [io.ReaderFrom].
+type copyFile struct {
+	wd.File
+	ctx    context.Context
+	name   string
+	copier webdav.FileCopier
+}
+
+// ReadFrom implements [io.ReaderFrom].
+func (f *copyFile) ReadFrom(r io.Reader) (int64, error) {
+	if src, ok := r.(*copyFile); ok {
+		info, err := src.Stat()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		err = f.copier.CopyFile(f.ctx, src.name, f.name)
+		if err == nil {
+			return info.Size(), nil
+		}
+
+		if !errors.Is(err, filesystem.ErrNotSupported) {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	// The file is not wrapped to prevent io.Copy from calling ReadFrom again
+	return io.Copy(f.File, r)
+}
+
+// DeadProps implements [wd.DeadPropsHolder].
+func (f *copyFile) DeadProps() (map[xml.Name]wd.Property, error) {
+	holder, ok := f.File.(wd.DeadPropsHolder)
+	if !ok {
+		return nil, nil
+	}
+
+	return holder.DeadProps()
+}
+
+// Patch implements [wd.DeadPropsHolder].
+func (f *copyFile) Patch(patches []wd.Proppatch) ([]wd.Propstat, error) {
+	return webdav.PatchDeadProps(f.File, patches)
+}
+
+var (
+	_ wd.FileSystem      = &copyFileSystem{}
+	_ io.ReaderFrom      = &copyFile{}
+	_ wd.DeadPropsHolder = &copyFile{}
+)
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/bornholm/go-webdav/filesystem/sqlite"
+	"github.com/bornholm/go-webdav/filesystem/testsuite"
+	"github.com/bornholm/go-webdav/middleware/deadprops"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestCopyHiddenCollection(t *testing.T) {
+	ctx := context.Background()
+
+	backend := webdav.NewMemFS()
+
+	for _, name := range []string{"/dir", "/dir/public", "/dir/secret", "/dir/zzz"} {
+		if err := backend.Mkdir(ctx, name, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	store := &recordingStore{Store: deadprops.NewMemStore()}
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+
+	for _, name := range []string{"/dir", "/dir/public", "/dir/secret", "/dir/zzz"} {
+		if _, err := store.Patch(ctx, name, []webdav.Proppatch{
+			{Props: []webdav.Property{{XMLName: author, InnerXML: []byte(name)}}},
+		}); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	store.patched = nil
+
+	user := authz.NewUser(nil, []authz.Rule{&denySecretRule{}, &allowRule{}})
+
+	h := New(backend,
+		WithMiddlewares(
+			authz.Middleware(),
+			deadprops.Middleware(store),
+		),
+		WithUserResolver(func(r *http.Request) (authz.User, error) {
+			return user, nil
+		}),
+	)
+
+	req := httptest.NewRequest("COPY", "/dir/", nil)
+	req.Header.Set("Destination", "/copy/")
+	res := httptest.NewRecorder()
+
+	h.ServeHTTP(res, req)
+
+	if e, g := http.StatusCreated, res.Code; e != g {
+		t.Fatalf("res.Code: expected %v, got %v", e, g)
+	}
+
+	if _, err := backend.Stat(ctx, "/copy/secret"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat(/copy/secret): expected error '%v', got '%v'", os.ErrNotExist, err)
+	}
+
+	// No property is written for the hidden collection, and the ones of
+	// its siblings are still copied
+	for _, name := range store.patched {
+		if strings.HasPrefix(name, "/copy/secret") {
+			t.Errorf("unexpected properties written for '%s'", name)
+		}
+	}
+
+	expected := map[string]string{
+		"/copy":        "/dir",
+		"/copy/public": "/dir/public",
+		"/copy/zzz":    "/dir/zzz",
+	}
+
+	for name, value := range expected {
+		props, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := value, string(props[author].InnerXML); e != g {
+			t.Errorf("%s: expected author '%v', got '%v'", name, e, g)
+		}
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	ctx := context.Background()
+
+	backend := sqlite.NewFileSystem(filepath.Join(t.TempDir(), "webdav.db"))
+	defer backend.Close()
+
+	fs := &readCountingFileSystem{FileSystem: backend}
+
+	h := New(fs)
+
+	testsuite.WriteFileContent(t, ctx, backend, "/a.txt", "content")
+	testsuite.WriteFileContent(t, ctx, backend, "/c.txt", "previous content")
+
+	type testCase struct {
+		Destination    string
+		ExpectedStatus int
+	}
+
+	testCases := []testCase{
+		{Destination: "/b.txt", ExpectedStatus: http.StatusCreated},
+		{Destination: "/c.txt", ExpectedStatus: http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Destination, func(t *testing.T) {
+			req := httptest.NewRequest("COPY", "/a.txt", nil)
+			req.Header.Set("Destination", tc.Destination)
+			res := httptest.NewRecorder()
+
+			h.ServeHTTP(res, req)
+
+			if e, g := tc.ExpectedStatus, res.Code; e != g {
+				t.Errorf("res.Code: expected %v, got %v", e, g)
+			}
+
+			if e, g := 0, fs.reads; e != g {
+				t.Errorf("fs.reads: expected %d, got %d", e, g)
+			}
+
+			if e, g := "content", testsuite.ReadFileContent(t, ctx, backend, tc.Destination); e != g {
+				t.Errorf("content: expected '%s', got '%s'", e, g)
+			}
+		})
+	}
+}
+
+// denySecretRule denies every operation on the secret collections and
+// allows the other ones.
+type denySecretRule struct{}
+
+func (r *denySecretRule) Exec(env map[string]any) (bool, error) {
+	for _, key := range []string{"name", "oldName", "newName"} {
+		if name, _ := env[key].(string); strings.Contains(name, "/secret") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *denySecretRule) Effect() authz.Effect {
+	return authz.EffectDeny
+}
+
+// allowRule allows every operation.
+type allowRule struct{}
+
+func (r *allowRule) Exec(env map[string]any) (bool, error) {
+	return true, nil
+}
+
+// recordingStore records the names of the resources whose dead properties
+// are patched.
+type recordingStore struct {
+	deadprops.Store
+	patched []string
+}
+
+func (s *recordingStore) Patch(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	s.patched = append(s.patched, name)
+	return s.Store.Patch(ctx, name, patches)
+}
+
+// readCountingFileSystem counts the reads of the content of its files.
+type readCountingFileSystem struct {
+	*sqlite.FileSystem
+	reads int
+}
+
+func (fs *readCountingFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	file, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readCountingFile{File: file, fs: fs}, nil
+}
+
+type readCountingFile struct {
+	webdav.File
+	fs *readCountingFileSystem
+}
+
+func (f *readCountingFile) Read(p []byte) (int, error) {
+	f.fs.reads++
+	return f.File.Read(p)
+}
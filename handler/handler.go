@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/authz"
 	"github.com/bornholm/go-webdav/lock"
 	"github.com/bornholm/go-webdav/middleware/deadprops"
 	"github.com/pkg/errors"
@@ -14,11 +15,15 @@ import (
 
 type Logger func(r *http.Request, err error)
 
+// UserResolver returns the authenticated user of the given request.
+type UserResolver func(r *http.Request) (authz.User, error)
+
 type Options struct {
-	Prefix      string
-	Middlewares []webdav.Middleware
-	LockSystem  wd.LockSystem
-	Logger      Logger
+	Prefix       string
+	Middlewares  []webdav.Middleware
+	LockSystem   wd.LockSystem
+	Logger       Logger
+	UserResolver UserResolver
 }
 
 type OptionFunc func(opts *Options)
@@ -47,6 +52,15 @@ func WithLogger(logger Logger) OptionFunc {
 	}
 }
 
+// WithUserResolver injects the user returned by the given resolver in the
+// context of each request, as expected by the [authz] middleware.
+// Requests for which no user can be resolved are rejected with 401.
+func WithUserResolver(resolver UserResolver) OptionFunc {
+	return func(opts *Options) {
+		opts.UserResolver = resolver
+	}
+}
+
 func NewOptions(funcs ...OptionFunc) *Options {
 	opts := &Options{
 		Prefix: "",
@@ -69,11 +83,71 @@ func NewOptions(funcs ...OptionFunc) *Options {
 }
 
 type Handler struct {
-	webdav *wd.Handler
+	webdav      *wd.Handler
+	resolveUser UserResolver
 }
 
 // ServeHTTP implements [http.Handler].
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.resolveUser != nil {
+		user, err := h.resolveUser(r)
+		if err != nil || user == nil {
+			if err == nil {
+				err = errors.New("no user resolved")
+			}
+
+			h.writeError(w, r, http.StatusUnauthorized, errors.WithStack(err))
+			return
+		}
+
+		r = r.WithContext(authz.WithContextUser(r.Context(), user))
+	}
+
+	if r.Method == "LOCK" {
+		// The webdav handler only supports exclusive locks
+		if lockSystem, ok := h.webdav.LockSystem.(lock.ScopedLockSystem); ok {
+			if h.serveSharedLock(w, r, lockSystem) {
+				return
+			}
+		}
+	}
+
+	if r.Method == "MOVE" {
+		if checker, ok := h.webdav.FileSystem.(webdav.RenameChecker); ok {
+			if !h.checkMove(w, r, checker) {
+				return
+			}
+		}
+	}
+
+	if r.Method == "COPY" {
+		// The webdav handler copies the dead properties of the files along
+		// with their content, the ones of the collections have to be
+		// duplicated afterwards
+		if copier, ok := h.webdav.FileSystem.(webdav.DeadPropsCopier); ok {
+			w = &copyResponseWriter{
+				ResponseWriter: w,
+				r:              r,
+				copier:         copier,
+				prefix:         h.webdav.Prefix,
+				logger:         h.webdav.Logger,
+			}
+		}
+
+		// The content of the copied files is duplicated by the filesystem
+		// when supported
+		if copier, ok := h.webdav.FileSystem.(webdav.FileCopier); ok {
+			handler := *h.webdav
+			handler.FileSystem = &copyFileSystem{
+				FileSystem: h.webdav.FileSystem,
+				copier:     copier,
+			}
+
+			handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	h.webdav.ServeHTTP(w, r)
 }
 
@@ -88,7 +162,17 @@ func New(fs wd.FileSystem, funcs ...OptionFunc) *Handler {
 	}
 
 	return &Handler{
-		webdav: webdav,
+		webdav:      webdav,
+		resolveUser: opts.UserResolver,
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.WriteHeader(status)
+	w.Write([]byte(wd.StatusText(status)))
+
+	if h.webdav.Logger != nil {
+		h.webdav.Logger(r, err)
 	}
 }
 
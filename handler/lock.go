@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/pkg/errors"
+	wd "golang.org/x/net/webdav"
+)
+
+// maxLockInfoSize is the maximum size of the body of a LOCK request, an owner
+// being usually a short href.
+const maxLockInfoSize = 8 * 1024
+
+// lockInfo is the body of a LOCK request, see RFC 4918 section 14.11.
+type lockInfo struct {
+	XMLName   xml.Name  `xml:"lockinfo"`
+	Exclusive *struct{} `xml:"lockscope>exclusive"`
+	Shared    *struct{} `xml:"lockscope>shared"`
+	Write     *struct{} `xml:"locktype>write"`
+	Owner     struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"owner"`
+}
+
+// refreshIfHeader matches the If header of a lock refresh, made of a single
+// lock token, optionally tagged with the locked resource.
+var refreshIfHeader = regexp.MustCompile(`^\s*(?:<[^>]*>\s*)?\(\s*<([^>]+)>\s*\)\s*$`)
+
+// serveSharedLock handles LOCK requests asking for a shared lock, which the
+// webdav handler does not support, and the refreshes of the locks, which it
+// always reports as exclusive. It returns false if the request has to be
+// handled by the webdav handler instead, in which case its body is restored.
+func (h *Handler) serveSharedLock(w http.ResponseWriter, r *http.Request, lockSystem lock.ScopedLockSystem) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLockInfoSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, errors.WithStack(err))
+			return true
+		}
+
+		h.writeError(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return true
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var status int
+
+	// Empty bodies are lock refreshes
+	if len(bytes.TrimSpace(body)) == 0 {
+		match := refreshIfHeader.FindStringSubmatch(r.Header.Get("If"))
+		if match == nil {
+			return false
+		}
+
+		status, err = h.refreshLock(w, r, lockSystem, match[1])
+	} else {
+		var li lockInfo
+		if err := xml.Unmarshal(body, &li); err != nil || li.Shared == nil || li.Exclusive != nil || li.Write == nil {
+			return false
+		}
+
+		status, err = h.createSharedLock(w, r, lockSystem, li)
+	}
+
+	if status != 0 {
+		h.writeError(w, r, status, err)
+		return true
+	}
+
+	if h.webdav.Logger != nil {
+		h.webdav.Logger(r, err)
+	}
+
+	return true
+}
+
+func (h *Handler) createSharedLock(w http.ResponseWriter, r *http.Request, lockSystem lock.ScopedLockSystem, li lockInfo) (int, error) {
+	duration, err := parseTimeout(r.Header.Get("Timeout"))
+	if err != nil {
+		return http.StatusBadRequest, errors.WithStack(err)
+	}
+
+	// A LOCK request without Depth header acts as if "Depth: infinity" was submitted
+	zeroDepth := false
+	switch depth := r.Header.Get("Depth"); depth {
+	case "", "infinity":
+	case "0":
+		zeroDepth = true
+	default:
+		return http.StatusBadRequest, errors.Errorf("invalid depth '%s'", depth)
+	}
+
+	reqPath, err := stripPrefix(r.URL.Path, h.webdav.Prefix)
+	if err != nil {
+		return http.StatusNotFound, errors.WithStack(err)
+	}
+
+	details := wd.LockDetails{
+		Root:      reqPath,
+		Duration:  duration,
+		OwnerXML:  li.Owner.InnerXML,
+		ZeroDepth: zeroDepth,
+	}
+
+	now := time.Now()
+
+	token, err := lockSystem.CreateWithScope(now, details, lock.ScopeShared)
+	if err != nil {
+		if errors.Is(err, wd.ErrLocked) {
+			return wd.StatusLocked, err
+		}
+
+		return http.StatusInternalServerError, errors.WithStack(err)
+	}
+
+	ctx := r.Context()
+
+	// Create the resource if it does not exist yet
+	created := false
+	if _, err := h.webdav.FileSystem.Stat(ctx, reqPath); err != nil {
+		f, err := h.webdav.FileSystem.OpenFile(ctx, reqPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			_ = lockSystem.Unlock(now, token)
+			return http.StatusInternalServerError, errors.WithStack(err)
+		}
+
+		if err := f.Close(); err != nil {
+			_ = lockSystem.Unlock(now, token)
+			return http.StatusInternalServerError, errors.WithStack(err)
+		}
+
+		created = true
+	}
+
+	w.Header().Set("Lock-Token", "<"+token+">")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	if err := writeLockInfo(w, token, details, lock.ScopeShared); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return 0, nil
+}
+
+func (h *Handler) refreshLock(w http.ResponseWriter, r *http.Request, lockSystem lock.ScopedLockSystem, token string) (int, error) {
+	duration, err := parseTimeout(r.Header.Get("Timeout"))
+	if err != nil {
+		return http.StatusBadRequest, errors.WithStack(err)
+	}
+
+	details, scope, err := lockSystem.RefreshWithScope(time.Now(), token, duration)
+	if err != nil {
+		if errors.Is(err, wd.ErrNoSuchLock) {
+			return http.StatusPreconditionFailed, err
+		}
+
+		return http.StatusInternalServerError, errors.WithStack(err)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	if err := writeLockInfo(w, token, details, scope); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return 0, nil
+}
+
+func writeLockInfo(w io.Writer, token string, details wd.LockDetails, scope lock.Scope) error {
+	depth := "infinity"
+	if details.ZeroDepth {
+		depth = "0"
+	}
+
+	timeout := "Infinite"
+	if details.Duration >= 0 {
+		timeout = "Second-" + strconv.FormatInt(int64(details.Duration/time.Second), 10)
+	}
+
+	_, err := fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n"+
+		"<D:prop xmlns:D=\"DAV:\"><D:lockdiscovery><D:activelock>\n"+
+		"	<D:locktype><D:write/></D:locktype>\n"+
+		"	<D:lockscope><D:%s/></D:lockscope>\n"+
+		"	<D:depth>%s</D:depth>\n"+
+		"	<D:owner>%s</D:owner>\n"+
+		"	<D:timeout>%s</D:timeout>\n"+
+		"	<D:locktoken><D:href>%s</D:href></D:locktoken>\n"+
+		"	<D:lockroot><D:href>%s</D:href></D:lockroot>\n"+
+		"</D:activelock></D:lockdiscovery></D:prop>",
+		scope, depth, details.OwnerXML, timeout, escape(token), escape(details.Root),
+	)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// parseTimeout parses the Timeout header of a LOCK request, following the
+// same rules as the webdav handler. A negative duration means an infinite timeout.
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return -1, nil
+	}
+
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "Infinite" {
+		return -1, nil
+	}
+
+	const prefix = "Second-"
+	if !strings.HasPrefix(s, prefix) {
+		return 0, errors.Errorf("invalid timeout '%s'", s)
+	}
+
+	s = s[len(prefix):]
+	if s == "" || s[0] < '0' || '9' < s[0] {
+		return 0, errors.Errorf("invalid timeout '%s'", s)
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || 1<<32-1 < n {
+		return 0, errors.Errorf("invalid timeout '%s'", s)
+	}
+
+	return time.Duration(n) * time.Second, nil
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		return s
+	}
+
+	return b.String()
+}
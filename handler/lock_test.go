@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/go-webdav/filesystem/testsuite"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestLockBodyTooLarge(t *testing.T) {
+	h := New(webdav.NewMemFS())
+
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+	<D:lockscope><D:shared/></D:lockscope>
+	<D:locktype><D:write/></D:locktype>
+	<D:owner>` + strings.Repeat("a", maxLockInfoSize) + `</D:owner>
+</D:lockinfo>`
+
+	req := httptest.NewRequest("LOCK", "/file.txt", strings.NewReader(body))
+	res := httptest.NewRecorder()
+
+	h.ServeHTTP(res, req)
+
+	if e, g := http.StatusRequestEntityTooLarge, res.Code; e != g {
+		t.Errorf("res.Code: expected %v, got %v", e, g)
+	}
+}
+
+func TestSharedLock(t *testing.T) {
+	ctx := context.Background()
+
+	fs := webdav.NewMemFS()
+	testsuite.WriteFileContent(t, ctx, fs, "/file.txt", "content")
+
+	h := New(fs)
+
+	res := serveLock(h, "/file.txt", lockInfoBody("shared", "alice"), map[string]string{"Timeout": "Second-3600"})
+
+	if e, g := http.StatusOK, res.Code; e != g {
+		t.Fatalf("res.Code: expected %v, got %v", e, g)
+	}
+
+	token := strings.Trim(res.Header().Get("Lock-Token"), "<>")
+	if token == "" {
+		t.Fatalf("expected a Lock-Token header")
+	}
+
+	active := parseActiveLock(t, res)
+
+	if active.Shared == nil || active.Exclusive != nil {
+		t.Errorf("expected a shared lock scope")
+	}
+
+	if e, g := "infinity", active.Depth; e != g {
+		t.Errorf("active.Depth: expected %v, got %v", e, g)
+	}
+
+	if e, g := "Second-3600", active.Timeout; e != g {
+		t.Errorf("active.Timeout: expected %v, got %v", e, g)
+	}
+
+	if e, g := token, active.Token; e != g {
+		t.Errorf("active.Token: expected %v, got %v", e, g)
+	}
+
+	if e, g := "/file.txt", active.Root; e != g {
+		t.Errorf("active.Root: expected %v, got %v", e, g)
+	}
+
+	if e, g := "<D:href>alice</D:href>", active.Owner.InnerXML; e != g {
+		t.Errorf("active.Owner: expected %v, got %v", e, g)
+	}
+
+	// Shared locks coexist with each other
+	res = serveLock(h, "/file.txt", lockInfoBody("shared", "bob"), nil)
+
+	if e, g := http.StatusOK, res.Code; e != g {
+		t.Fatalf("res.Code: expected %v, got %v", e, g)
+	}
+
+	if other := strings.Trim(res.Header().Get("Lock-Token"), "<>"); other == "" || other == token {
+		t.Errorf("expected another lock token, got '%s'", other)
+	}
+
+	// But not with an exclusive one
+	res = serveLock(h, "/file.txt", lockInfoBody("exclusive", "carol"), nil)
+
+	if e, g := webdav.StatusLocked, res.Code; e != g {
+		t.Errorf("res.Code: expected %v, got %v", e, g)
+	}
+
+	// Refreshing a shared lock keeps its scope
+	res = serveLock(h, "/file.txt", "", map[string]string{"If": "(<" + token + ">)", "Timeout": "Second-60"})
+
+	if e, g := http.StatusOK, res.Code; e != g {
+		t.Fatalf("res.Code: expected %v, got %v", e, g)
+	}
+
+	active = parseActiveLock(t, res)
+
+	if active.Shared == nil || active.Exclusive != nil {
+		t.Errorf("expected a shared lock scope")
+	}
+
+	if e, g := "Second-60", active.Timeout; e != g {
+		t.Errorf("active.Timeout: expected %v, got %v", e, g)
+	}
+
+	if e, g := token, active.Token; e != g {
+		t.Errorf("active.Token: expected %v, got %v", e, g)
+	}
+
+	res = serveLock(h, "/file.txt", "", map[string]string{"If": "(<urn:uuid:unknown>)"})
+
+	if e, g := http.StatusPreconditionFailed, res.Code; e != g {
+		t.Errorf("res.Code: expected %v, got %v", e, g)
+	}
+}
+
+func TestSharedLockCreate(t *testing.T) {
+	ctx := context.Background()
+
+	fs := webdav.NewMemFS()
+	h := New(fs)
+
+	res := serveLock(h, "/new.txt", lockInfoBody("shared", "alice"), map[string]string{"Depth": "0"})
+
+	if e, g := http.StatusCreated, res.Code; e != g {
+		t.Fatalf("res.Code: expected %v, got %v", e, g)
+	}
+
+	active := parseActiveLock(t, res)
+
+	if e, g := "0", active.Depth; e != g {
+		t.Errorf("active.Depth: expected %v, got %v", e, g)
+	}
+
+	if e, g := "Infinite", active.Timeout; e != g {
+		t.Errorf("active.Timeout: expected %v, got %v", e, g)
+	}
+
+	if _, err := fs.Stat(ctx, "/new.txt"); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+}
+
+// activeLock is the lock discovery returned by a LOCK request.
+type activeLock struct {
+	XMLName   xml.Name  `xml:"DAV: prop"`
+	Shared    *struct{} `xml:"DAV: lockdiscovery>activelock>lockscope>shared"`
+	Exclusive *struct{} `xml:"DAV: lockdiscovery>activelock>lockscope>exclusive"`
+	Depth     string    `xml:"DAV: lockdiscovery>activelock>depth"`
+	Owner     struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"DAV: lockdiscovery>activelock>owner"`
+	Timeout string `xml:"DAV: lockdiscovery>activelock>timeout"`
+	Token   string `xml:"DAV: lockdiscovery>activelock>locktoken>href"`
+	Root    string `xml:"DAV: lockdiscovery>activelock>lockroot>href"`
+}
+
+func parseActiveLock(t *testing.T, res *httptest.ResponseRecorder) activeLock {
+	var active activeLock
+	if err := xml.Unmarshal(res.Body.Bytes(), &active); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	return active
+}
+
+func lockInfoBody(scope string, owner string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+	<D:lockscope><D:` + scope + `/></D:lockscope>
+	<D:locktype><D:write/></D:locktype>
+	<D:owner><D:href>` + owner + `</D:href></D:owner>
+</D:lockinfo>`
+}
+
+func serveLock(h http.Handler, name string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("LOCK", name, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res := httptest.NewRecorder()
+
+	h.ServeHTTP(res, req)
+
+	return res
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"syscall"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+)
+
+// checkMove rejects the MOVE requests the filesystem is not able to perform,
+// i.e. across the mounts of a mount table, with 502 (Bad Gateway) following
+// RFC 4918 section 9.9.4. The webdav handler would otherwise overwrite the
+// destination before failing to rename the resource.
+// It returns false if the request has been rejected.
+func (h *Handler) checkMove(w http.ResponseWriter, r *http.Request, checker webdav.RenameChecker) bool {
+	src, err := stripPrefix(r.URL.Path, h.webdav.Prefix)
+	if err != nil {
+		// Let the webdav handler reject the request
+		return true
+	}
+
+	destination, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil {
+		return true
+	}
+
+	dst, err := stripPrefix(destination.Path, h.webdav.Prefix)
+	if err != nil {
+		return true
+	}
+
+	if err := checker.CheckRename(r.Context(), src, dst); err != nil && errors.Is(err, syscall.EXDEV) {
+		h.writeError(w, r, http.StatusBadGateway, errors.WithStack(err))
+		return false
+	}
+
+	return true
+}
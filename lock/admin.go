@@ -0,0 +1,104 @@
+package lock
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Locks returns the active locks, sorted by root path.
+func (s *System) Locks(now time.Time) ([]*LockNode, error) {
+	nodes, err := s.store.ListLocks()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	nodes = slices.DeleteFunc(nodes, func(node *LockNode) bool {
+		return isExpired(node, now)
+	})
+
+	slices.SortFunc(nodes, func(a, b *LockNode) int {
+		if c := strings.Compare(a.Details.Root, b.Details.Root); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Token, b.Token)
+	})
+
+	return nodes, nil
+}
+
+// Lock returns the active lock identified by the given token.
+// It returns [ErrLockNotFound] if the lock does not exist or has expired.
+func (s *System) Lock(now time.Time, token string) (*LockNode, error) {
+	token = strings.TrimPrefix(token, "<")
+	token = strings.TrimSuffix(token, ">")
+
+	node, err := s.store.GetLock(token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if isExpired(node, now) {
+		return nil, errors.WithStack(ErrLockNotFound)
+	}
+
+	return node, nil
+}
+
+// Break forcibly releases the lock identified by the given token,
+// regardless of its owner.
+// It returns [ErrLockNotFound] if the lock does not exist.
+func (s *System) Break(token string) error {
+	token = strings.TrimPrefix(token, "<")
+	token = strings.TrimSuffix(token, ">")
+
+	if err := s.store.RemoveLock(token); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Sweep removes the locks expired at the given time and returns their count.
+func (s *System) Sweep(now time.Time) (int, error) {
+	removed, err := s.store.RemoveExpired(now)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return removed, nil
+}
+
+// StartSweeper periodically removes expired locks in a background goroutine
+// until the given context is canceled.
+func (s *System) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				removed, err := s.Sweep(now)
+				if err != nil {
+					slog.ErrorContext(ctx, "could not sweep expired locks", slog.Any("error", errors.WithStack(err)))
+					continue
+				}
+
+				if removed > 0 {
+					slog.DebugContext(ctx, "expired locks swept", slog.Int("removed", removed))
+				}
+			}
+		}
+	}()
+}
+
+func isExpired(node *LockNode, now time.Time) bool {
+	return !node.Expiry.IsZero() && now.After(node.Expiry)
+}
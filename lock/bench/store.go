@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/lock"
+	"golang.org/x/net/webdav"
+)
+
+type storeBenchmark struct {
+	Name string
+	Run  func(b *testing.B, store lock.Store)
+}
+
+// totalLocks is the number of locks held by the store during lookups
+const totalLocks = 5000
+
+var storeBenchmarks = []storeBenchmark{
+	{
+		Name: "GetLocksByPath_5000Locks",
+		Run: func(b *testing.B, store lock.Store) {
+			seedLocks(b, store, "/bench_lookup", totalLocks)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				path := fmt.Sprintf("/bench_lookup/dir_%d/sub/file.txt", i%totalLocks)
+
+				locks, err := store.GetLocksByPath(path)
+				if err != nil {
+					b.Fatalf("%+v", err)
+				}
+
+				if len(locks) != 1 {
+					b.Fatalf("expected 1 lock, got %d", len(locks))
+				}
+			}
+		},
+	},
+	{
+		Name: "GetLocksByPath_Unlocked",
+		Run: func(b *testing.B, store lock.Store) {
+			seedLocks(b, store, "/bench_unlocked", totalLocks)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				path := fmt.Sprintf("/bench_free/dir_%d/a/b/c/d/e/f/file.txt", i%totalLocks)
+
+				if _, err := store.GetLocksByPath(path); err != nil {
+					b.Fatalf("%+v", err)
+				}
+			}
+		},
+	},
+	{
+		Name: "Confirm_5000Locks",
+		Run: func(b *testing.B, store lock.Store) {
+			tokens := seedLocks(b, store, "/bench_confirm", totalLocks)
+
+			system := lock.NewSystem(store)
+			now := time.Now()
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				idx := i % totalLocks
+				path := fmt.Sprintf("/bench_confirm/dir_%d/file.txt", idx)
+
+				release, err := system.Confirm(now, path, "", webdav.Condition{Token: tokens[idx]})
+				if err != nil {
+					b.Fatalf("%+v", err)
+				}
+
+				release()
+			}
+		},
+	},
+	{
+		Name: "ApplyRemoveLock",
+		Run: func(b *testing.B, store lock.Store) {
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				node := &lock.LockNode{
+					Token: fmt.Sprintf("urn:uuid:bench-apply-%d", i),
+					Details: webdav.LockDetails{
+						Root:     fmt.Sprintf("/bench_apply/dir_%d/file.txt", i),
+						Duration: time.Hour,
+					},
+					Expiry: time.Now().Add(time.Hour),
+				}
+
+				if err := store.ApplyLock(node, node.Details.Root); err != nil {
+					b.Fatalf("%+v", err)
+				}
+
+				if err := store.RemoveLock(node.Token); err != nil {
+					b.Fatalf("%+v", err)
+				}
+			}
+		},
+	},
+}
+
+// seedLocks applies total depth-infinity locks under the given directory.
+// Tokens are deterministic so that seeding the same store again is idempotent.
+func seedLocks(b *testing.B, store lock.Store, dir string, total int) []string {
+	tokens := make([]string, total)
+	expiry := time.Now().Add(time.Hour)
+
+	for i := 0; i < total; i++ {
+		node := &lock.LockNode{
+			Token: fmt.Sprintf("urn:uuid:bench%s-%d", dir, i),
+			Details: webdav.LockDetails{
+				Root:     fmt.Sprintf("%s/dir_%d", dir, i),
+				Duration: time.Hour,
+			},
+			Expiry: expiry,
+		}
+
+		if err := store.ApplyLock(node, node.Details.Root); err != nil {
+			b.Fatalf("%+v", err)
+		}
+
+		tokens[i] = node.Token
+	}
+
+	return tokens
+}
+
+func RunTestSuite(b *testing.B, store lock.Store) {
+	for _, bc := range storeBenchmarks {
+		b.Run(bc.Name, func(b *testing.B) {
+			bc.Run(b, store)
+		})
+	}
+}
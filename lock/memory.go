@@ -1,15 +1,32 @@
 package lock
 
 import (
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// MemoryStore is an in-memory lock store.
+// Locks are indexed in a path trie so that lookups scale with the depth
+// of the requested path rather than with the number of locks.
 type MemoryStore struct {
 	mu    sync.RWMutex
-	locks map[string]LockNode        // map[token]lockNode
-	paths map[string]map[string]bool // map[path]map[token]bool (index)
+	locks map[string]memoryEntry // map[token]memoryEntry
+	index *pathNode
+}
+
+type memoryEntry struct {
+	node  LockNode
+	paths []string // Paths the lock is indexed on
+}
+
+// pathNode is a node of the locks path trie.
+type pathNode struct {
+	children map[string]*pathNode
+	rooted   map[string]struct{} // Locks rooted on this path
+	indexed  map[string]struct{} // Locks explicitly indexed on this path
 }
 
 // GetLock implements [Store].
@@ -17,11 +34,13 @@ func (m *MemoryStore) GetLock(token string) (*LockNode, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	node, ok := m.locks[token]
+	entry, ok := m.locks[token]
 	if !ok {
 		return nil, errors.WithStack(ErrLockNotFound)
 	}
 
+	node := entry.node
+
 	return &node, nil
 }
 
@@ -32,42 +51,43 @@ func (m *MemoryStore) GetLocksByPath(path string) ([]*LockNode, error) {
 
 	var result []*LockNode
 
-	// Check exact path match
-	if tokens, ok := m.paths[path]; ok {
-		for token := range tokens {
-			if node, ok := m.locks[token]; ok {
-				nodeCopy := node
-				result = append(result, &nodeCopy)
-			}
+	seen := make(map[string]struct{})
+	add := func(token string) {
+		if _, exists := seen[token]; exists {
+			return
 		}
-	}
 
-	// Check parent paths for depth-infinity locks
-	for token, node := range m.locks {
-		if node.Details.ZeroDepth {
-			continue // Skip zero-depth locks
+		entry, ok := m.locks[token]
+		if !ok {
+			return
 		}
 
-		root := node.Details.Root
-		if root == path {
-			continue // Already added above
-		}
+		seen[token] = struct{}{}
 
-		// Check if path is a child of the lock root
-		if isChildOf(path, root) {
-			// Check if we already have this token
-			found := false
-			for _, existing := range result {
-				if existing.Token == token {
-					found = true
-					break
-				}
-			}
-			if !found {
-				nodeCopy := node
-				result = append(result, &nodeCopy)
+		node := entry.node
+		result = append(result, &node)
+	}
+
+	segments := splitPath(path)
+	current := m.index
+
+	// Depth-infinity locks rooted on an ancestor
+	for _, segment := range segments {
+		for token := range current.rooted {
+			if !m.locks[token].node.Details.ZeroDepth {
+				add(token)
 			}
 		}
+
+		current = current.children[segment]
+		if current == nil {
+			return result, nil
+		}
+	}
+
+	// Locks applied on the path itself
+	for token := range current.indexed {
+		add(token)
 	}
 
 	return result, nil
@@ -78,13 +98,22 @@ func (m *MemoryStore) ApplyLock(node *LockNode, paths ...string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.locks[node.Token] = *node
+	// Refreshed locks are reindexed
+	if entry, exists := m.locks[node.Token]; exists {
+		m.unindex(entry)
+	}
+
+	entry := memoryEntry{
+		node:  *node,
+		paths: append([]string(nil), paths...),
+	}
+
+	m.locks[node.Token] = entry
+
+	m.lookup(node.Details.Root, true).rooted[node.Token] = struct{}{}
 
 	for _, p := range paths {
-		if m.paths[p] == nil {
-			m.paths[p] = make(map[string]bool)
-		}
-		m.paths[p][node.Token] = true
+		m.lookup(p, true).indexed[node.Token] = struct{}{}
 	}
 
 	return nil
@@ -95,29 +124,147 @@ func (m *MemoryStore) RemoveLock(token string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	node, ok := m.locks[token]
+	entry, ok := m.locks[token]
 	if !ok {
 		return ErrLockNotFound
 	}
 
-	delete(m.locks, token)
+	m.removeLock(entry)
+
+	return nil
+}
+
+// ListLocks implements [Store].
+func (m *MemoryStore) ListLocks() ([]*LockNode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*LockNode, 0, len(m.locks))
+	for _, entry := range m.locks {
+		node := entry.node
+		result = append(result, &node)
+	}
 
-	root := node.Details.Root
-	if tokens, ok := m.paths[root]; ok {
-		delete(tokens, token)
-		if len(tokens) == 0 {
-			delete(m.paths, root)
+	return result, nil
+}
+
+// RemoveExpired implements [Store].
+func (m *MemoryStore) RemoveExpired(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for _, entry := range m.locks {
+		if entry.node.Expiry.IsZero() || !now.After(entry.node.Expiry) {
+			continue
 		}
+
+		m.removeLock(entry)
+		removed++
 	}
 
-	return nil
+	return removed, nil
+}
+
+func (m *MemoryStore) removeLock(entry memoryEntry) {
+	delete(m.locks, entry.node.Token)
+	m.unindex(entry)
+}
+
+// unindex removes the given lock from every trie node it is referenced by,
+// pruning the nodes left empty.
+func (m *MemoryStore) unindex(entry memoryEntry) {
+	token := entry.node.Token
+
+	if n := m.lookup(entry.node.Details.Root, false); n != nil {
+		delete(n.rooted, token)
+	}
+
+	for _, p := range entry.paths {
+		if n := m.lookup(p, false); n != nil {
+			delete(n.indexed, token)
+		}
+	}
+
+	m.prune(entry.node.Details.Root)
+
+	for _, p := range entry.paths {
+		m.prune(p)
+	}
+}
+
+// lookup returns the trie node of the given path, creating it
+// and its ancestors if create is true.
+func (m *MemoryStore) lookup(path string, create bool) *pathNode {
+	current := m.index
+
+	for _, segment := range splitPath(path) {
+		next, exists := current.children[segment]
+		if !exists {
+			if !create {
+				return nil
+			}
+
+			next = newPathNode()
+			current.children[segment] = next
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+// prune removes the empty trie nodes along the given path.
+func (m *MemoryStore) prune(path string) {
+	segments := splitPath(path)
+
+	nodes := make([]*pathNode, 0, len(segments)+1)
+	nodes = append(nodes, m.index)
+
+	current := m.index
+	for _, segment := range segments {
+		current = current.children[segment]
+		if current == nil {
+			return
+		}
+
+		nodes = append(nodes, current)
+	}
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		n := nodes[i+1]
+		if len(n.children) > 0 || len(n.rooted) > 0 || len(n.indexed) > 0 {
+			return
+		}
+
+		delete(nodes[i].children, segments[i])
+	}
 }
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		locks: make(map[string]LockNode),
-		paths: make(map[string]map[string]bool),
+		locks: make(map[string]memoryEntry),
+		index: newPathNode(),
 	}
 }
 
 var _ Store = &MemoryStore{}
+
+func newPathNode() *pathNode {
+	return &pathNode{
+		children: make(map[string]*pathNode),
+		rooted:   make(map[string]struct{}),
+		indexed:  make(map[string]struct{}),
+	}
+}
+
+// splitPath returns the segments of the given path, "/" having none.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
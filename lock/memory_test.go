@@ -0,0 +1,13 @@
+package lock_test
+
+import (
+	"testing"
+
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/bornholm/go-webdav/lock/bench"
+)
+
+func BenchmarkMemoryStore(b *testing.B) {
+	store := lock.NewMemoryStore()
+	bench.RunTestSuite(b, store)
+}
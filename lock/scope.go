@@ -0,0 +1,57 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// Scope is the scope of a lock, as defined in RFC 4918 section 6.1.
+type Scope int
+
+const (
+	// ScopeExclusive locks can not be shared with any other lock.
+	ScopeExclusive Scope = iota
+	// ScopeShared locks can coexist with other shared locks.
+	ScopeShared
+)
+
+// String implements [fmt.Stringer].
+func (s Scope) String() string {
+	switch s {
+	case ScopeShared:
+		return "shared"
+	default:
+		return "exclusive"
+	}
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (s Scope) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (s *Scope) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "exclusive":
+		*s = ScopeExclusive
+	case "shared":
+		*s = ScopeShared
+	default:
+		return errors.Errorf("invalid lock scope '%s'", text)
+	}
+
+	return nil
+}
+
+// ScopedLockSystem is a [webdav.LockSystem] able to create locks
+// with a scope other than exclusive.
+type ScopedLockSystem interface {
+	webdav.LockSystem
+	CreateWithScope(now time.Time, details webdav.LockDetails, scope Scope) (string, error)
+	// RefreshWithScope refreshes the lock as Refresh does, and also returns
+	// its scope.
+	RefreshWithScope(now time.Time, token string, duration time.Duration) (webdav.LockDetails, Scope, error)
+}
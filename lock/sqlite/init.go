@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pkg/errors"
+)
+
+const Type = "sqlite"
+
+type Options struct {
+	Path string `mapstructure:"path" validate:"required"`
+}
+
+func CreateStoreFromOptions(options any) (*Store, error) {
+	opts := Options{}
+
+	if err := mapstructure.Decode(options, &opts); err != nil {
+		return nil, errors.Wrapf(err, "could not parse '%s' lock store options", Type)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(&opts); err != nil {
+		return nil, errors.Wrap(err, "could not validate sqlite lock store options")
+	}
+
+	store := NewStore(opts.Path)
+
+	return store, nil
+}
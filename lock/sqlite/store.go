@@ -0,0 +1,406 @@
+package sqlite
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitemigration"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Store is a persistent lock store backed by a SQLite database.
+// Locks survive server restarts until they expire. Expired locks are returned
+// as the other ones, the [lock.System] deciding of their expiry, until they
+// are removed by [Store.RemoveExpired].
+type Store struct {
+	pool *sqlitemigration.Pool
+}
+
+// GetLock implements [lock.Store].
+func (s *Store) GetLock(token string) (*lock.LockNode, error) {
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	var node *lock.LockNode
+
+	err = sqlitex.Execute(conn, `
+		SELECT token, root, zero_depth, owner_xml, duration, expiry, scope FROM locks
+		WHERE token = ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{token},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			n, err := scanLockNode(stmt)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			node = n
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if node == nil {
+		return nil, errors.WithStack(lock.ErrLockNotFound)
+	}
+
+	return node, nil
+}
+
+// GetLocksByPath implements [lock.Store].
+func (s *Store) GetLocksByPath(name string) ([]*lock.LockNode, error) {
+	name = clean(name)
+
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	ancestors := ancestorsOf(name)
+
+	args := make([]any, 0, len(ancestors)+1)
+	args = append(args, name)
+
+	// Locks applied on the path itself, whatever their depth
+	query := `
+		SELECT token, root, zero_depth, owner_xml, duration, expiry, scope FROM locks
+		WHERE token IN (SELECT token FROM lock_paths WHERE path = ?)
+	`
+
+	// Depth-infinity locks rooted on one of its ancestors
+	if len(ancestors) > 0 {
+		query += `
+			UNION
+			SELECT token, root, zero_depth, owner_xml, duration, expiry, scope FROM locks
+			WHERE root IN (` + placeholders(len(ancestors)) + `) AND zero_depth = 0
+		`
+
+		for _, a := range ancestors {
+			args = append(args, a)
+		}
+	}
+
+	var nodes []*lock.LockNode
+
+	err = sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+		Args: args,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			node, err := scanLockNode(stmt)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			nodes = append(nodes, node)
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return nodes, nil
+}
+
+// ApplyLock implements [lock.Store].
+func (s *Store) ApplyLock(node *lock.LockNode, paths ...string) error {
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	var expiry int64
+	if !node.Expiry.IsZero() {
+		expiry = node.Expiry.UnixNano()
+	}
+
+	err = withImmediate(conn, func() error {
+		err := sqlitex.Execute(conn, `
+			INSERT OR REPLACE INTO locks (token, root, zero_depth, owner_xml, duration, expiry, scope)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, &sqlitex.ExecOptions{
+			Args: []any{
+				node.Token,
+				clean(node.Details.Root),
+				node.Details.ZeroDepth,
+				node.Details.OwnerXML,
+				int64(node.Details.Duration),
+				expiry,
+				node.Scope.String(),
+			},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, p := range paths {
+			err := sqlitex.Execute(conn, `
+				INSERT OR IGNORE INTO lock_paths (path, token) VALUES (?, ?)
+			`, &sqlitex.ExecOptions{
+				Args: []any{clean(p), node.Token},
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// RemoveLock implements [lock.Store].
+func (s *Store) RemoveLock(token string) error {
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	err = withImmediate(conn, func() error {
+		err := sqlitex.Execute(conn, `DELETE FROM locks WHERE token = ?`, &sqlitex.ExecOptions{
+			Args: []any{token},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if conn.Changes() == 0 {
+			return errors.WithStack(lock.ErrLockNotFound)
+		}
+
+		err = sqlitex.Execute(conn, `DELETE FROM lock_paths WHERE token = ?`, &sqlitex.ExecOptions{
+			Args: []any{token},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// ListLocks implements [lock.Store].
+func (s *Store) ListLocks() ([]*lock.LockNode, error) {
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	var nodes []*lock.LockNode
+
+	err = sqlitex.Execute(conn, `
+		SELECT token, root, zero_depth, owner_xml, duration, expiry, scope FROM locks
+		ORDER BY root, token
+	`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			node, err := scanLockNode(stmt)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			nodes = append(nodes, node)
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return nodes, nil
+}
+
+// RemoveExpired implements [lock.Store].
+func (s *Store) RemoveExpired(now time.Time) (int, error) {
+	conn, err := s.pool.Take(context.Background())
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	var removed int
+
+	err = withImmediate(conn, func() error {
+		n, err := deleteExpired(conn, now)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		removed = n
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return removed, nil
+}
+
+// Close releases the underlying database connections.
+func (s *Store) Close() error {
+	if err := s.pool.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// NewStore creates a new lock store persisted in the SQLite database at dbPath.
+func NewStore(dbPath string) *Store {
+	schema := sqlitemigration.Schema{
+		Migrations: []string{
+			`CREATE TABLE IF NOT EXISTS locks (
+					token TEXT PRIMARY KEY,                -- Lock token
+					root TEXT NOT NULL,                    -- Locked resource path
+					zero_depth BOOLEAN NOT NULL DEFAULT 0, -- Depth 0 lock
+					owner_xml TEXT NOT NULL DEFAULT '',    -- Lock owner raw XML
+					duration INTEGER NOT NULL DEFAULT 0,   -- Lock timeout, in nanoseconds
+					expiry INTEGER NOT NULL DEFAULT 0      -- Expiry, as unix nanoseconds (0 means never)
+				);
+				CREATE INDEX IF NOT EXISTS idx_locks_root ON locks(root);
+				CREATE INDEX IF NOT EXISTS idx_locks_expiry ON locks(expiry);
+
+				CREATE TABLE IF NOT EXISTS lock_paths (
+					path TEXT NOT NULL,  -- Indexed resource path
+					token TEXT NOT NULL, -- Lock token
+					PRIMARY KEY (path, token)
+				);
+				CREATE INDEX IF NOT EXISTS idx_lock_paths_token ON lock_paths(token);
+			`,
+			`ALTER TABLE locks ADD COLUMN scope TEXT NOT NULL DEFAULT 'exclusive'; -- Lock scope (exclusive or shared)`,
+		},
+	}
+
+	pool := sqlitemigration.NewPool(dbPath, schema, sqlitemigration.Options{
+		Flags: sqlite.OpenCreate | sqlite.OpenReadWrite | sqlite.OpenWAL,
+		PrepareConn: func(conn *sqlite.Conn) error {
+			return sqlitex.ExecScript(conn, `PRAGMA busy_timeout = 5000;`)
+		},
+		OnError: func(e error) {
+			log.Printf("%+v", e)
+		},
+	})
+
+	return &Store{
+		pool: pool,
+	}
+}
+
+var _ lock.Store = &Store{}
+
+func scanLockNode(stmt *sqlite.Stmt) (*lock.LockNode, error) {
+	node := &lock.LockNode{
+		Token: stmt.ColumnText(0),
+		Details: webdav.LockDetails{
+			Root:      stmt.ColumnText(1),
+			ZeroDepth: stmt.ColumnBool(2),
+			OwnerXML:  stmt.ColumnText(3),
+			Duration:  time.Duration(stmt.ColumnInt64(4)),
+		},
+	}
+
+	if expiry := stmt.ColumnInt64(5); expiry != 0 {
+		node.Expiry = time.Unix(0, expiry)
+	}
+
+	if err := node.Scope.UnmarshalText([]byte(stmt.ColumnText(6))); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return node, nil
+}
+
+func deleteExpired(conn *sqlite.Conn, now time.Time) (int, error) {
+	err := sqlitex.Execute(conn, `
+		DELETE FROM lock_paths WHERE token IN (
+			SELECT token FROM locks WHERE expiry != 0 AND expiry < ?
+		)
+	`, &sqlitex.ExecOptions{
+		Args: []any{now.UnixNano()},
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	err = sqlitex.Execute(conn, `
+		DELETE FROM locks WHERE expiry != 0 AND expiry < ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{now.UnixNano()},
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return conn.Changes(), nil
+}
+
+// clean normalizes the given resource path so that "/foo", "foo" and "/foo/"
+// share the same locks.
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+// ancestorsOf returns the strict ancestors of the given cleaned path,
+// from the root to its direct parent.
+func ancestorsOf(name string) []string {
+	if name == "/" {
+		return nil
+	}
+
+	ancestors := []string{"/"}
+
+	for i := 1; i < len(name); i++ {
+		if name[i] == '/' {
+			ancestors = append(ancestors, name[:i])
+		}
+	}
+
+	return ancestors
+}
+
+func placeholders(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+func withImmediate(conn *sqlite.Conn, fn func() error) (err error) {
+	end, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer end(&err)
+
+	err = fn()
+
+	return err
+}
@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bornholm/go-webdav/lock"
+	"github.com/bornholm/go-webdav/lock/bench"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestStore(t *testing.T) {
+	dbPath := createDatabasePath(t)
+
+	store := NewStore(dbPath)
+
+	now := time.Now()
+	system := lock.NewSystem(store)
+
+	token, err := system.Create(now, webdav.LockDetails{
+		Root:     "/dir",
+		Duration: time.Hour,
+		OwnerXML: "<D:href>John Doe</D:href>",
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	shared, err := system.CreateWithScope(now, webdav.LockDetails{
+		Root:      "/shared.txt",
+		Duration:  time.Hour,
+		ZeroDepth: true,
+	}, lock.ScopeShared)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expired := &lock.LockNode{
+		Token:   "urn:uuid:expired",
+		Details: webdav.LockDetails{Root: "/expired", ZeroDepth: true},
+		Expiry:  now.Add(-time.Minute),
+	}
+
+	if err := store.ApplyLock(expired, expired.Details.Root); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Reopen the database to ensure that locks are persisted
+	store = NewStore(dbPath)
+	defer store.Close()
+
+	system = lock.NewSystem(store)
+
+	node, err := store.GetLock(token)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "<D:href>John Doe</D:href>", node.Details.OwnerXML; e != g {
+		t.Errorf("node.Details.OwnerXML: expected %v, got %v", e, g)
+	}
+
+	node, err = store.GetLock(shared)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := lock.ScopeShared, node.Scope; e != g {
+		t.Errorf("node.Scope: expected %v, got %v", e, g)
+	}
+
+	// The expiry of the locks is decided by the system, with its own clock
+	if _, err := system.Lock(now, expired.Token); !errors.Is(err, lock.ErrLockNotFound) {
+		t.Errorf("expected error '%v', got '%v'", lock.ErrLockNotFound, err)
+	}
+
+	if _, err := system.Lock(now.Add(-time.Hour), expired.Token); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	for name, expected := range map[string]int{
+		"/":                 0,
+		"/dir":              1,
+		"/dir/":             1,
+		"/dir/sub/file.txt": 1,
+		"/directory":        0,
+		"/expired":          1,
+	} {
+		locks, err := store.GetLocksByPath(name)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := expected, len(locks); e != g {
+			t.Errorf("len(GetLocksByPath(%s)): expected %v, got %v", name, e, g)
+		}
+	}
+
+	if _, err := system.Confirm(now, "/expired", ""); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := system.Confirm(now, "/dir/file.txt", ""); !errors.Is(err, webdav.ErrLocked) {
+		t.Errorf("expected error '%v', got '%v'", webdav.ErrLocked, err)
+	}
+
+	release, err := system.Confirm(now, "/dir/file.txt", "", webdav.Condition{Token: token})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	release()
+
+	if err := system.Unlock(now, token); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := store.GetLock(token); !errors.Is(err, lock.ErrLockNotFound) {
+		t.Errorf("expected error '%v', got '%v'", lock.ErrLockNotFound, err)
+	}
+
+	removed, err := store.RemoveExpired(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// The shared lock, the expired one being removed by the confirmation
+	if e, g := 1, removed; e != g {
+		t.Errorf("removed: expected %v, got %v", e, g)
+	}
+}
+
+func BenchmarkStore(b *testing.B) {
+	store := NewStore(createDatabasePath(b))
+	defer store.Close()
+
+	bench.RunTestSuite(b, store)
+}
+
+func createDatabasePath(t testing.TB) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	dbPath := filepath.Join(cwd, "testdata", "locks.db")
+
+	files, err := filepath.Glob(dbPath + "*")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	for _, f := range files {
+		if err := os.RemoveAll(f); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	return dbPath
+}
@@ -1,14 +1,21 @@
 package lock
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrLockNotFound = errors.New("lock not found")
 )
 
+// Store persists the locks of a [System].
+// Implementations must keep every field of the given [LockNode], including its [Scope].
 type Store interface {
 	GetLock(token string) (*LockNode, error)
 	GetLocksByPath(path string) ([]*LockNode, error)
 	ApplyLock(node *LockNode, paths ...string) error
 	RemoveLock(token string) error
+	ListLocks() ([]*LockNode, error)
+	RemoveExpired(now time.Time) (int, error)
 }
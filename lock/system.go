@@ -20,10 +20,11 @@ type LockNode struct {
 	Details webdav.LockDetails
 	Token   string
 	Expiry  time.Time
+	Scope   Scope
 }
 
 // NewSystem creates a new lock system.
-func NewSystem(store Store) webdav.LockSystem {
+func NewSystem(store Store) *System {
 	return &System{
 		store: store,
 	}
@@ -32,20 +33,22 @@ func NewSystem(store Store) webdav.LockSystem {
 // Confirm verifies that the given conditions allow access to the named resource.
 // It returns a release function if the access is granted.
 func (s *System) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
-	name0 = normalizePath(name0)
-	name1 = normalizePath(name1)
-
-	// Collect all paths we need to check
+	// Collect all paths we need to check, empty names are ignored
 	var paths []string
 	if name0 != "" {
+		name0 = normalizePath(name0)
 		paths = append(paths, name0)
 	}
-	if name1 != "" && name1 != name0 {
-		paths = append(paths, name1)
+	if name1 != "" {
+		name1 = normalizePath(name1)
+		if name1 != name0 {
+			paths = append(paths, name1)
+		}
 	}
 
 	// Get all locks affecting our paths
-	affectingLocks := make(map[string]*LockNode)
+	affectingLocks := make(map[string][]*LockNode)
+	totalAffectingLocks := 0
 	for _, path := range paths {
 		locks, err := s.store.GetLocksByPath(path)
 		if err != nil {
@@ -56,13 +59,14 @@ func (s *System) Confirm(now time.Time, name0, name1 string, conditions ...webda
 				_ = s.store.RemoveLock(lock.Token)
 				continue
 			}
-			affectingLocks[lock.Token] = lock
+			affectingLocks[path] = append(affectingLocks[path], lock)
+			totalAffectingLocks++
 		}
 	}
 
 	// No conditions provided
 	if len(conditions) == 0 {
-		if len(affectingLocks) > 0 {
+		if totalAffectingLocks > 0 {
 			return nil, webdav.ErrLocked
 		}
 		return func() {}, nil
@@ -126,9 +130,27 @@ func (s *System) Confirm(now time.Time, name0, name1 string, conditions ...webda
 		return nil, webdav.ErrNoSuchLock
 	}
 
-	// Ensure ALL affecting locks are satisfied
-	for token := range affectingLocks {
-		if !satisfiedLocks[token] {
+	// Ensure that every exclusive lock is satisfied and, when a path is
+	// held by shared locks, that at least one of them is
+	for _, locks := range affectingLocks {
+		hasShared := false
+		sharedSatisfied := false
+
+		for _, lock := range locks {
+			if lock.Scope != ScopeShared {
+				if !satisfiedLocks[lock.Token] {
+					return nil, webdav.ErrLocked
+				}
+				continue
+			}
+
+			hasShared = true
+			if satisfiedLocks[lock.Token] {
+				sharedSatisfied = true
+			}
+		}
+
+		if hasShared && !sharedSatisfied {
 			return nil, webdav.ErrLocked
 		}
 	}
@@ -153,8 +175,15 @@ func (s *System) lockCoversPath(node *LockNode, path string) bool {
 	return false
 }
 
-// Create creates a new lock.
+// Create creates a new exclusive lock.
 func (s *System) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	return s.CreateWithScope(now, details, ScopeExclusive)
+}
+
+// CreateWithScope creates a new lock with the given scope.
+// Shared locks can coexist with each other, whereas an exclusive lock
+// conflicts with any existing lock.
+func (s *System) CreateWithScope(now time.Time, details webdav.LockDetails, scope Scope) (string, error) {
 	details.Root = normalizePath(details.Root)
 
 	// Check for conflicts with existing locks
@@ -169,7 +198,11 @@ func (s *System) Create(now time.Time, details webdav.LockDetails) (string, erro
 			_ = s.store.RemoveLock(existing.Token)
 			continue
 		}
-		// Any existing lock conflicts with a new lock request
+		// Shared locks only conflict with exclusive ones
+		if scope == ScopeShared && existing.Scope == ScopeShared {
+			continue
+		}
+
 		return "", webdav.ErrLocked
 	}
 
@@ -189,6 +222,7 @@ func (s *System) Create(now time.Time, details webdav.LockDetails) (string, erro
 		Details: details,
 		Token:   token,
 		Expiry:  expiry,
+		Scope:   scope,
 	}
 
 	if err := s.store.ApplyLock(node, details.Root); err != nil {
@@ -200,6 +234,12 @@ func (s *System) Create(now time.Time, details webdav.LockDetails) (string, erro
 
 // Refresh refreshes an existing lock.
 func (s *System) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	details, _, err := s.RefreshWithScope(now, token, duration)
+	return details, err
+}
+
+// RefreshWithScope implements [ScopedLockSystem].
+func (s *System) RefreshWithScope(now time.Time, token string, duration time.Duration) (webdav.LockDetails, Scope, error) {
 	// Strip angle brackets if present
 	token = strings.TrimPrefix(token, "<")
 	token = strings.TrimSuffix(token, ">")
@@ -207,15 +247,15 @@ func (s *System) Refresh(now time.Time, token string, duration time.Duration) (w
 	node, err := s.store.GetLock(token)
 	if err != nil {
 		if errors.Is(err, ErrLockNotFound) {
-			return webdav.LockDetails{}, webdav.ErrNoSuchLock
+			return webdav.LockDetails{}, ScopeExclusive, webdav.ErrNoSuchLock
 		}
-		return webdav.LockDetails{}, errors.WithStack(err)
+		return webdav.LockDetails{}, ScopeExclusive, errors.WithStack(err)
 	}
 
 	// Check if already expired
 	if !node.Expiry.IsZero() && now.After(node.Expiry) {
 		_ = s.store.RemoveLock(token)
-		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+		return webdav.LockDetails{}, ScopeExclusive, webdav.ErrNoSuchLock
 	}
 
 	// Update expiry
@@ -227,10 +267,10 @@ func (s *System) Refresh(now time.Time, token string, duration time.Duration) (w
 	}
 
 	if err := s.store.ApplyLock(node, node.Details.Root); err != nil {
-		return webdav.LockDetails{}, errors.WithStack(err)
+		return webdav.LockDetails{}, ScopeExclusive, errors.WithStack(err)
 	}
 
-	return node.Details, nil
+	return node.Details, node.Scope, nil
 }
 
 // Unlock removes a lock.
@@ -298,4 +338,4 @@ func generateUUID() (string, error) {
 	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
-var _ webdav.LockSystem = &System{}
+var _ ScopedLockSystem = &System{}
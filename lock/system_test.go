@@ -0,0 +1,143 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestSharedLocks(t *testing.T) {
+	now := time.Now()
+	system := NewSystem(NewMemoryStore())
+
+	details := webdav.LockDetails{
+		Root:     "/dir",
+		Duration: time.Hour,
+	}
+
+	first, err := system.CreateWithScope(now, details, ScopeShared)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	second, err := system.CreateWithScope(now, details, ScopeShared)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := system.Create(now, details); !errors.Is(err, webdav.ErrLocked) {
+		t.Errorf("exclusive lock: expected error '%v', got '%v'", webdav.ErrLocked, err)
+	}
+
+	if _, err := system.Confirm(now, "/dir/file.txt", ""); !errors.Is(err, webdav.ErrLocked) {
+		t.Errorf("confirm without token: expected error '%v', got '%v'", webdav.ErrLocked, err)
+	}
+
+	// Holding any of the shared locks is enough
+	for _, token := range []string{first, second} {
+		release, err := system.Confirm(now, "/dir/file.txt", "", webdav.Condition{Token: token})
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		release()
+	}
+
+	if err := system.Unlock(now, first); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := system.Unlock(now, second); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	exclusive, err := system.Create(now, details)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := system.CreateWithScope(now, details, ScopeShared); !errors.Is(err, webdav.ErrLocked) {
+		t.Errorf("shared lock: expected error '%v', got '%v'", webdav.ErrLocked, err)
+	}
+
+	release, err := system.Confirm(now, "/dir", "", webdav.Condition{Token: exclusive})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	release()
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Now()
+	system := NewSystem(NewMemoryStore())
+
+	expiring, err := system.Create(now, webdav.LockDetails{Root: "/expiring", Duration: time.Minute})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	forever, err := system.Create(now, webdav.LockDetails{Root: "/forever", Duration: -1})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	later := now.Add(time.Hour)
+
+	removed, err := system.Sweep(later)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, removed; e != g {
+		t.Errorf("removed: expected %v, got %v", e, g)
+	}
+
+	if _, err := system.Lock(later, expiring); !errors.Is(err, ErrLockNotFound) {
+		t.Errorf("expected error '%v', got '%v'", ErrLockNotFound, err)
+	}
+
+	locks, err := system.Locks(later)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, len(locks); e != g {
+		t.Fatalf("len(locks): expected %v, got %v", e, g)
+	}
+
+	if e, g := forever, locks[0].Token; e != g {
+		t.Errorf("locks[0].Token: expected %v, got %v", e, g)
+	}
+
+	if err := system.Break(forever); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if _, err := system.Confirm(later, "/forever", ""); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+}
+
+func TestConfirmZeroDepthRoot(t *testing.T) {
+	now := time.Now()
+	system := NewSystem(NewMemoryStore())
+
+	if _, err := system.Create(now, webdav.LockDetails{Root: "/", ZeroDepth: true, Duration: time.Hour}); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// A depth 0 lock on the root collection does not cover its members
+	release, err := system.Confirm(now, "/dir/file.txt", "")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	release()
+
+	if _, err := system.Confirm(now, "/", ""); !errors.Is(err, webdav.ErrLocked) {
+		t.Errorf("expected error '%v', got '%v'", webdav.ErrLocked, err)
+	}
+}
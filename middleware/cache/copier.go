@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// CopyDeadProps implements [webdav.DeadPropsCopier].
+func (fs *FileSystem) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	copier, ok := fs.backend.(webdav.DeadPropsCopier)
+	if !ok {
+		return nil
+	}
+
+	return copier.CopyDeadProps(ctx, src, dst, recursive)
+}
+
+// CopyFile implements [webdav.FileCopier].
+func (fs *FileSystem) CopyFile(ctx context.Context, src string, dst string) error {
+	copier, ok := fs.backend.(webdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	if err := copier.CopyFile(ctx, src, dst); err != nil {
+		return err
+	}
+
+	// Invalidated once copied, so that a concurrent lookup does not cache
+	// the previous state of the destination again
+	return fs.invalidateWithParent(ctx, dst)
+}
+
+var (
+	_ webdav.DeadPropsCopier = &FileSystem{}
+	_ webdav.FileCopier      = &FileSystem{}
+)
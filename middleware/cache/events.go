@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+)
+
+// Watch invalidates the entries of the given store as soon as the matching
+// resources are changed by other processes, if the filesystem reports them
+// (see [webdav.EventSource]), until the returned subscription is closed.
+// The filesystem must be the one wrapped by the cache middleware.
+func Watch(fs webdav.FileSystem, store Store) io.Closer {
+	return webdav.Subscribe(fs, func(e webdav.Event) {
+		ctx := context.Background()
+
+		if err := invalidateEvent(ctx, store, e); err != nil {
+			slog.ErrorContext(ctx, "could not invalidate cache entries", "event", e.Type.String(), "name", e.Name, "error", errors.WithStack(err))
+		}
+	})
+}
+
+func invalidateEvent(ctx context.Context, store Store, e webdav.Event) error {
+	names := []string{e.Name}
+	if e.Type == webdav.EventRename {
+		names = append(names, e.OldName)
+	}
+
+	for _, name := range names {
+		switch e.Type {
+		case webdav.EventCreate, webdav.EventWrite:
+			for _, key := range cacheKeys(name) {
+				if err := store.Invalidate(ctx, key); err != nil {
+					return err
+				}
+
+				if err := store.InvalidateChildren(ctx, key); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if err := store.InvalidateTree(ctx, name); err != nil {
+				return err
+			}
+		}
+
+		if name == "/" {
+			continue
+		}
+
+		for _, key := range cacheKeys(path.Dir(name)) {
+			if err := store.InvalidateChildren(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cacheKeys returns the keys the named resource may be cached with, with or
+// without trailing slash, the root listing being cached with an empty key.
+func cacheKeys(name string) []string {
+	if name == "/" {
+		return []string{name, ""}
+	}
+
+	return []string{name, name + "/"}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
 	"io/fs"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"path"
 	"strings"
 
+	gowebdav "github.com/bornholm/go-webdav"
 	"golang.org/x/net/webdav"
 )
 
@@ -127,4 +129,22 @@ func (w *fileWrapper) Close() error {
 	return closeErr
 }
 
-var _ webdav.File = &fileWrapper{}
+// DeadProps implements [webdav.DeadPropsHolder].
+func (w *fileWrapper) DeadProps() (map[xml.Name]webdav.Property, error) {
+	holder, ok := w.file.(webdav.DeadPropsHolder)
+	if !ok {
+		return nil, nil
+	}
+
+	return holder.DeadProps()
+}
+
+// Patch implements [webdav.DeadPropsHolder].
+func (w *fileWrapper) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return gowebdav.PatchDeadProps(w.file, patches)
+}
+
+var (
+	_ webdav.File            = &fileWrapper{}
+	_ webdav.DeadPropsHolder = &fileWrapper{}
+)
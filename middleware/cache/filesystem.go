@@ -128,7 +128,8 @@ func (fs *FileSystem) invalidateWithParent(ctx context.Context, name string) err
 		return nil
 	}
 
-	parent := path.Dir(cleanPath)
+	// Listings are cached without trailing slash, the root one included
+	parent := strings.TrimSuffix(path.Dir(cleanPath), "/")
 
 	if err := fs.store.InvalidateChildren(ctx, parent); err != nil {
 		return err
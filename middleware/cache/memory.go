@@ -2,7 +2,10 @@ package cache
 
 import (
 	"context"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bornholm/go-webdav/syncx"
@@ -22,11 +25,15 @@ type MemoryStore struct {
 	ttl      time.Duration
 	items    syncx.Map[string, cachedEntry]
 	children syncx.Map[string, cachedChildren]
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func NewMemoryStore(ttl time.Duration) *MemoryStore {
 	m := &MemoryStore{
-		ttl: ttl,
+		ttl:  ttl,
+		done: make(chan struct{}),
 	}
 	go m.garbageCollect()
 	return m
@@ -87,9 +94,48 @@ func (m *MemoryStore) InvalidateChildren(ctx context.Context, path string) error
 	return nil
 }
 
+func (m *MemoryStore) InvalidateTree(ctx context.Context, path string) error {
+	base := strings.TrimSuffix(path, "/")
+	matches := func(key string) bool {
+		return strings.TrimSuffix(key, "/") == base || strings.HasPrefix(key, base+"/")
+	}
+
+	m.items.Range(func(key string, _ cachedEntry) bool {
+		if matches(key) {
+			m.items.Delete(key)
+		}
+		return true
+	})
+	m.children.Range(func(key string, _ cachedChildren) bool {
+		if matches(key) {
+			m.children.Delete(key)
+		}
+		return true
+	})
+
+	return nil
+}
+
+// Close stops the garbage collection of the expired entries.
+func (m *MemoryStore) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+
+	return nil
+}
+
 func (m *MemoryStore) garbageCollect() {
 	ticker := time.NewTicker(m.ttl * 2)
-	for range ticker.C {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+
 		now := time.Now()
 		// Clean items
 		m.items.Range(func(key string, val cachedEntry) bool {
@@ -109,3 +155,4 @@ func (m *MemoryStore) garbageCollect() {
 }
 
 var _ Store = &MemoryStore{}
+var _ io.Closer = &MemoryStore{}
@@ -12,4 +12,7 @@ type Store interface {
 	GetChildren(ctx context.Context, path string) ([]os.FileInfo, bool, error)
 	PutChildren(ctx context.Context, path string, children []os.FileInfo) error
 	InvalidateChildren(ctx context.Context, path string) error
+	// InvalidateTree removes the entries and the listings of the given path
+	// and of its descendants.
+	InvalidateTree(ctx context.Context, path string) error
 }
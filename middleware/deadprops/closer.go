@@ -0,0 +1,15 @@
+package deadprops
+
+import (
+	"io"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// Close implements [io.Closer].
+// The store is shared with the other filesystems of the middleware and is not closed.
+func (fs *Filesystem) Close() error {
+	return webdav.Close(fs.backend)
+}
+
+var _ io.Closer = &Filesystem{}
@@ -0,0 +1,22 @@
+package deadprops
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// CopyFile implements [webdav.FileCopier].
+// The dead properties of the file are copied by the webdav handler.
+func (fs *Filesystem) CopyFile(ctx context.Context, src string, dst string) error {
+	copier, ok := fs.backend.(webdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	return copier.CopyFile(ctx, src, dst)
+}
+
+var _ webdav.FileCopier = &Filesystem{}
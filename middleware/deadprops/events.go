@@ -0,0 +1,36 @@
+package deadprops
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/pkg/errors"
+)
+
+// Watch moves or removes the dead properties held by the given store as soon
+// as the matching resources are moved or removed by other processes, if the
+// filesystem reports them (see [webdav.EventSource]), until the returned
+// subscription is closed.
+// The filesystem must be the one wrapped by the dead properties middleware.
+func Watch(fs webdav.FileSystem, store Store) io.Closer {
+	return webdav.Subscribe(fs, func(e webdav.Event) {
+		ctx := context.Background()
+
+		var err error
+
+		switch e.Type {
+		case webdav.EventRemove:
+			err = store.RemoveAll(ctx, e.Name)
+		case webdav.EventRename:
+			err = store.Rename(ctx, e.OldName, e.Name)
+		default:
+			return
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "could not update dead properties", "event", e.Type.String(), "name", e.Name, "error", errors.WithStack(err))
+		}
+	})
+}
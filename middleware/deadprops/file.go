@@ -1,6 +1,7 @@
 package deadprops
 
 import (
+	"context"
 	"encoding/xml"
 	"io/fs"
 
@@ -8,19 +9,20 @@ import (
 )
 
 type File struct {
-	name  string
-	file  webdav.File
-	store Store
+	ctx  context.Context
+	name string
+	file webdav.File
+	fs   *Filesystem
 }
 
 // DeadProps implements webdav.DeadPropsHolder.
 func (f *File) DeadProps() (map[xml.Name]webdav.Property, error) {
-	return f.store.Get(f.name)
+	return f.fs.getProps(f.ctx, f.name)
 }
 
 // Patch implements webdav.DeadPropsHolder.
 func (f *File) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
-	return f.store.Patch(f.name, patches)
+	return f.fs.patchProps(f.ctx, f.name, patches)
 }
 
 // Close implements webdav.File.
@@ -2,14 +2,23 @@ package deadprops
 
 import (
 	"context"
+	"encoding/xml"
+	"maps"
+	"net/http"
 	"os"
+	"path"
+	"slices"
 
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
 	"golang.org/x/net/webdav"
 )
 
 type Filesystem struct {
-	store   Store
-	backend webdav.FileSystem
+	store      Store
+	backend    webdav.FileSystem
+	validators []Validator
 }
 
 // Mkdir implements webdav.FileSystem.
@@ -24,7 +33,7 @@ func (fs *Filesystem) OpenFile(ctx context.Context, name string, flag int, perm
 		return nil, err
 	}
 
-	return &File{name: name, file: file, store: fs.store}, nil
+	return &File{ctx: ctx, name: name, file: file, fs: fs}, nil
 }
 
 // RemoveAll implements webdav.FileSystem.
@@ -33,7 +42,7 @@ func (fs *Filesystem) RemoveAll(ctx context.Context, name string) error {
 		return err
 	}
 
-	return fs.store.RemoveAll(name)
+	return fs.store.RemoveAll(ctx, name)
 }
 
 // Rename implements webdav.FileSystem.
@@ -42,7 +51,7 @@ func (fs *Filesystem) Rename(ctx context.Context, oldName string, newName string
 		return err
 	}
 
-	return fs.store.Rename(oldName, newName)
+	return fs.store.Rename(ctx, oldName, newName)
 }
 
 // Stat implements webdav.FileSystem.
@@ -50,11 +59,239 @@ func (fs *Filesystem) Stat(ctx context.Context, name string) (os.FileInfo, error
 	return fs.backend.Stat(ctx, name)
 }
 
+// CopyDeadProps implements [webdav.DeadPropsCopier].
+func (fs *Filesystem) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	info, err := fs.backend.Stat(ctx, src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	if err := fs.copyProps(ctx, src, dst, recursive); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// copyProps copies the dead properties of the named collection and, if
+// recursive is true, of its descendant collections. The properties of the
+// files are copied by the webdav handler along with their content.
+// Descendants are listed from the destination, so that the ones the webdav
+// handler did not copy, i.e. hidden to the user, are left out.
+func (fs *Filesystem) copyProps(ctx context.Context, src string, dst string, recursive bool) error {
+	props, err := fs.getProps(ctx, src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(props) > 0 {
+		patch := webdav.Proppatch{
+			Props: slices.Collect(maps.Values(props)),
+		}
+
+		if _, err := fs.applyPatches(ctx, dst, []webdav.Proppatch{patch}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	dir, err := fs.backend.OpenFile(ctx, dst, os.O_RDONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	children, err := dir.Readdir(-1)
+	if err != nil {
+		dir.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := dir.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, c := range children {
+		if !c.IsDir() {
+			continue
+		}
+
+		childSrc := path.Join(src, c.Name())
+
+		if _, err := fs.backend.Stat(ctx, childSrc); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return errors.WithStack(err)
+		}
+
+		if err := fs.copyProps(ctx, childSrc, path.Join(dst, c.Name()), true); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// getProps returns the dead properties of the named resource, from the backend
+// if it is able to store them or from the store otherwise.
+func (fs *Filesystem) getProps(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	if backend, ok := fs.backend.(filesystem.PropertiesFileSystem); ok {
+		props, err := backend.GetProperties(ctx, name)
+		if err == nil {
+			return props, nil
+		}
+
+		if !errors.Is(err, filesystem.ErrNotSupported) {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return fs.store.Get(ctx, name)
+}
+
+// patchProps applies the given patches to the named resource, in the backend
+// if it is able to store them or in the store otherwise.
+// Following RFC 4918 section 9.2, either all the changes are applied or none of them.
+func (fs *Filesystem) patchProps(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	propstats, err := fs.validate(ctx, name, patches)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if propstats != nil {
+		return propstats, nil
+	}
+
+	return fs.applyPatches(ctx, name, patches)
+}
+
+// applyPatches applies the given patches without validating them.
+func (fs *Filesystem) applyPatches(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	if backend, ok := fs.backend.(filesystem.PropertiesFileSystem); ok {
+		err := backend.PatchProperties(ctx, name, patches)
+		if err == nil {
+			return gowebdav.Propstats(http.StatusOK, patches), nil
+		}
+
+		if !errors.Is(err, filesystem.ErrNotSupported) {
+			return nil, errors.WithStack(err)
+		}
+
+		// The properties held by the backend until now are moved to the store
+		var unsupported *filesystem.UnsupportedPropertiesError
+		if errors.As(err, &unsupported) && len(unsupported.Properties) > 0 {
+			moved := webdav.Proppatch{
+				Props: slices.Collect(maps.Values(unsupported.Properties)),
+			}
+
+			if _, err := fs.store.Patch(ctx, name, []webdav.Proppatch{moved}); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	return fs.store.Patch(ctx, name, patches)
+}
+
+// validate checks each property change against the configured validators and,
+// when they implement [Validator], the store and the backend.
+// It returns nil propstats if all the changes are valid, or the propstats
+// describing the failure otherwise.
+func (fs *Filesystem) validate(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	validators := fs.validators
+
+	if validator, ok := fs.store.(Validator); ok {
+		validators = append(slices.Clip(validators), validator)
+	}
+
+	if validator, ok := fs.backend.(Validator); ok {
+		validators = append(slices.Clip(validators), validator)
+	}
+
+	failed := false
+	failures := make([]*PropertyError, 0)
+
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			var propErr *PropertyError
+
+			for _, v := range validators {
+				err := v.Validate(ctx, name, prop, patch.Remove)
+				if err == nil {
+					continue
+				}
+
+				if !errors.As(err, &propErr) {
+					return nil, errors.WithStack(err)
+				}
+
+				failed = true
+
+				break
+			}
+
+			failures = append(failures, propErr)
+		}
+	}
+
+	if !failed {
+		return nil, nil
+	}
+
+	// Group properties by status, the valid ones being reported as failed dependencies
+	propstats := make([]webdav.Propstat, 0)
+	indexes := make(map[PropertyError]int)
+
+	i := 0
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			failure := failures[i]
+			i++
+
+			key := PropertyError{Status: http.StatusFailedDependency}
+			if failure != nil {
+				key = PropertyError{Status: failure.Status, XMLError: failure.XMLError}
+			}
+
+			idx, exists := indexes[key]
+			if !exists {
+				idx = len(propstats)
+				indexes[key] = idx
+				propstats = append(propstats, webdav.Propstat{
+					Status:   key.Status,
+					XMLError: key.XMLError,
+				})
+			}
+
+			propstats[idx].Props = append(propstats[idx].Props, webdav.Property{XMLName: prop.XMLName})
+		}
+	}
+
+	return propstats, nil
+}
+
 var _ webdav.FileSystem = &Filesystem{}
 
-func NewFileSystem(backend webdav.FileSystem, store Store) *Filesystem {
+func NewFileSystem(backend webdav.FileSystem, store Store, funcs ...OptionFunc) *Filesystem {
+	opts := NewOptions(funcs...)
+
+	validators := make([]Validator, 0, len(opts.Validators)+1)
+	if len(opts.ProtectedProperties) > 0 {
+		validators = append(validators, ProtectedProperties(opts.ProtectedProperties...))
+	}
+	validators = append(validators, opts.Validators...)
+
 	return &Filesystem{
-		store:   store,
-		backend: backend,
+		store:      store,
+		backend:    backend,
+		validators: validators,
 	}
 }
@@ -0,0 +1,318 @@
+package deadprops
+
+import (
+	"context"
+	"encoding/xml"
+	"maps"
+	"net/http"
+	"os"
+	"testing"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestPatchAtomicity(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemStore()
+	fs := NewFileSystem(webdav.NewMemFS(), store)
+
+	file, err := fs.OpenFile(ctx, "/file.txt", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer file.Close()
+
+	holder, ok := file.(webdav.DeadPropsHolder)
+	if !ok {
+		t.Fatalf("file does not implement webdav.DeadPropsHolder")
+	}
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+	quota := xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+
+	propstats, err := holder.Patch([]webdav.Proppatch{
+		{Props: []webdav.Property{{XMLName: author, InnerXML: []byte("John Doe")}}},
+		{Props: []webdav.Property{{XMLName: quota, InnerXML: []byte("0")}}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	statuses := map[xml.Name]int{}
+	for _, ps := range propstats {
+		for _, p := range ps.Props {
+			statuses[p.XMLName] = ps.Status
+		}
+	}
+
+	if e, g := http.StatusFailedDependency, statuses[author]; e != g {
+		t.Errorf("statuses[author]: expected %v, got %v", e, g)
+	}
+
+	if e, g := http.StatusForbidden, statuses[quota]; e != g {
+		t.Errorf("statuses[quota]: expected %v, got %v", e, g)
+	}
+
+	props, err := holder.DeadProps()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 0, len(props); e != g {
+		t.Errorf("len(props): expected %v, got %v", e, g)
+	}
+
+	propstats, err = holder.Patch([]webdav.Proppatch{
+		{Props: []webdav.Property{{XMLName: author, InnerXML: []byte("John Doe")}}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := http.StatusOK, propstats[0].Status; e != g {
+		t.Errorf("propstats[0].Status: expected %v, got %v", e, g)
+	}
+
+	props, err = holder.DeadProps()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "John Doe", string(props[author].InnerXML); e != g {
+		t.Errorf("props[author]: expected %v, got %v", e, g)
+	}
+}
+
+func TestCopyDeadProps(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemStore()
+	fs := NewFileSystem(webdav.NewMemFS(), store)
+
+	for _, name := range []string{"/dir", "/dir/sub"} {
+		if err := fs.Mkdir(ctx, name, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+
+	for _, name := range []string{"/dir", "/dir/file.txt", "/dir/sub"} {
+		if _, err := store.Patch(ctx, name, []webdav.Proppatch{
+			{Props: []webdav.Property{{XMLName: author, InnerXML: []byte(name)}}},
+		}); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	// The collections are copied by the webdav handler beforehand
+	for _, name := range []string{"/shallow", "/deep", "/deep/sub"} {
+		if err := fs.Mkdir(ctx, name, 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	if err := fs.CopyDeadProps(ctx, "/dir", "/shallow", false); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := fs.CopyDeadProps(ctx, "/dir", "/deep", true); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// The properties of the files are copied by the webdav handler
+	expected := map[string]string{
+		"/shallow":          "/dir",
+		"/shallow/file.txt": "",
+		"/shallow/sub":      "",
+		"/deep":             "/dir",
+		"/deep/file.txt":    "",
+		"/deep/sub":         "/dir/sub",
+	}
+
+	for name, value := range expected {
+		props, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := value, string(props[author].InnerXML); e != g {
+			t.Errorf("%s: expected author '%v', got '%v'", name, e, g)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemStore()
+	source := &eventSource{FileSystem: webdav.NewMemFS()}
+
+	watch := Watch(source, store)
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+
+	for _, name := range []string{"/dir", "/dir/file.txt"} {
+		if _, err := store.Patch(ctx, name, []webdav.Proppatch{
+			{Props: []webdav.Property{{XMLName: author, InnerXML: []byte(name)}}},
+		}); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	source.emit(gowebdav.Event{Type: gowebdav.EventRename, Name: "/moved", OldName: "/dir"})
+	source.emit(gowebdav.Event{Type: gowebdav.EventWrite, Name: "/moved"})
+
+	expected := map[string]string{
+		"/dir":            "",
+		"/dir/file.txt":   "",
+		"/moved":          "/dir",
+		"/moved/file.txt": "/dir/file.txt",
+	}
+
+	for name, value := range expected {
+		props, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := value, string(props[author].InnerXML); e != g {
+			t.Errorf("%s: expected author '%v', got '%v'", name, e, g)
+		}
+	}
+
+	source.emit(gowebdav.Event{Type: gowebdav.EventRemove, Name: "/moved"})
+
+	props, err := store.Get(ctx, "/moved/file.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if len(props) != 0 {
+		t.Errorf("expected removed resource to have no properties, got %v", props)
+	}
+
+	if err := watch.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if source.fn != nil {
+		t.Errorf("expected subscription to be cancelled")
+	}
+}
+
+type eventSource struct {
+	webdav.FileSystem
+	fn func(gowebdav.Event)
+}
+
+func (s *eventSource) Subscribe(fn func(gowebdav.Event)) func() {
+	s.fn = fn
+
+	return func() {
+		s.fn = nil
+	}
+}
+
+func (s *eventSource) emit(e gowebdav.Event) {
+	if s.fn != nil {
+		s.fn(e)
+	}
+}
+
+func TestPatchFallback(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemStore()
+	backend := &limitedProperties{FileSystem: webdav.NewMemFS(), props: map[xml.Name]webdav.Property{}}
+	fs := NewFileSystem(backend, store)
+
+	file, err := fs.OpenFile(ctx, "/file.txt", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer file.Close()
+
+	holder := file.(webdav.DeadPropsHolder)
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+	title := xml.Name{Space: "http://example.com/ns", Local: "title"}
+
+	for _, name := range []xml.Name{author, title} {
+		propstats, err := holder.Patch([]webdav.Proppatch{
+			{Props: []webdav.Property{{XMLName: name, InnerXML: []byte(name.Local)}}},
+		})
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := http.StatusOK, propstats[0].Status; e != g {
+			t.Errorf("propstats[0].Status: expected %v, got %v", e, g)
+		}
+
+		if e, g := 1, len(propstats); e != g {
+			t.Errorf("len(propstats): expected %v, got %v", e, g)
+		}
+	}
+
+	// The backend holds a single property, both are moved to the store
+	stored, err := store.Get(ctx, "/file.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 2, len(stored); e != g {
+		t.Errorf("len(stored): expected %v, got %v", e, g)
+	}
+
+	props, err := holder.DeadProps()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	for _, name := range []xml.Name{author, title} {
+		if e, g := name.Local, string(props[name].InnerXML); e != g {
+			t.Errorf("props[%s]: expected %v, got %v", name.Local, e, g)
+		}
+	}
+}
+
+// limitedProperties is a backend holding a single property, the properties
+// being handed over to the fallback storage beyond.
+type limitedProperties struct {
+	webdav.FileSystem
+	props    map[xml.Name]webdav.Property
+	fallback bool
+}
+
+func (fs *limitedProperties) GetProperties(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	if fs.fallback {
+		return nil, errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	return maps.Clone(fs.props), nil
+}
+
+func (fs *limitedProperties) PatchProperties(ctx context.Context, name string, patches []webdav.Proppatch) error {
+	if fs.fallback {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	props := maps.Clone(fs.props)
+	filesystem.ApplyProppatches(props, patches)
+
+	if len(props) > 1 {
+		fs.fallback = true
+		return errors.WithStack(&filesystem.UnsupportedPropertiesError{Properties: fs.props})
+	}
+
+	fs.props = props
+
+	return nil
+}
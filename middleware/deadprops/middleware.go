@@ -2,8 +2,10 @@ package deadprops
 
 import "github.com/bornholm/go-webdav"
 
-func Middleware(store Store) webdav.Middleware {
+func Middleware(store Store, funcs ...OptionFunc) webdav.Middleware {
 	return func(next webdav.FileSystem) webdav.FileSystem {
-		return NewFileSystem(next, store)
+		return NewFileSystem(next, store, funcs...)
 	}
 }
+
+var _ webdav.DeadPropsCopier = &Filesystem{}
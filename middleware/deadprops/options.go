@@ -0,0 +1,37 @@
+package deadprops
+
+import "encoding/xml"
+
+type Options struct {
+	// ProtectedProperties are the properties clients are not allowed to modify
+	ProtectedProperties []xml.Name
+	// Validators are additional checks applied to each property change
+	Validators []Validator
+}
+
+type OptionFunc func(opts *Options)
+
+func WithProtectedProperties(names ...xml.Name) OptionFunc {
+	return func(opts *Options) {
+		opts.ProtectedProperties = names
+	}
+}
+
+func WithValidators(validators ...Validator) OptionFunc {
+	return func(opts *Options) {
+		opts.Validators = validators
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		ProtectedProperties: DefaultProtectedProperties,
+		Validators:          []Validator{},
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
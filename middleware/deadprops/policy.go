@@ -0,0 +1,92 @@
+package deadprops
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/webdav"
+)
+
+// PropertyError rejects a property change with the given HTTP status.
+type PropertyError struct {
+	Status int
+	// XMLError is an optional precondition/postcondition XML element
+	// describing the error, as defined by RFC 4918 section 16.
+	XMLError string
+	Message  string
+}
+
+// Error implements error.
+func (e *PropertyError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("property change rejected with status %d", e.Status)
+}
+
+var (
+	ErrProtectedProperty = &PropertyError{
+		Status:   http.StatusForbidden,
+		XMLError: `<D:cannot-modify-protected-property xmlns:D="DAV:"/>`,
+		Message:  "cannot modify protected property",
+	}
+)
+
+// NewPropertyError returns a new [PropertyError] with the given status and message.
+func NewPropertyError(status int, message string) *PropertyError {
+	return &PropertyError{
+		Status:  status,
+		Message: message,
+	}
+}
+
+// Validator checks a property change before it is applied.
+//
+// Returning a [PropertyError] rejects the change with the associated status,
+// any other error aborts the whole PROPPATCH request.
+type Validator interface {
+	Validate(ctx context.Context, name string, prop webdav.Property, remove bool) error
+}
+
+type ValidatorFunc func(ctx context.Context, name string, prop webdav.Property, remove bool) error
+
+// Validate implements [Validator].
+func (fn ValidatorFunc) Validate(ctx context.Context, name string, prop webdav.Property, remove bool) error {
+	return fn(ctx, name, prop, remove)
+}
+
+// DefaultProtectedProperties are the live properties defined by RFC 4918 (and
+// commonly used extensions) that clients must not be able to modify.
+var DefaultProtectedProperties = []xml.Name{
+	{Space: "DAV:", Local: "creationdate"},
+	{Space: "DAV:", Local: "getcontentlength"},
+	{Space: "DAV:", Local: "getcontenttype"},
+	{Space: "DAV:", Local: "getcontentlanguage"},
+	{Space: "DAV:", Local: "getetag"},
+	{Space: "DAV:", Local: "getlastmodified"},
+	{Space: "DAV:", Local: "lockdiscovery"},
+	{Space: "DAV:", Local: "resourcetype"},
+	{Space: "DAV:", Local: "supportedlock"},
+	{Space: "DAV:", Local: "quota-available-bytes"},
+	{Space: "DAV:", Local: "quota-used-bytes"},
+}
+
+// ProtectedProperties returns a [Validator] rejecting any change of the given properties
+// with [ErrProtectedProperty].
+func ProtectedProperties(names ...xml.Name) Validator {
+	protected := make(map[xml.Name]struct{}, len(names))
+	for _, n := range names {
+		protected[n] = struct{}{}
+	}
+
+	return ValidatorFunc(func(ctx context.Context, name string, prop webdav.Property, remove bool) error {
+		if _, exists := protected[prop.XMLName]; exists {
+			return ErrProtectedProperty
+		}
+
+		return nil
+	})
+}
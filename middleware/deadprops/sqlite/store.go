@@ -0,0 +1,255 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"path"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/middleware/deadprops"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitemigration"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Store is a persistent dead properties store backed by a SQLite database.
+type Store struct {
+	pool *sqlitemigration.Pool
+}
+
+// Get implements [deadprops.Store].
+func (s *Store) Get(ctx context.Context, filename string) (map[xml.Name]webdav.Property, error) {
+	filename = clean(filename)
+
+	conn, err := s.pool.Take(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	props := make(map[xml.Name]webdav.Property)
+
+	err = sqlitex.Execute(conn, `
+		SELECT namespace, local, lang, inner_xml FROM properties
+		WHERE path = ?
+	`, &sqlitex.ExecOptions{
+		Args: []any{filename},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			name := xml.Name{
+				Space: stmt.ColumnText(0),
+				Local: stmt.ColumnText(1),
+			}
+
+			innerXML := make([]byte, stmt.ColumnLen(3))
+			stmt.ColumnBytes(3, innerXML)
+
+			props[name] = webdav.Property{
+				XMLName:  name,
+				Lang:     stmt.ColumnText(2),
+				InnerXML: innerXML,
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return props, nil
+}
+
+// Patch implements [deadprops.Store].
+func (s *Store) Patch(ctx context.Context, filename string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	filename = clean(filename)
+
+	conn, err := s.pool.Take(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	err = withImmediate(conn, func() error {
+		for _, patch := range patches {
+			for _, prop := range patch.Props {
+				if patch.Remove {
+					err := sqlitex.Execute(conn, `
+						DELETE FROM properties
+						WHERE path = ? AND namespace = ? AND local = ?
+					`, &sqlitex.ExecOptions{
+						Args: []any{filename, prop.XMLName.Space, prop.XMLName.Local},
+					})
+					if err != nil {
+						return errors.WithStack(err)
+					}
+
+					continue
+				}
+
+				err := sqlitex.Execute(conn, `
+					INSERT OR REPLACE INTO properties (path, namespace, local, lang, inner_xml)
+					VALUES (?, ?, ?, ?, ?)
+				`, &sqlitex.ExecOptions{
+					Args: []any{filename, prop.XMLName.Space, prop.XMLName.Local, prop.Lang, prop.InnerXML},
+				})
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return gowebdav.Propstats(http.StatusOK, patches), nil
+}
+
+// RemoveAll implements [deadprops.Store].
+func (s *Store) RemoveAll(ctx context.Context, filename string) error {
+	filename = clean(filename)
+
+	conn, err := s.pool.Take(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	lower, upper := childrenRange(filename)
+
+	err = sqlitex.Execute(conn, `
+		DELETE FROM properties
+		WHERE path = ? OR (path >= ? AND path < ?)
+	`, &sqlitex.ExecOptions{
+		Args: []any{filename, lower, upper},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Rename implements [deadprops.Store].
+func (s *Store) Rename(ctx context.Context, oldName string, newName string) error {
+	oldName = clean(oldName)
+	newName = clean(newName)
+
+	conn, err := s.pool.Take(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer s.pool.Put(conn)
+
+	lower, upper := childrenRange(oldName)
+
+	err = withImmediate(conn, func() error {
+		// Properties left on the destination belong to a resource that does not exist anymore
+		newLower, newUpper := childrenRange(newName)
+
+		err := sqlitex.Execute(conn, `
+			DELETE FROM properties
+			WHERE path = ? OR (path >= ? AND path < ?)
+		`, &sqlitex.ExecOptions{
+			Args: []any{newName, newLower, newUpper},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		err = sqlitex.Execute(conn, `
+			UPDATE properties
+			SET path = ? || substr(path, length(?) + 1)
+			WHERE path = ? OR (path >= ? AND path < ?)
+		`, &sqlitex.ExecOptions{
+			Args: []any{newName, oldName, oldName, lower, upper},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Close releases the underlying database connections.
+func (s *Store) Close() error {
+	if err := s.pool.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// NewStore creates a new dead properties store persisted in the SQLite database at dbPath.
+func NewStore(dbPath string) *Store {
+	schema := sqlitemigration.Schema{
+		Migrations: []string{
+			`CREATE TABLE IF NOT EXISTS properties (
+					path TEXT NOT NULL,             -- Resource path
+					namespace TEXT NOT NULL,        -- Property XML namespace
+					local TEXT NOT NULL,            -- Property XML local name
+					lang TEXT NOT NULL DEFAULT '',  -- Property xml:lang attribute
+					inner_xml BLOB,                 -- Property raw XML value
+					PRIMARY KEY (path, namespace, local)
+				);
+			`,
+		},
+	}
+
+	pool := sqlitemigration.NewPool(dbPath, schema, sqlitemigration.Options{
+		Flags: sqlite.OpenCreate | sqlite.OpenReadWrite | sqlite.OpenWAL,
+		PrepareConn: func(conn *sqlite.Conn) error {
+			return sqlitex.ExecScript(conn, `PRAGMA busy_timeout = 5000;`)
+		},
+		OnError: func(e error) {
+			log.Printf("%+v", e)
+		},
+	})
+
+	return &Store{
+		pool: pool,
+	}
+}
+
+var _ deadprops.Store = &Store{}
+
+// clean normalizes the given resource path so that "/foo", "foo" and "/foo/"
+// share the same properties.
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+// childrenRange returns the bounds of the paths located under the given
+// directory, so that the lookup can use the primary key index.
+// Descendants of "/foo" are all the paths in the range ["/foo/", "/foo0").
+func childrenRange(name string) (string, string) {
+	if name == "/" {
+		return "/", "0"
+	}
+
+	return name + "/", name + "0"
+}
+
+func withImmediate(conn *sqlite.Conn, fn func() error) (err error) {
+	end, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer end(&err)
+
+	err = fn()
+
+	return err
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dbPath := createDatabasePath(t)
+
+	store := NewStore(dbPath)
+
+	author := xml.Name{Space: "http://example.com/ns", Local: "author"}
+
+	patches := []webdav.Proppatch{
+		{Props: []webdav.Property{{XMLName: author, InnerXML: []byte("John Doe")}}},
+	}
+
+	if _, err := store.Patch(ctx, "/dir/file.txt", patches); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := store.Rename(ctx, "/dir", "/renamed"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// Reopen the database to ensure that properties are persisted
+	store = NewStore(dbPath)
+	defer store.Close()
+
+	props, err := store.Get(ctx, "/dir/file.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 0, len(props); e != g {
+		t.Errorf("len(props): expected %v, got %v", e, g)
+	}
+
+	props, err = store.Get(ctx, "/renamed/file.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "John Doe", string(props[author].InnerXML); e != g {
+		t.Errorf("props[author]: expected %v, got %v", e, g)
+	}
+
+	if err := store.RemoveAll(ctx, "/renamed"); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	props, err = store.Get(ctx, "/renamed/file.txt")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 0, len(props); e != g {
+		t.Errorf("len(props): expected %v, got %v", e, g)
+	}
+}
+
+func createDatabasePath(t testing.TB) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	dbPath := filepath.Join(cwd, "testdata", "deadprops.db")
+
+	files, err := filepath.Glob(dbPath + "*")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	for _, f := range files {
+		if err := os.RemoveAll(f); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	return dbPath
+}
@@ -1,19 +1,29 @@
 package deadprops
 
 import (
+	"context"
 	"encoding/xml"
 	"net/http"
 	"strings"
 	"sync"
 
+	gowebdav "github.com/bornholm/go-webdav"
 	"golang.org/x/net/webdav"
 )
 
+// Store persists the dead properties of the resources.
 type Store interface {
-	Get(filename string) (map[xml.Name]webdav.Property, error)
-	Patch(filename string, patches []webdav.Proppatch) ([]webdav.Propstat, error)
-	RemoveAll(filename string) error
-	Rename(oldName, newName string) error
+	// Get returns the dead properties of the named resource.
+	Get(ctx context.Context, name string) (map[xml.Name]webdav.Property, error)
+	// Patch applies the given patches to the named resource and returns
+	// the status of each property.
+	// Patches must be applied atomically: either all of them succeed or none
+	// of them is applied.
+	Patch(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error)
+	// RemoveAll removes the dead properties of the named resource and its descendants.
+	RemoveAll(ctx context.Context, name string) error
+	// Rename moves the dead properties of the named resource and its descendants.
+	Rename(ctx context.Context, oldName, newName string) error
 }
 
 type MemStore struct {
@@ -22,7 +32,11 @@ type MemStore struct {
 }
 
 // Rename implements DeadPropsStore.
-func (m *MemStore) Rename(oldName string, newName string) error {
+func (m *MemStore) Rename(ctx context.Context, oldName string, newName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -67,7 +81,11 @@ func NewMemStore() *MemStore {
 }
 
 // Get implements DeadPropsStore.
-func (m *MemStore) Get(filename string) (map[xml.Name]webdav.Property, error) {
+func (m *MemStore) Get(ctx context.Context, filename string) (map[xml.Name]webdav.Property, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -86,7 +104,11 @@ func (m *MemStore) Get(filename string) (map[xml.Name]webdav.Property, error) {
 }
 
 // Patch implements DeadPropsStore.
-func (m *MemStore) Patch(filename string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+func (m *MemStore) Patch(ctx context.Context, filename string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -95,41 +117,29 @@ func (m *MemStore) Patch(filename string, patches []webdav.Proppatch) ([]webdav.
 		m.props[filename] = make(map[xml.Name]webdav.Property)
 	}
 
-	var propstats []webdav.Propstat
-
 	for _, patch := range patches {
-		var props []webdav.Property
-		var status int
-
-		if patch.Remove {
-			// Remove properties
-			for _, prop := range patch.Props {
+		for _, prop := range patch.Props {
+			if patch.Remove {
 				delete(m.props[filename], prop.XMLName)
-				props = append(props, webdav.Property{XMLName: prop.XMLName})
-			}
-			status = http.StatusOK // OK
-		} else {
-			// Set properties
-			for _, prop := range patch.Props {
+			} else {
 				m.props[filename][prop.XMLName] = prop
-				props = append(props, prop)
 			}
-			status = http.StatusOK // OK
 		}
+	}
 
-		if len(props) > 0 {
-			propstats = append(propstats, webdav.Propstat{
-				Props:  props,
-				Status: status,
-			})
-		}
+	if len(m.props[filename]) == 0 {
+		delete(m.props, filename)
 	}
 
-	return propstats, nil
+	return gowebdav.Propstats(http.StatusOK, patches), nil
 }
 
 // RemoveAll implements DeadPropsStore.
-func (m *MemStore) RemoveAll(filename string) error {
+func (m *MemStore) RemoveAll(ctx context.Context, filename string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -0,0 +1,29 @@
+package home
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// CheckRename implements [webdav.RenameChecker].
+func (fs *FileSystem) CheckRename(ctx context.Context, oldName string, newName string) error {
+	checker, ok := fs.backend.(webdav.RenameChecker)
+	if !ok {
+		return nil
+	}
+
+	resolvedOld, err := fs.resolve(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	resolvedNew, err := fs.resolve(ctx, newName)
+	if err != nil {
+		return err
+	}
+
+	return checker.CheckRename(ctx, resolvedOld, resolvedNew)
+}
+
+var _ webdav.RenameChecker = &FileSystem{}
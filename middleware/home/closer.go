@@ -0,0 +1,14 @@
+package home
+
+import (
+	"io"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// Close implements [io.Closer].
+func (fs *FileSystem) Close() error {
+	return webdav.Close(fs.backend)
+}
+
+var _ io.Closer = &FileSystem{}
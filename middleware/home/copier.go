@@ -0,0 +1,54 @@
+package home
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// CopyDeadProps implements [webdav.DeadPropsCopier].
+func (fs *FileSystem) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	copier, ok := fs.backend.(webdav.DeadPropsCopier)
+	if !ok {
+		return nil
+	}
+
+	resolvedSrc, err := fs.resolve(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	resolvedDst, err := fs.resolve(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	return copier.CopyDeadProps(ctx, resolvedSrc, resolvedDst, recursive)
+}
+
+// CopyFile implements [webdav.FileCopier].
+func (fs *FileSystem) CopyFile(ctx context.Context, src string, dst string) error {
+	copier, ok := fs.backend.(webdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	resolvedSrc, err := fs.resolve(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	resolvedDst, err := fs.resolve(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	return copier.CopyFile(ctx, resolvedSrc, resolvedDst)
+}
+
+var (
+	_ webdav.DeadPropsCopier = &FileSystem{}
+	_ webdav.FileCopier      = &FileSystem{}
+)
@@ -0,0 +1,106 @@
+package home
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/fs"
+
+	gowebdav "github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// File is the root [webdav.File] of a home directory, whose listing
+// contains the shared directory.
+type File struct {
+	ctx  context.Context
+	file webdav.File
+	fs   *FileSystem
+
+	// Whether the shared directory has been listed
+	listed bool
+}
+
+// Close implements webdav.File.
+func (f *File) Close() error {
+	return f.file.Close()
+}
+
+// Read implements webdav.File.
+func (f *File) Read(p []byte) (n int, err error) {
+	return f.file.Read(p)
+}
+
+// Readdir implements webdav.File.
+// The shared directory is listed after the entries of the home directory,
+// replacing the one of the same name if any.
+func (f *File) Readdir(count int) ([]fs.FileInfo, error) {
+	children, err := f.file.Readdir(count)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	entries := make([]fs.FileInfo, 0, len(children)+1)
+	for _, child := range children {
+		if child.Name() == f.fs.sharedName {
+			continue
+		}
+
+		entries = append(entries, child)
+	}
+
+	exhausted := count <= 0 || errors.Is(err, io.EOF) || len(children) < count
+	if exhausted && !f.listed {
+		f.listed = true
+
+		shared, statErr := f.fs.backend.Stat(f.ctx, f.fs.shared)
+		if statErr != nil {
+			return nil, errors.WithStack(statErr)
+		}
+
+		entries = append(entries, filesystem.WithName(shared, f.fs.sharedName))
+	}
+
+	if count > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+
+	return entries, nil
+}
+
+// Seek implements webdav.File.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+// Stat implements webdav.File.
+func (f *File) Stat() (fs.FileInfo, error) {
+	return f.file.Stat()
+}
+
+// Write implements webdav.File.
+func (f *File) Write(p []byte) (n int, err error) {
+	return f.file.Write(p)
+}
+
+// DeadProps implements webdav.DeadPropsHolder.
+func (f *File) DeadProps() (map[xml.Name]webdav.Property, error) {
+	holder, ok := f.file.(webdav.DeadPropsHolder)
+	if !ok {
+		return nil, nil
+	}
+
+	return holder.DeadProps()
+}
+
+// Patch implements webdav.DeadPropsHolder.
+func (f *File) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return gowebdav.PatchDeadProps(f.file, patches)
+}
+
+var (
+	_ webdav.File            = &File{}
+	_ webdav.DeadPropsHolder = &File{}
+)
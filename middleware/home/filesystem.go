@@ -0,0 +1,248 @@
+package home
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/bornholm/go-webdav/syncx"
+	"github.com/pkg/errors"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem re-roots each operation under the home directory of the user
+// of the context, creating it on first access.
+// The shared directory, if any, is exposed beside the content of the home directory.
+type FileSystem struct {
+	backend    webdav.FileSystem
+	dir        string
+	template   string
+	perm       os.FileMode
+	shared     string
+	sharedName string
+
+	// Serializes the home directories creation
+	mu sync.Mutex
+	// Users whose home directory exists
+	provisioned syncx.Map[string, struct{}]
+}
+
+// Mkdir implements webdav.FileSystem.
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	resolved, err := fs.resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return fs.backend.Mkdir(ctx, resolved, perm)
+}
+
+// OpenFile implements webdav.FileSystem.
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	resolved, err := fs.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fs.backend.OpenFile(ctx, resolved, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.shared == "" || clean(name) != "/" {
+		return file, nil
+	}
+
+	return &File{ctx: ctx, file: file, fs: fs}, nil
+}
+
+// RemoveAll implements webdav.FileSystem.
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	if fs.isRoot(name) {
+		return os.ErrPermission
+	}
+
+	resolved, err := fs.resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return fs.backend.RemoveAll(ctx, resolved)
+}
+
+// Rename implements webdav.FileSystem.
+func (fs *FileSystem) Rename(ctx context.Context, oldName string, newName string) error {
+	if fs.isRoot(oldName) || fs.isRoot(newName) {
+		return os.ErrPermission
+	}
+
+	resolvedOld, err := fs.resolve(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	resolvedNew, err := fs.resolve(ctx, newName)
+	if err != nil {
+		return err
+	}
+
+	return fs.backend.Rename(ctx, resolvedOld, resolvedNew)
+}
+
+// Stat implements webdav.FileSystem.
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	resolved, err := fs.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fs.backend.Stat(ctx, resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	if base := path.Base(clean(name)); info.Name() != base {
+		return filesystem.WithName(info, base), nil
+	}
+
+	return info, nil
+}
+
+// isRoot returns true if the given name is the root of the home directory
+// or of the shared directory, which can not be removed nor renamed.
+func (fs *FileSystem) isRoot(name string) bool {
+	name = clean(name)
+	return name == "/" || (fs.shared != "" && name == "/"+fs.sharedName)
+}
+
+// resolve returns the name of the given resource in the backend, creating
+// the home directory of the user if it does not exist yet.
+func (fs *FileSystem) resolve(ctx context.Context, name string) (string, error) {
+	username, err := contextUsername(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := fs.provision(ctx, username); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	name = clean(name)
+
+	if fs.shared != "" {
+		sharedRoot := "/" + fs.sharedName
+		if name == sharedRoot || strings.HasPrefix(name, sharedRoot+"/") {
+			return path.Join(fs.shared, strings.TrimPrefix(name, sharedRoot)), nil
+		}
+	}
+
+	return path.Join(fs.dir, username, name), nil
+}
+
+// provision creates the home directory of the given user, from the template
+// if any, if it does not exist yet.
+func (fs *FileSystem) provision(ctx context.Context, username string) error {
+	if _, exists := fs.provisioned.Load(username); exists {
+		return nil
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.provisioned.Load(username); exists {
+		return nil
+	}
+
+	home := path.Join(fs.dir, username)
+
+	_, err := fs.backend.Stat(ctx, home)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+
+	if err != nil {
+		if err := fs.createHome(ctx, home); err != nil {
+			return errors.Wrapf(err, "could not create home directory '%s'", home)
+		}
+	}
+
+	fs.provisioned.Store(username, struct{}{})
+
+	return nil
+}
+
+func (fs *FileSystem) createHome(ctx context.Context, home string) error {
+	// Create the missing parent directories
+	current := "/"
+	for _, segment := range strings.Split(strings.Trim(fs.dir, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		current = path.Join(current, segment)
+
+		// Other errors, i.e. an unauthorized stat, are reported by the
+		// creation of the home directory itself
+		if _, err := fs.backend.Stat(ctx, current); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err := fs.backend.Mkdir(ctx, current, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+			return errors.WithStack(err)
+		}
+	}
+
+	if fs.template == "" {
+		if err := fs.backend.Mkdir(ctx, home, fs.perm); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	}
+
+	if err := filesystem.Copy(ctx, fs.backend, fs.template, fs.backend, home); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// contextUsername returns the name of the user of the given context.
+// Names unable to designate a directory are rejected.
+func contextUsername(ctx context.Context) (string, error) {
+	user, err := authz.ContextUser(ctx)
+	if err != nil {
+		return "", errors.Wrap(os.ErrPermission, err.Error())
+	}
+
+	username := auth.Username(user)
+	if username == "" || username == "." || username == ".." || strings.ContainsAny(username, "/\\\x00") {
+		return "", errors.Wrapf(os.ErrPermission, "invalid username '%s'", username)
+	}
+
+	return username, nil
+}
+
+func NewFileSystem(backend webdav.FileSystem, funcs ...OptionFunc) *FileSystem {
+	opts := NewOptions(funcs...)
+
+	return &FileSystem{
+		backend:    backend,
+		dir:        clean(opts.Dir),
+		template:   opts.Template,
+		perm:       opts.Perm,
+		shared:     opts.Shared,
+		sharedName: strings.Trim(opts.SharedName, "/"),
+	}
+}
+
+var _ webdav.FileSystem = &FileSystem{}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
@@ -0,0 +1,109 @@
+package home
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bornholm/go-webdav/auth"
+	"github.com/bornholm/go-webdav/authz"
+	"github.com/bornholm/go-webdav/filesystem/local"
+	"github.com/bornholm/go-webdav/filesystem/testsuite"
+	"github.com/pkg/errors"
+)
+
+func TestFileSystem(t *testing.T) {
+	root := t.TempDir()
+
+	for _, dir := range []string{"template", "template/Documents", "public"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "template", "README.txt"), []byte("welcome"), 0644); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	fs := NewFileSystem(
+		local.NewFileSystem(root),
+		WithDir("/users"),
+		WithTemplate("/template"),
+		WithShared("/public", "shared"),
+	)
+
+	alice := authz.WithContextUser(context.Background(), auth.NewUser("alice", nil))
+	bob := authz.WithContextUser(context.Background(), auth.NewUser("bob", nil))
+
+	// The home directory is created from the template on first access
+	if e, g := []string{"Documents", "README.txt", "shared"}, testsuite.ReaddirNames(t, alice, fs, "/"); !slices.Equal(e, g) {
+		t.Errorf("readdir '/': expected %v, got %v", e, g)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "users", "alice", "README.txt"))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "welcome", string(data); e != g {
+		t.Errorf("content: expected %v, got %v", e, g)
+	}
+
+	// Each user is re-rooted under its own home directory
+	testsuite.WriteFileContent(t, alice, fs, "/notes.txt", "alice")
+	testsuite.WriteFileContent(t, bob, fs, "/notes.txt", "bob")
+
+	for _, username := range []string{"alice", "bob"} {
+		data, err := os.ReadFile(filepath.Join(root, "users", username, "notes.txt"))
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := username, string(data); e != g {
+			t.Errorf("content: expected %v, got %v", e, g)
+		}
+	}
+
+	// The shared directory is common to all users
+	testsuite.WriteFileContent(t, alice, fs, "/shared/common.txt", "common")
+
+	if _, err := fs.Stat(bob, "/shared/common.txt"); err != nil {
+		t.Errorf("%+v", errors.WithStack(err))
+	}
+
+	info, err := fs.Stat(bob, "/shared")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "shared", info.Name(); e != g {
+		t.Errorf("info.Name(): expected %v, got %v", e, g)
+	}
+
+	// Paths can not escape the home directory
+	if _, err := fs.Stat(alice, "/../bob/notes.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected error '%v', got '%v'", os.ErrNotExist, err)
+	}
+
+	// Roots can not be removed
+	for _, name := range []string{"/", "/shared"} {
+		if err := fs.RemoveAll(alice, name); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("remove '%s': expected error '%v', got '%v'", name, os.ErrPermission, err)
+		}
+	}
+
+	// Anonymous users and invalid usernames are rejected
+	invalid := []context.Context{
+		context.Background(),
+		authz.WithContextUser(context.Background(), auth.NewUser("..", nil)),
+		authz.WithContextUser(context.Background(), auth.NewUser("a/b", nil)),
+	}
+
+	for _, ctx := range invalid {
+		if _, err := fs.Stat(ctx, "/"); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("expected error '%v', got '%v'", os.ErrPermission, err)
+		}
+	}
+}
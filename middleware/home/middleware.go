@@ -0,0 +1,9 @@
+package home
+
+import "github.com/bornholm/go-webdav"
+
+func Middleware(funcs ...OptionFunc) webdav.Middleware {
+	return func(next webdav.FileSystem) webdav.FileSystem {
+		return NewFileSystem(next, funcs...)
+	}
+}
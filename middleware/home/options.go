@@ -0,0 +1,61 @@
+package home
+
+import "os"
+
+type Options struct {
+	// Dir is the directory holding the home directories
+	Dir string
+	// Template is a directory copied to create the missing home directories,
+	// empty ones being created if not set
+	Template string
+	// Perm are the permissions of the home directories created without template
+	Perm os.FileMode
+	// Shared is a directory exposed beside the home directory, disabled if empty
+	Shared string
+	// SharedName is the name under which the shared directory is exposed
+	SharedName string
+}
+
+type OptionFunc func(opts *Options)
+
+func WithDir(dir string) OptionFunc {
+	return func(opts *Options) {
+		opts.Dir = dir
+	}
+}
+
+func WithTemplate(template string) OptionFunc {
+	return func(opts *Options) {
+		opts.Template = template
+	}
+}
+
+func WithPerm(perm os.FileMode) OptionFunc {
+	return func(opts *Options) {
+		opts.Perm = perm
+	}
+}
+
+// WithShared exposes the given directory as name in each home directory.
+func WithShared(dir string, name string) OptionFunc {
+	return func(opts *Options) {
+		opts.Shared = dir
+		opts.SharedName = name
+	}
+}
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Dir:        "/home",
+		Template:   "",
+		Perm:       0700,
+		Shared:     "",
+		SharedName: "shared",
+	}
+
+	for _, fn := range funcs {
+		fn(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,19 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// CheckRename implements [webdav.RenameChecker].
+func (fs *LoggerFilesystem) CheckRename(ctx context.Context, oldName string, newName string) error {
+	checker, ok := fs.backend.(webdav.RenameChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.CheckRename(ctx, oldName, newName)
+}
+
+var _ webdav.RenameChecker = &LoggerFilesystem{}
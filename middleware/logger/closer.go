@@ -0,0 +1,14 @@
+package logger
+
+import (
+	"io"
+
+	"github.com/bornholm/go-webdav"
+)
+
+// Close implements [io.Closer].
+func (fs *LoggerFilesystem) Close() error {
+	return webdav.Close(fs.backend)
+}
+
+var _ io.Closer = &LoggerFilesystem{}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bornholm/go-webdav"
+	"github.com/bornholm/go-webdav/filesystem"
+	"github.com/pkg/errors"
+)
+
+// CopyDeadProps implements [webdav.DeadPropsCopier].
+func (fs *LoggerFilesystem) CopyDeadProps(ctx context.Context, src string, dst string, recursive bool) error {
+	copier, ok := fs.backend.(webdav.DeadPropsCopier)
+	if !ok {
+		return nil
+	}
+
+	fs.logger.DebugContext(ctx, "webdav operation", slog.String("operation", "copydeadprops"), slog.String("src", src), slog.String("dst", dst), slog.Bool("recursive", recursive))
+	return copier.CopyDeadProps(ctx, src, dst, recursive)
+}
+
+// CopyFile implements [webdav.FileCopier].
+func (fs *LoggerFilesystem) CopyFile(ctx context.Context, src string, dst string) error {
+	copier, ok := fs.backend.(webdav.FileCopier)
+	if !ok {
+		return errors.WithStack(filesystem.ErrNotSupported)
+	}
+
+	fs.logger.DebugContext(ctx, "webdav operation", slog.String("operation", "copyfile"), slog.String("src", src), slog.String("dst", dst))
+	return copier.CopyFile(ctx, src, dst)
+}
+
+var (
+	_ webdav.DeadPropsCopier = &LoggerFilesystem{}
+	_ webdav.FileCopier      = &LoggerFilesystem{}
+)
@@ -0,0 +1,37 @@
+package webdav
+
+import (
+	"net/http"
+
+	"golang.org/x/net/webdav"
+)
+
+// Propstats returns the propstats reporting the given status for all
+// the properties of the patches.
+func Propstats(status int, patches []webdav.Proppatch) []webdav.Propstat {
+	propstat := webdav.Propstat{Status: status}
+
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			propstat.Props = append(propstat.Props, webdav.Property{XMLName: p.XMLName})
+		}
+	}
+
+	if len(propstat.Props) == 0 {
+		return nil
+	}
+
+	return []webdav.Propstat{propstat}
+}
+
+// PatchDeadProps applies the given patches to the dead properties of the file.
+// Files not holding dead properties report all the properties as forbidden,
+// as the webdav handler does.
+func PatchDeadProps(file File, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	holder, ok := file.(webdav.DeadPropsHolder)
+	if !ok {
+		return Propstats(http.StatusForbidden, patches), nil
+	}
+
+	return holder.Patch(patches)
+}
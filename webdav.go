@@ -1,8 +1,52 @@
 package webdav
 
-import "golang.org/x/net/webdav"
+import (
+	"context"
+	"io"
+
+	"golang.org/x/net/webdav"
+)
 
 type FileSystem = webdav.FileSystem
 type File = webdav.File
 type Dir = webdav.Dir
 type LockSystem = webdav.LockSystem
+
+// DeadPropsCopier is implemented by filesystems able to duplicate the dead
+// properties of a collection, and optionally of its descendant collections, once
+// it has been copied. The properties of the files are copied by the webdav
+// handler along with their content.
+type DeadPropsCopier interface {
+	CopyDeadProps(ctx context.Context, src, dst string, recursive bool) error
+}
+
+// FileCopier is implemented by filesystems able to copy a file without
+// reading and writing its content, i.e. by sharing its storage.
+// CopyFile replaces the content of the existing file dst by the one of the
+// file src. It returns an error wrapping filesystem.ErrNotSupported if
+// these files can not be copied this way, their content being transferred
+// instead.
+type FileCopier interface {
+	CopyFile(ctx context.Context, src, dst string) error
+}
+
+// RenameChecker is implemented by filesystems unable to rename some resources,
+// i.e. across the mounts of a mount table.
+// CheckRename returns an error wrapping [syscall.EXDEV] if oldName can not be
+// renamed to newName, allowing the MOVE request to be rejected before the
+// destination is overwritten.
+type RenameChecker interface {
+	CheckRename(ctx context.Context, oldName, newName string) error
+}
+
+// Close releases the resources held by the given filesystem (connections pools,
+// background goroutines...) if it implements [io.Closer].
+// Filesystems wrapped by the middlewares are closed through them.
+func Close(fs FileSystem) error {
+	closer, ok := fs.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}